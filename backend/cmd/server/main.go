@@ -1,20 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/Rakesh2908/shopgo/internal/auth"
 	"github.com/Rakesh2908/shopgo/internal/cart"
+	"github.com/Rakesh2908/shopgo/internal/export"
+	"github.com/Rakesh2908/shopgo/internal/inventory"
 	"github.com/Rakesh2908/shopgo/internal/order"
 	"github.com/Rakesh2908/shopgo/internal/payment"
 	"github.com/Rakesh2908/shopgo/internal/product"
 	"github.com/Rakesh2908/shopgo/internal/review"
+	"github.com/Rakesh2908/shopgo/internal/user"
 	"github.com/Rakesh2908/shopgo/internal/wishlist"
 	"github.com/Rakesh2908/shopgo/pkg/cache"
 	"github.com/Rakesh2908/shopgo/pkg/config"
 	"github.com/Rakesh2908/shopgo/pkg/database"
+	"github.com/Rakesh2908/shopgo/pkg/mail"
 	"github.com/Rakesh2908/shopgo/pkg/response"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -23,6 +28,7 @@ import (
 
 const (
 	defaultFakestoreURL = "https://fakestoreapi.com"
+	defaultCatalogFile  = "data/products.json"
 	defaultPort         = "8080"
 	cacheExpiry         = time.Minute
 )
@@ -60,24 +66,44 @@ func main() {
 
 	stripe.Key = cfg.StripeSecretKey
 
-	authRepo := auth.NewAuthRepository(db)
-	authSvc := auth.NewAuthService(authRepo, cfg)
-	jwtMiddleware := auth.JWTMiddleware(authSvc)
+	authRepo := auth.NewAuthRepository(db, cart.DeleteUserData, wishlist.DeleteUserData, review.AnonymizeUserData)
+	mailer := mail.NewSender(cfg.MailDriver, cfg.MailFrom, cfg.MailFileDir)
+	keyring, err := auth.LoadKeyring(cfg)
+	if err != nil {
+		log.Fatalf("auth: load signing keys: %v", err)
+	}
+	revocations := auth.NewDBRevocationStore(db)
 
 	fakestoreURL := cfg.FakestoreBaseURL
 	if fakestoreURL == "" {
 		fakestoreURL = defaultFakestoreURL
 	}
-	productClient := product.NewClient(fakestoreURL)
-	productSvc := product.NewProductService(productClient, memCache)
+	catalogFile := cfg.CatalogFile
+	if catalogFile == "" {
+		catalogFile = defaultCatalogFile
+	}
+	productRepo := product.NewRepository(db)
+	catalog := product.NewCatalogSource(cfg.CatalogSource, fakestoreURL, catalogFile, productRepo)
+	productSvc := product.NewProductService(catalog, memCache)
+	catalogAdminSvc := product.NewAdminService(productRepo, memCache)
+	var catalogSyncSvc product.SyncService
+	if cfg.CatalogSource == product.SourceMirror {
+		catalogSyncSvc = product.NewSyncService(product.NewClient(fakestoreURL), productRepo, memCache, time.Duration(cfg.CatalogSyncInterval))
+		go catalogSyncSvc.Run(context.Background())
+	}
+
+	inventoryRepo := inventory.NewRepository(db)
+	paymentCanceller := payment.IntentCanceller{}
+	inventorySvc := inventory.NewService(inventoryRepo, time.Duration(cfg.InventoryReservationTTL), paymentCanceller)
+	go inventorySvc.Run(context.Background())
 
 	cartRepo := cart.NewRepository(db)
-	cartSvc := cart.NewService(cartRepo, productSvc)
+	cartSvc := cart.NewService(cartRepo, productSvc, inventorySvc)
 
 	orderRepo := order.NewRepository(db)
-	orderSvc := order.NewService(orderRepo, cartSvc, productSvc)
+	orderSvc := order.NewService(orderRepo, cartSvc, productSvc, inventorySvc)
 
-	paymentSvc := payment.NewPaymentService(cfg.StripeSecretKey, cfg.StripeWebhookSecret, orderSvc)
+	paymentSvc := payment.NewPaymentService(cfg.StripeSecretKey, cfg.StripeWebhookSecret, orderSvc, inventorySvc, paymentCanceller)
 
 	wishlistRepo := wishlist.NewRepository(db)
 	wishlistSvc := wishlist.NewService(wishlistRepo, productSvc)
@@ -85,15 +111,52 @@ func main() {
 	reviewRepo := review.NewRepository(db)
 	reviewSvc := review.NewService(reviewRepo, productSvc)
 
+	exportRepo := export.NewRepository(db)
+	exportSvc := export.NewService(exportRepo, export.Sources{
+		Auth:     authRepo,
+		Cart:     cartRepo,
+		Wishlist: wishlistRepo,
+		Order:    orderRepo,
+		Review:   reviewRepo,
+	}, cfg.ExportDir)
+
+	// Built last: deleting an account also purges its export archives.
+	authSvc := auth.NewAuthService(authRepo, cfg, mailer, keyring, revocations, exportSvc)
+	jwtMiddleware := auth.JWTMiddleware(authSvc)
+	if err := authSvc.PromoteAdmins(context.Background(), splitTrim(cfg.AdminEmails, ",")); err != nil {
+		log.Fatalf("auth: admin bootstrap: %v", err)
+	}
+
 	v1 := r.Group("/api/v1")
 	auth.RegisterRoutes(v1.Group("/auth"), authSvc, jwtMiddleware)
 	productsGroup := v1.Group("/products")
 	product.RegisterRoutes(productsGroup, productSvc)
 	cart.RegisterRoutes(v1.Group("/cart"), cartSvc, jwtMiddleware)
 	order.RegisterRoutes(v1.Group("/orders"), orderSvc, jwtMiddleware)
-	payment.RegisterRoutes(v1, paymentSvc, cartSvc, jwtMiddleware)
+	var checkoutGuards []gin.HandlerFunc
+	if cfg.RequireVerifiedEmailForCheckout {
+		checkoutGuards = append(checkoutGuards, auth.RequireVerifiedEmail(authSvc))
+	}
+	payment.RegisterRoutes(v1, paymentSvc, cartSvc, jwtMiddleware, checkoutGuards...)
 	wishlist.RegisterRoutes(v1.Group("/wishlist"), wishlistSvc, jwtMiddleware)
 	review.RegisterRoutes(v1, productsGroup, reviewSvc, jwtMiddleware)
+	export.RegisterRoutes(v1.Group("/auth/me/export", jwtMiddleware, auth.RejectAPIKeys()), exportSvc)
+
+	// Back-office API: every route requires a staff or admin access token from an interactive
+	// login; API keys are refused so a leaked key cannot act with its owner's staff role.
+	admin := v1.Group("/admin", jwtMiddleware, auth.RejectAPIKeys(), auth.RequireRole(user.RoleStaff, user.RoleAdmin))
+	auth.RegisterAdminRoutes(admin.Group("/users"), authSvc)
+	auth.RegisterAuthEventRoutes(admin.Group("/auth-events"), authSvc)
+	if catalogSyncSvc != nil {
+		// The mirror belongs to the sync, which would overwrite or archive manual edits and
+		// whose upstream IDs would collide with products created here, so it has no catalog editing.
+		product.RegisterSyncRoutes(admin.Group("/catalog"), catalogSyncSvc)
+	} else {
+		product.RegisterAdminRoutes(admin, catalogAdminSvc)
+	}
+	inventory.RegisterAdminRoutes(admin.Group("/inventory"), inventorySvc)
+
+	auth.RegisterJWKSRoute(r, keyring)
 
 	r.GET("/health", func(c *gin.Context) {
 		response.Success(c, 200, gin.H{
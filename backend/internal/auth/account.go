@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/mail"
+	"github.com/google/uuid"
+)
+
+// ProfileUpdate lists the profile fields to change. Nil fields are left unchanged.
+type ProfileUpdate struct {
+	FullName *string
+	Email    *string
+}
+
+// UpdateProfile changes the user's name and/or email. A new email is not applied straight away:
+// it is stored as pending and a verification link is sent to it, and only VerifyEmail switches
+// the account over. Requesting the current address again cancels a pending change.
+func (s *authService) UpdateProfile(ctx context.Context, userID uuid.UUID, update ProfileUpdate) (*user.User, error) {
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	if update.FullName != nil && *update.FullName != u.FullName {
+		if err := s.repo.UpdateFullName(ctx, userID, *update.FullName); err != nil {
+			return nil, err
+		}
+		u.FullName = *update.FullName
+	}
+	if update.Email != nil {
+		email := strings.TrimSpace(*update.Email)
+		switch {
+		case strings.EqualFold(email, u.Email):
+			if u.PendingEmail != "" {
+				if err := s.repo.SetPendingEmail(ctx, userID, ""); err != nil {
+					return nil, err
+				}
+				u.PendingEmail = ""
+			}
+		case email != u.PendingEmail:
+			taken, err := s.repo.FindUserByEmail(ctx, email)
+			if err != nil {
+				return nil, err
+			}
+			if taken != nil {
+				return nil, ErrEmailTaken
+			}
+			if err := checkVerificationCooldown(u); err != nil {
+				return nil, err
+			}
+			if err := s.repo.SetPendingEmail(ctx, userID, email); err != nil {
+				return nil, err
+			}
+			u.PendingEmail = email
+			if err := s.sendVerificationEmail(ctx, u, email); err != nil {
+				// The change stays pending; the user can ask for another link.
+				log.Printf("auth: send email change verification: %v", err)
+			}
+		}
+	}
+	return userWithoutPassword(u), nil
+}
+
+// ChangePassword replaces the user's password after checking the current one, and signs out every
+// session except the one identified by refreshCookieValue. Outstanding access tokens are revoked.
+func (s *authService) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword, refreshCookieValue string, client ClientInfo) error {
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return ErrUserNotFound
+	}
+	if ok, _ := s.hasher.Verify(u.Password, currentPassword); !ok {
+		s.recordFailure(ctx, EventPasswordChange, u.ID, u.Email, "invalid_credentials", client)
+		return ErrInvalidCredentials
+	}
+	hashed, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateUserPassword(ctx, userID, hashed); err != nil {
+		return err
+	}
+	if err := s.repo.DeletePasswordResetTokensByUserID(ctx, userID); err != nil {
+		return err
+	}
+	keep := s.currentFamily(ctx, userID, refreshCookieValue)
+	if err := s.repo.DeleteOtherRefreshTokenFamilies(ctx, userID, keep); err != nil {
+		return err
+	}
+	// Access tokens issued before the change stop working; the kept session simply refreshes.
+	if err := s.revokeUserAccessTokens(ctx, userID, time.Now().Truncate(time.Second)); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, client, user.AuthEvent{Type: EventPasswordChange, UserID: &u.ID, Email: u.Email, Outcome: OutcomeSuccess})
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Your ShopGo password was changed",
+		Body: "The password for your ShopGo account was just changed and your other devices were signed out.\n\n" +
+			"If this wasn't you, reset your password straight away.",
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("auth: send password changed email: %v", err)
+	}
+	return nil
+}
+
+// sendDeletionConfirmation emails u a link that confirms deleting their account.
+func (s *authService) sendDeletionConfirmation(ctx context.Context, u *user.User) error {
+	token, err := s.signPurposeToken(purposeAccountDeletion, u.ID.String(), u.Email, accountDeletionTTL)
+	if err != nil {
+		return err
+	}
+	link := s.config.AppBaseURL + "/account/delete?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Confirm deleting your ShopGo account",
+		Body: "We received a request to delete your ShopGo account.\n\n" +
+			"To delete it, open this link:\n" + link + "\n\n" +
+			"The link expires in 1 hour. If you did not ask for this, you can ignore this email.",
+	})
+}
+
+// AccountDataPurger removes data another package keeps for a user, such as files on disk, once
+// their account has been deleted.
+type AccountDataPurger interface {
+	PurgeUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// DeleteAccount removes the user's account. Accounts with a password must confirm it. Accounts
+// created through social login or magic links have none, so a bearer token alone is not enough:
+// called without confirmationToken, DeleteAccount emails a confirmation link and returns
+// ErrDeletionConfirmationSent, and the token from that link deletes the account.
+// Orders and reviews are kept but anonymized.
+func (s *authService) DeleteAccount(ctx context.Context, userID uuid.UUID, password, confirmationToken string) error {
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return ErrUserNotFound
+	}
+	switch {
+	case u.Password != "":
+		if ok, _ := s.hasher.Verify(u.Password, password); !ok {
+			return ErrInvalidCredentials
+		}
+	case confirmationToken == "":
+		if err := s.sendDeletionConfirmation(ctx, u); err != nil {
+			return err
+		}
+		return ErrDeletionConfirmationSent
+	default:
+		claims, err := s.parsePurposeToken(purposeAccountDeletion, confirmationToken)
+		if err != nil || claims.Subject != u.ID.String() || claims.Email != u.Email {
+			return ErrInvalidDeletionToken
+		}
+	}
+	deleted, err := s.repo.DeleteAccount(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrUserNotFound
+	}
+	if s.purger != nil {
+		if err := s.purger.PurgeUser(ctx, userID); err != nil {
+			// The account is gone either way; what is left expires on its own.
+			log.Printf("auth: purge data of deleted user %s: %v", userID, err)
+		}
+	}
+	return s.revokeUserAccessTokens(ctx, userID, time.Now())
+}
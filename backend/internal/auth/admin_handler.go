@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultUsersPage  = 1
+	defaultUsersLimit = 20
+)
+
+// SetRoleRequest is the request body for PATCH /admin/users/:id/role.
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=customer staff admin"`
+}
+
+// RegisterAdminRoutes registers back-office user management routes on the given router group.
+// The group must already require JWT auth and a staff or admin role; changing roles and bans additionally
+// require admin. Group path should be "/admin/users" so routes are GET /admin/users,
+// PATCH /admin/users/:id/role, POST /admin/users/:id/ban and DELETE /admin/users/:id/ban.
+func RegisterAdminRoutes(rg *gin.RouterGroup, svc AuthService) {
+	rg.GET("", handleListUsers(svc))
+	rg.PATCH("/:id/role", RequireRole(user.RoleAdmin), handleSetRole(svc))
+	rg.POST("/:id/ban", RequireRole(user.RoleAdmin), handleBanUser(svc))
+	rg.DELETE("/:id/ban", RequireRole(user.RoleAdmin), handleUnbanUser(svc))
+}
+
+// handleListUsers handles GET /admin/users?page=&limit=
+func handleListUsers(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit := pageParams(c)
+		users, total, err := svc.ListUsers(c.Request.Context(), page, limit)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list users")
+			return
+		}
+		response.SuccessWithMeta(c, http.StatusOK, users, gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// handleSetRole handles PATCH /admin/users/:id/role (admin only).
+func handleSetRole(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := GetUserIDFromContext(c)
+		if actorID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid user id")
+			return
+		}
+		var req SetRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		if err := svc.SetUserRole(c.Request.Context(), actorID, userID, req.Role); err != nil {
+			switch {
+			case errors.Is(err, ErrUserNotFound):
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "user not found")
+			case errors.Is(err, ErrInvalidRole):
+				response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid role")
+			case errors.Is(err, ErrCannotChangeOwnRole):
+				response.Error(c, http.StatusBadRequest, "CANNOT_CHANGE_OWN_ROLE", "you cannot change your own role")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update role")
+			}
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"id": userID, "role": req.Role})
+	}
+}
+
+// handleBanUser handles POST /admin/users/:id/ban (admin only). The user's sessions and access tokens
+// are revoked immediately.
+func handleBanUser(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := GetUserIDFromContext(c)
+		if actorID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid user id")
+			return
+		}
+		if err := svc.BanUser(c.Request.Context(), actorID, userID); err != nil {
+			switch {
+			case errors.Is(err, ErrUserNotFound):
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "user not found")
+			case errors.Is(err, ErrCannotBanSelf):
+				response.Error(c, http.StatusBadRequest, "CANNOT_BAN_SELF", "you cannot ban your own account")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to ban user")
+			}
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"id": userID, "banned": true})
+	}
+}
+
+// handleUnbanUser handles DELETE /admin/users/:id/ban (admin only).
+func handleUnbanUser(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid user id")
+			return
+		}
+		if err := svc.UnbanUser(c.Request.Context(), userID); err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "user not found")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to unban user")
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"id": userID, "banned": false})
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateAPIKeyRequest is the request body for POST /auth/api-keys.
+// ExpiresInDays defaults to 90 when omitted.
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=read write"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+// handleListAPIKeys handles GET /auth/api-keys — lists the caller's API keys without their secrets.
+func handleListAPIKeys(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		keys, err := svc.ListAPIKeys(c.Request.Context(), userID)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list api keys")
+			return
+		}
+		response.Success(c, http.StatusOK, keys)
+	}
+}
+
+// handleCreateAPIKey handles POST /auth/api-keys — mints a key and returns its plaintext once.
+func handleCreateAPIKey(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		var req CreateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		key, err := svc.CreateAPIKey(c.Request.Context(), userID, req.Name, req.Scopes, ttl)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create api key")
+			return
+		}
+		response.Success(c, http.StatusCreated, key)
+	}
+}
+
+// handleRevokeAPIKey handles DELETE /auth/api-keys/:id — revokes one of the caller's keys.
+func handleRevokeAPIKey(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		keyID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid api key id")
+			return
+		}
+		if err := svc.RevokeAPIKey(c.Request.Context(), userID, keyID); err != nil {
+			if errors.Is(err, ErrAPIKeyNotFound) {
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "api key not found")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke api key")
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"revoked": true})
+	}
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+)
+
+// API key scopes. A read key may only make safe (GET/HEAD/OPTIONS) requests.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+const (
+	// apiKeyPrefix marks ShopGo API keys so they are easy to spot in logs and secret scanners.
+	apiKeyPrefix = "sgk_"
+	// apiKeyDisplayLen is how many characters of the key are kept in clear to identify it.
+	apiKeyDisplayLen = len(apiKeyPrefix) + 8
+	// APIKeyDefaultTTL is the lifetime of a key created without an explicit expiry.
+	APIKeyDefaultTTL = 90 * 24 * time.Hour
+	// APIKeyMaxTTL is the longest lifetime a key may be given.
+	APIKeyMaxTTL = 365 * 24 * time.Hour
+	// apiKeyTouchInterval limits how often last_used_at is written for a busy key.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyInfo describes an API key without its secret.
+type APIKeyInfo struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// CreatedAPIKey is a newly minted API key. Key is the plaintext secret and is never shown again.
+type CreatedAPIKey struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
+
+// CreateAPIKey mints a named key for the user. ttl is clamped to APIKeyMaxTTL and defaults to
+// APIKeyDefaultTTL when zero.
+func (s *authService) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string, ttl time.Duration) (*CreatedAPIKey, error) {
+	if ttl <= 0 {
+		ttl = APIKeyDefaultTTL
+	}
+	if ttl > APIKeyMaxTTL {
+		ttl = APIKeyMaxTTL
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now()
+	k := &user.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLen],
+		KeyHash:   hashAPIKey(key),
+		Scopes:    strings.Join(normalizeScopes(scopes), " "),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateAPIKey(ctx, k); err != nil {
+		return nil, err
+	}
+	return &CreatedAPIKey{APIKeyInfo: apiKeyInfo(k), Key: key}, nil
+}
+
+// ListAPIKeys returns the user's API keys, newest first.
+func (s *authService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKeyInfo, error) {
+	keys, err := s.repo.ListAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]APIKeyInfo, 0, len(keys))
+	for i := range keys {
+		out = append(out, apiKeyInfo(&keys[i]))
+	}
+	return out, nil
+}
+
+// RevokeAPIKey deletes one of the user's API keys. It takes effect on the key's next request.
+func (s *authService) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	deleted, err := s.repo.DeleteAPIKey(ctx, userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// ParseAPIKey looks up a plaintext API key and returns the identity of its owner, limited to the
+// key's scopes. The owner's current role is used, so demoting a user also limits their keys.
+func (s *authService) ParseAPIKey(ctx context.Context, key string) (*Identity, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	k, err := s.repo.FindAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if k == nil || !k.ExpiresAt.After(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	u, err := s.repo.FindUserByID(ctx, k.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if u.BannedAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchAPIKey(ctx, k.ID, now); err != nil {
+			log.Printf("auth: touch api key: %v", err)
+		}
+	}
+	role := u.Role
+	if role == "" {
+		role = user.RoleCustomer
+	}
+	return &Identity{UserID: u.ID, Role: role, APIKeyID: k.ID, Scopes: strings.Fields(k.Scopes)}, nil
+}
+
+// HasScope reports whether the identity may act with scope. Access tokens have every scope,
+// and the write scope implies read.
+func (i *Identity) HasScope(scope string) bool {
+	if i.APIKeyID == uuid.Nil {
+		return true
+	}
+	for _, s := range i.Scopes {
+		if s == scope || s == ScopeWrite {
+			return true
+		}
+	}
+	return false
+}
+
+// hashAPIKey returns the hex SHA-256 of key. API keys carry 256 bits of entropy, so a fast hash
+// is enough and lets the key be looked up directly.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes removes duplicates and defaults to read-only.
+func normalizeScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	out := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, ScopeRead)
+	}
+	return out
+}
+
+// apiKeyInfo converts the stored key to its public description.
+func apiKeyInfo(k *user.APIKey) APIKeyInfo {
+	return APIKeyInfo{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     strings.Fields(k.Scopes),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
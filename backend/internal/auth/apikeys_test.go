@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity Identity
+		scope    string
+		want     bool
+	}{
+		{"access token reads", Identity{}, ScopeRead, true},
+		{"access token writes", Identity{}, ScopeWrite, true},
+		{"read key reads", Identity{APIKeyID: uuid.New(), Scopes: []string{ScopeRead}}, ScopeRead, true},
+		{"read key writes", Identity{APIKeyID: uuid.New(), Scopes: []string{ScopeRead}}, ScopeWrite, false},
+		{"write key reads", Identity{APIKeyID: uuid.New(), Scopes: []string{ScopeWrite}}, ScopeRead, true},
+		{"write key writes", Identity{APIKeyID: uuid.New(), Scopes: []string{ScopeWrite}}, ScopeWrite, true},
+		{"key without scopes", Identity{APIKeyID: uuid.New()}, ScopeRead, false},
+	}
+	for _, tt := range tests {
+		if got := tt.identity.HasScope(tt.scope); got != tt.want {
+			t.Errorf("%s: HasScope(%s) = %v, want %v", tt.name, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKeyScopesInMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	readKey, err := svc.CreateAPIKey(ctx, u.ID, "reporting", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey, err := svc.CreateAPIKey(ctx, u.ID, "sync", []string{ScopeWrite, ScopeWrite}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readKey.Scopes) != 1 || readKey.Scopes[0] != ScopeRead || len(writeKey.Scopes) != 1 {
+		t.Fatalf("scopes = %v and %v, want [read] and [write]", readKey.Scopes, writeKey.Scopes)
+	}
+
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/items", JWTMiddleware(svc), ok)
+	r.POST("/items", JWTMiddleware(svc), ok)
+	r.POST("/api-keys", JWTMiddleware(svc), RejectAPIKeys(), ok)
+
+	tests := []struct {
+		method, path, key string
+		want              int
+	}{
+		{http.MethodGet, "/items", readKey.Key, http.StatusOK},
+		{http.MethodPost, "/items", readKey.Key, http.StatusForbidden},
+		{http.MethodGet, "/items", writeKey.Key, http.StatusOK},
+		{http.MethodPost, "/items", writeKey.Key, http.StatusOK},
+		{http.MethodPost, "/api-keys", writeKey.Key, http.StatusForbidden},
+		{http.MethodGet, "/items", "sgk_unknown", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "ApiKey "+tt.key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s with %.12s: status %d, want %d", tt.method, tt.path, tt.key, w.Code, tt.want)
+		}
+	}
+}
+
+func TestParseAPIKeyRejectsExpiredAndRevoked(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	created, err := svc.CreateAPIKey(ctx, u.ID, "sync", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := svc.ParseAPIKey(ctx, created.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != u.ID || identity.APIKeyID != created.ID {
+		t.Errorf("identity = %+v, want user %s and key %s", identity, u.ID, created.ID)
+	}
+
+	repo.mu.Lock()
+	repo.apiKeys[created.ID].ExpiresAt = time.Now().Add(-time.Second)
+	repo.mu.Unlock()
+	if _, err := svc.ParseAPIKey(ctx, created.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expired key: err = %v, want ErrInvalidAPIKey", err)
+	}
+
+	live, err := svc.CreateAPIKey(ctx, u.ID, "sync", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RevokeAPIKey(ctx, uuid.New(), live.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("revoking another user's key: err = %v, want ErrAPIKeyNotFound", err)
+	}
+	if err := svc.RevokeAPIKey(ctx, u.ID, live.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ParseAPIKey(ctx, live.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("revoked key: err = %v, want ErrInvalidAPIKey", err)
+	}
+}
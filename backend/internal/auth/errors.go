@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenReused is returned when a retired refresh token is presented again.
+	ErrRefreshTokenReused = errors.New("auth: refresh token reuse detected")
+	// ErrInvalidResetToken is returned when a password reset token is unknown, expired or already used.
+	ErrInvalidResetToken = errors.New("auth: invalid or expired reset token")
+	// ErrSessionNotFound is returned when a session does not exist or belongs to another user.
+	ErrSessionNotFound = errors.New("auth: session not found")
+	// ErrInvalidVerificationToken is returned when an email verification token is malformed, expired
+	// or was issued for an address the account no longer uses.
+	ErrInvalidVerificationToken = errors.New("auth: invalid or expired verification token")
+	// ErrEmailAlreadyVerified is returned when a verification email is requested for a verified address.
+	ErrEmailAlreadyVerified = errors.New("auth: email already verified")
+	// ErrInvalidCredentials is returned when the email or password is wrong.
+	ErrInvalidCredentials = errors.New("auth: invalid email or password")
+	// ErrTwoFactorAlreadyEnabled is returned when 2FA setup is started for an account that already uses it.
+	ErrTwoFactorAlreadyEnabled = errors.New("auth: two-factor authentication already enabled")
+	// ErrTwoFactorNotSetUp is returned when 2FA is enabled or disabled before setup was started.
+	ErrTwoFactorNotSetUp = errors.New("auth: two-factor authentication not set up")
+	// ErrInvalidTwoFactorCode is returned when a TOTP or recovery code is wrong or was already used.
+	ErrInvalidTwoFactorCode = errors.New("auth: invalid two-factor code")
+	// ErrInvalidChallenge is returned when a 2FA login challenge token is malformed or expired.
+	ErrInvalidChallenge = errors.New("auth: invalid or expired login challenge")
+	// ErrUserNotFound is returned when an operation targets a user that does not exist.
+	ErrUserNotFound = errors.New("auth: user not found")
+	// ErrInvalidRole is returned when a role name is not one of the known roles.
+	ErrInvalidRole = errors.New("auth: invalid role")
+	// ErrCannotChangeOwnRole is returned when an admin tries to change their own role.
+	ErrCannotChangeOwnRole = errors.New("auth: cannot change your own role")
+	// ErrAccountLocked is returned when logins are temporarily blocked after repeated failures.
+	// It is always wrapped in a *RetryAfterError.
+	ErrAccountLocked = errors.New("auth: account temporarily locked")
+	// ErrUnknownProvider is returned when a social login provider is not configured.
+	ErrUnknownProvider = errors.New("auth: unknown identity provider")
+	// ErrInvalidOIDCState is returned when the OIDC state does not match the one issued to this browser.
+	ErrInvalidOIDCState = errors.New("auth: invalid or expired login state")
+	// ErrOIDCExchange is returned when the provider rejects the code or returns an invalid ID token.
+	ErrOIDCExchange = errors.New("auth: identity provider login failed")
+	// ErrOIDCEmailNotVerified is returned when the provider does not vouch for the user's email address.
+	ErrOIDCEmailNotVerified = errors.New("auth: identity provider did not return a verified email")
+	// ErrAccountLinkConflict is returned when an unverified local account already uses the provider's email.
+	ErrAccountLinkConflict = errors.New("auth: an account with this email already exists")
+	// ErrInvalidAPIKey is returned when an API key is unknown or expired.
+	ErrInvalidAPIKey = errors.New("auth: invalid or expired api key")
+	// ErrAPIKeyNotFound is returned when the API key does not exist or belongs to another user.
+	ErrAPIKeyNotFound = errors.New("auth: api key not found")
+	// ErrEmailTaken is returned when another account already uses the requested email address.
+	ErrEmailTaken = errors.New("auth: email already registered")
+	// ErrAccountDisabled is returned when a banned user tries to sign in or use a credential.
+	ErrAccountDisabled = errors.New("auth: account disabled")
+	// ErrCannotBanSelf is returned when an admin tries to ban their own account.
+	ErrCannotBanSelf = errors.New("auth: cannot ban own account")
+	// ErrInvalidMagicLink is returned when a magic link is unknown, expired, used, or opened in another browser.
+	ErrInvalidMagicLink = errors.New("auth: invalid or expired magic link")
+	// ErrDeletionConfirmationSent is returned when an account without a password asks to be deleted:
+	// nothing is deleted yet, and a confirmation link has been emailed instead.
+	ErrDeletionConfirmationSent = errors.New("auth: account deletion confirmation sent")
+	// ErrInvalidDeletionToken is returned when an account deletion confirmation is malformed, expired,
+	// for another account or for an address the account no longer uses.
+	ErrInvalidDeletionToken = errors.New("auth: invalid or expired deletion confirmation")
+	// ErrTooManyRequests is returned when an action is attempted again before its cooldown has passed.
+	ErrTooManyRequests = errors.New("auth: too many requests")
+)
+
+// RetryAfterError wraps an error with a hint for when the caller may try again.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error so errors.Is matches the sentinel.
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+)
+
+// Authentication event types recorded in the audit log.
+const (
+	EventLogin             = "login"
+	EventLoginTwoFactor    = "login_2fa"
+	EventLoginOIDC         = "login_oidc"
+	EventLoginMagicLink    = "login_magic_link"
+	EventLoginLockout      = "login_lockout"
+	EventTokenRefresh      = "token_refresh"
+	EventRefreshTokenReuse = "refresh_token_reuse"
+	EventLogout            = "logout"
+	EventPasswordChange    = "password_change"
+	EventPasswordReset     = "password_reset"
+)
+
+// Outcomes of an authentication event.
+const (
+	OutcomeSuccess    = "success"
+	OutcomeFailure    = "failure"
+	OutcomeChallenged = "challenged"
+)
+
+// AuthEventFilter narrows an audit log query. Zero fields match everything.
+type AuthEventFilter struct {
+	UserID    uuid.UUID
+	Email     string
+	Type      string
+	Outcome   string
+	IPAddress string
+	Since     time.Time
+	Until     time.Time
+}
+
+// ListActivity returns a page of the user's own authentication events, newest first, and the total count.
+func (s *authService) ListActivity(ctx context.Context, userID uuid.UUID, page, limit int) ([]user.AuthEvent, int64, error) {
+	return s.repo.ListAuthEvents(ctx, AuthEventFilter{UserID: userID}, page, limit)
+}
+
+// ListAuthEvents returns a page of everyone's authentication events matching filter, newest first,
+// and the total count.
+func (s *authService) ListAuthEvents(ctx context.Context, filter AuthEventFilter, page, limit int) ([]user.AuthEvent, int64, error) {
+	return s.repo.ListAuthEvents(ctx, filter, page, limit)
+}
+
+// recordEvent appends e to the audit log with the client's details. Failures are only logged so a
+// broken audit log never blocks signing in.
+func (s *authService) recordEvent(ctx context.Context, client ClientInfo, e user.AuthEvent) {
+	e.IPAddress = client.IPAddress
+	e.UserAgent = client.UserAgent
+	if len(e.UserAgent) > maxUserAgentLen {
+		e.UserAgent = e.UserAgent[:maxUserAgentLen]
+	}
+	e.CreatedAt = time.Now()
+	if err := s.repo.CreateAuthEvent(ctx, &e); err != nil {
+		log.Printf("auth: record %s event: %v", e.Type, err)
+	}
+}
+
+// recordLogin records the outcome of a sign-in by u that got past its credential checks, given the
+// result and error of firstFactorResult or issueSession. Unexpected errors are not recorded.
+func (s *authService) recordLogin(ctx context.Context, eventType string, u *user.User, client ClientInfo, result *LoginResult, err error) {
+	e := user.AuthEvent{Type: eventType, UserID: &u.ID, Email: u.Email}
+	switch {
+	case errors.Is(err, ErrAccountDisabled):
+		e.Outcome, e.Reason = OutcomeFailure, "account_disabled"
+	case err != nil:
+		return
+	case result.TwoFactorChallenge != "":
+		e.Outcome = OutcomeChallenged
+	default:
+		e.Outcome = OutcomeSuccess
+	}
+	s.recordEvent(ctx, client, e)
+}
+
+// recordFailure records a failed attempt of the given type. userID may be uuid.Nil when the
+// attempt could not be tied to an account.
+func (s *authService) recordFailure(ctx context.Context, eventType string, userID uuid.UUID, email, reason string, client ClientInfo) {
+	e := user.AuthEvent{Type: eventType, Email: email, Outcome: OutcomeFailure, Reason: reason}
+	if userID != uuid.Nil {
+		e.UserID = &userID
+	}
+	s.recordEvent(ctx, client, e)
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RegisterAuthEventRoutes registers the admin audit log query on the given router group. The group
+// must already require JWT auth and a staff or admin role; the route itself requires admin.
+// Group path should be "/admin/auth-events" so the route is GET /admin/auth-events.
+func RegisterAuthEventRoutes(rg *gin.RouterGroup, svc AuthService) {
+	rg.GET("", RequireRole(user.RoleAdmin), handleListAuthEvents(svc))
+}
+
+// handleListActivity handles GET /auth/me/activity?page=&limit= — the caller's own sign-in history.
+func handleListActivity(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		page, limit := pageParams(c)
+		events, total, err := svc.ListActivity(c.Request.Context(), userID, page, limit)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list activity")
+			return
+		}
+		response.SuccessWithMeta(c, http.StatusOK, events, gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// handleListAuthEvents handles GET /admin/auth-events?userId=&email=&type=&outcome=&ip=&from=&to=&page=&limit=
+// (admin only). from and to are RFC 3339 timestamps; from is inclusive, to exclusive.
+func handleListAuthEvents(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := AuthEventFilter{
+			Email:     c.Query("email"),
+			Type:      c.Query("type"),
+			Outcome:   c.Query("outcome"),
+			IPAddress: c.Query("ip"),
+		}
+		if v := c.Query("userId"); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid userId")
+				return
+			}
+			filter.UserID = id
+		}
+		for _, p := range []struct {
+			name string
+			dst  *time.Time
+		}{{"from", &filter.Since}, {"to", &filter.Until}} {
+			v := c.Query(p.name)
+			if v == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", p.name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*p.dst = t
+		}
+		page, limit := pageParams(c)
+		events, total, err := svc.ListAuthEvents(c.Request.Context(), filter, page, limit)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list auth events")
+			return
+		}
+		response.SuccessWithMeta(c, http.StatusOK, events, gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// pageParams reads the page and limit query parameters, falling back to the defaults when they are
+// missing or out of range.
+func pageParams(c *gin.Context) (page, limit int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", strconv.Itoa(defaultUsersPage)))
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultUsersLimit)))
+	if page < 1 {
+		page = defaultUsersPage
+	}
+	if limit < 1 || limit > 100 {
+		limit = defaultUsersLimit
+	}
+	return page, limit
+}
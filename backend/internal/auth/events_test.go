@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// takeEvents returns the events recorded so far and forgets them.
+func takeEvents(repo *fakeAuthRepo) []user.AuthEvent {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	events := repo.events
+	repo.events = nil
+	return events
+}
+
+// wantEvent fails the test unless events holds exactly one event of the given type, outcome and
+// reason, for userID (uuid.Nil for none), and returns it.
+func wantEvent(t *testing.T, events []user.AuthEvent, eventType, outcome, reason string, userID uuid.UUID) user.AuthEvent {
+	t.Helper()
+	if len(events) != 1 {
+		t.Fatalf("%d events recorded, want one %s event: %+v", len(events), eventType, events)
+	}
+	e := events[0]
+	if e.Type != eventType || e.Outcome != outcome || e.Reason != reason {
+		t.Errorf("event = %s/%s/%q, want %s/%s/%q", e.Type, e.Outcome, e.Reason, eventType, outcome, reason)
+	}
+	if (userID == uuid.Nil) != (e.UserID == nil) || (e.UserID != nil && *e.UserID != userID) {
+		t.Errorf("%s event user = %v, want %s", eventType, e.UserID, userID)
+	}
+	return e
+}
+
+func TestAuthEventsRecordSignInLifecycle(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	hashed, err := svc.hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.users[u.ID].Password = hashed
+	ctx := context.Background()
+	client := ClientInfo{IPAddress: "192.0.2.1", UserAgent: "test-browser/" + strings.Repeat("x", maxUserAgentLen)}
+
+	if _, err := svc.Login(ctx, u.Email, "wrong", client); err == nil {
+		t.Fatal("login with a wrong password succeeded")
+	}
+	e := wantEvent(t, takeEvents(repo), EventLogin, OutcomeFailure, "invalid_credentials", u.ID)
+	if e.Email != u.Email || e.IPAddress != client.IPAddress || len(e.UserAgent) != maxUserAgentLen || e.CreatedAt.IsZero() {
+		t.Errorf("failure event email %q, IP %q, user agent length %d, created %v", e.Email, e.IPAddress, len(e.UserAgent), e.CreatedAt)
+	}
+
+	if _, err := svc.Login(ctx, "nobody@example.com", "wrong", client); err == nil {
+		t.Fatal("login for an unknown email succeeded")
+	}
+	e = wantEvent(t, takeEvents(repo), EventLogin, OutcomeFailure, "unknown_user", uuid.Nil)
+	if e.Email != "nobody@example.com" {
+		t.Errorf("unknown user event email = %q", e.Email)
+	}
+
+	result, err := svc.Login(ctx, u.Email, "correct horse", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEvent(t, takeEvents(repo), EventLogin, OutcomeSuccess, "", u.ID)
+
+	access, cookie, err := svc.RefreshToken(ctx, result.RefreshCookieValue, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEvent(t, takeEvents(repo), EventTokenRefresh, OutcomeSuccess, "", u.ID)
+
+	if err := svc.Logout(ctx, cookie, access, client); err != nil {
+		t.Fatal(err)
+	}
+	wantEvent(t, takeEvents(repo), EventLogout, OutcomeSuccess, "", u.ID)
+
+	// Logging out without any valid token has no one to attribute the event to.
+	if err := svc.Logout(ctx, "", "not-a-token", client); err != nil {
+		t.Fatal(err)
+	}
+	if events := takeEvents(repo); len(events) != 0 {
+		t.Errorf("anonymous logout recorded %+v", events)
+	}
+}
+
+// eventsResponse is the JSON body of the activity and audit log routes.
+type eventsResponse struct {
+	Data []user.AuthEvent `json:"data"`
+	Meta struct {
+		Page  int   `json:"page"`
+		Limit int   `json:"limit"`
+		Total int64 `json:"total"`
+	} `json:"meta"`
+}
+
+// getJSON sends a GET for target with a bearer token and decodes the response into v if it is 200.
+func getJSON(t *testing.T, r http.Handler, target, accessToken string, v interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusOK && v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code
+}
+
+func TestListActivityShowsOnlyTheCallersEvents(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	other := repo.addUser("other@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+	svc.recordEvent(ctx, ClientInfo{}, user.AuthEvent{Type: EventLogin, UserID: &u.ID, Outcome: OutcomeSuccess})
+	svc.recordEvent(ctx, ClientInfo{}, user.AuthEvent{Type: EventLogin, UserID: &other.ID, Outcome: OutcomeSuccess})
+	svc.recordEvent(ctx, ClientInfo{}, user.AuthEvent{Type: EventLogout, UserID: &u.ID, Outcome: OutcomeSuccess})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me/activity", JWTMiddleware(svc), handleListActivity(svc))
+	token, err := svc.generateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Filters in the query are ignored: the activity is always the caller's own.
+	var body eventsResponse
+	if code := getJSON(t, r, "/me/activity?userId="+other.ID.String()+"&type=logout&page=2&limit=5", token, &body); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if f := repo.lastEventFilter(t); f != (AuthEventFilter{UserID: u.ID}) {
+		t.Errorf("filter = %+v, want only the caller's ID", f)
+	}
+	if body.Meta.Page != 2 || body.Meta.Limit != 5 || body.Meta.Total != 2 {
+		t.Errorf("meta = %+v, want page 2, limit 5, total 2", body.Meta)
+	}
+	if len(body.Data) != 2 || body.Data[0].Type != EventLogout {
+		t.Errorf("events = %+v, want the caller's two, newest first", body.Data)
+	}
+}
+
+func TestListAuthEventsFilters(t *testing.T) {
+	repo := newFakeAuthRepo()
+	admin := repo.addUser("admin@example.com", true)
+	repo.users[admin.ID].Role = user.RoleAdmin
+	staff := repo.addUser("staff@example.com", true)
+	repo.users[staff.ID].Role = user.RoleStaff
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterAuthEventRoutes(r.Group("/admin/auth-events", JWTMiddleware(svc)), svc)
+	adminToken, err := svc.generateAccessToken(repo.users[admin.ID])
+	if err != nil {
+		t.Fatal(err)
+	}
+	staffToken, err := svc.generateAccessToken(repo.users[staff.ID])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := getJSON(t, r, "/admin/auth-events", staffToken, nil); code != http.StatusForbidden {
+		t.Errorf("staff: status %d, want 403", code)
+	}
+
+	userID := uuid.New()
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+	var body eventsResponse
+	target := "/admin/auth-events?userId=" + userID.String() + "&email=member@example.com&type=login&outcome=failure" +
+		"&ip=192.0.2.1&from=" + since.Format(time.RFC3339) + "&to=" + until.Format(time.RFC3339) + "&page=3&limit=1000"
+	if code := getJSON(t, r, target, adminToken, &body); code != http.StatusOK {
+		t.Fatalf("admin: status %d", code)
+	}
+	want := AuthEventFilter{
+		UserID:    userID,
+		Email:     "member@example.com",
+		Type:      EventLogin,
+		Outcome:   OutcomeFailure,
+		IPAddress: "192.0.2.1",
+		Since:     since,
+		Until:     until,
+	}
+	if f := repo.lastEventFilter(t); f.UserID != want.UserID || f.Email != want.Email || f.Type != want.Type ||
+		f.Outcome != want.Outcome || f.IPAddress != want.IPAddress || !f.Since.Equal(want.Since) || !f.Until.Equal(want.Until) {
+		t.Errorf("filter = %+v, want %+v", f, want)
+	}
+	if body.Meta.Page != 3 || body.Meta.Limit != defaultUsersLimit {
+		t.Errorf("meta = %+v, want page 3 and the default limit for an out-of-range one", body.Meta)
+	}
+
+	for _, query := range []string{"userId=42", "from=yesterday", "to=2026-01-02"} {
+		if code := getJSON(t, r, "/admin/auth-events?"+query, adminToken, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, code)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/cache"
+	"github.com/Rakesh2908/shopgo/pkg/config"
+	"github.com/Rakesh2908/shopgo/pkg/mail"
+	"github.com/google/uuid"
+)
+
+// fakeAuthRepo extends fakeOIDCRepo with the tokens and throttles the password and session flows
+// touch. Methods it does not implement panic through the nil embedded interface.
+type fakeAuthRepo struct {
+	*fakeOIDCRepo
+
+	throttles     map[string]*user.LoginThrottle
+	counters      map[string]*user.RequestCounter
+	resetTokens   map[uuid.UUID]*user.PasswordResetToken
+	magicLinks    map[uuid.UUID]*user.MagicLinkToken
+	refreshTokens map[uuid.UUID]*user.RefreshToken
+	recoveryCodes []user.RecoveryCode
+	lockouts      []user.LoginLockout
+	apiKeys       map[uuid.UUID]*user.APIKey
+	eventFilters  []AuthEventFilter
+}
+
+func newFakeAuthRepo() *fakeAuthRepo {
+	return &fakeAuthRepo{
+		fakeOIDCRepo:  newFakeOIDCRepo(),
+		throttles:     make(map[string]*user.LoginThrottle),
+		counters:      make(map[string]*user.RequestCounter),
+		resetTokens:   make(map[uuid.UUID]*user.PasswordResetToken),
+		magicLinks:    make(map[uuid.UUID]*user.MagicLinkToken),
+		refreshTokens: make(map[uuid.UUID]*user.RefreshToken),
+		apiKeys:       make(map[uuid.UUID]*user.APIKey),
+	}
+}
+
+func (r *fakeAuthRepo) FindLoginThrottles(ctx context.Context, keys []string) ([]user.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []user.LoginThrottle
+	for _, k := range keys {
+		if t, ok := r.throttles[k]; ok {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeAuthRepo) IncrementLoginFailures(ctx context.Context, key string, resetBefore time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.throttles[key]
+	if !ok || t.LastFailureAt.Before(resetBefore) {
+		t = &user.LoginThrottle{Key: key}
+		r.throttles[key] = t
+	}
+	t.Failures++
+	t.LastFailureAt = time.Now()
+	return t.Failures, nil
+}
+
+func (r *fakeAuthRepo) IncrementRequestCount(ctx context.Context, key string, resetBefore time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.counters[key]
+	if !ok || c.LastRequestAt.Before(resetBefore) {
+		c = &user.RequestCounter{Key: key}
+		r.counters[key] = c
+	}
+	c.Requests++
+	c.LastRequestAt = time.Now()
+	return c.Requests, nil
+}
+
+func (r *fakeAuthRepo) SetLoginLockedUntil(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.throttles[key]; ok {
+		t.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *fakeAuthRepo) DeleteLoginThrottle(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.throttles, key)
+	return nil
+}
+
+func (r *fakeAuthRepo) CreateLoginLockout(ctx context.Context, l *user.LoginLockout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lockouts = append(r.lockouts, *l)
+	return nil
+}
+
+func (r *fakeAuthRepo) CreatePasswordResetToken(ctx context.Context, t *user.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.ID = uuid.New()
+	c := *t
+	r.resetTokens[t.ID] = &c
+	return nil
+}
+
+func (r *fakeAuthRepo) resetTokenCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.resetTokens)
+}
+
+func (r *fakeAuthRepo) CreateMagicLinkToken(ctx context.Context, t *user.MagicLinkToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.ID = uuid.New()
+	c := *t
+	r.magicLinks[t.ID] = &c
+	return nil
+}
+
+func (r *fakeAuthRepo) FindMagicLinkTokenByID(ctx context.Context, id uuid.UUID) (*user.MagicLinkToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.magicLinks[id]; ok {
+		c := *t
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeAuthRepo) MarkMagicLinkTokenUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.magicLinks[id]
+	if !ok || t.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	return true, nil
+}
+
+func (r *fakeAuthRepo) CountMagicLinkTokensSince(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, t := range r.magicLinks {
+		if t.UserID == userID && !t.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// expireMagicLinks moves the expiry of every magic link into the past.
+func (r *fakeAuthRepo) expireMagicLinks() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.magicLinks {
+		t.ExpiresAt = time.Now().Add(-time.Second)
+	}
+}
+
+func (r *fakeAuthRepo) CreateRefreshToken(ctx context.Context, rt *user.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rt.ID = uuid.New()
+	c := *rt
+	r.refreshTokens[rt.ID] = &c
+	return nil
+}
+
+func (r *fakeAuthRepo) FindRefreshTokenByID(ctx context.Context, id uuid.UUID) (*user.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rt, ok := r.refreshTokens[id]
+	if !ok {
+		return nil, nil
+	}
+	c := *rt
+	return &c, nil
+}
+
+func (r *fakeAuthRepo) RetireRefreshToken(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rt, ok := r.refreshTokens[id]
+	if !ok || rt.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	rt.RevokedAt = &now
+	return true, nil
+}
+
+func (r *fakeAuthRepo) MarkRefreshTokenReissued(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rt, ok := r.refreshTokens[id]
+	if !ok || rt.RevokedAt == nil || rt.ReissuedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	rt.ReissuedAt = &now
+	return true, nil
+}
+
+func (r *fakeAuthRepo) FindRefreshTokenByUserID(ctx context.Context, userID uuid.UUID) ([]user.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []user.RefreshToken
+	for _, rt := range r.refreshTokens {
+		if rt.UserID == userID && rt.RevokedAt == nil && rt.ExpiresAt.After(time.Now()) {
+			out = append(out, *rt)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastUsedAt.After(out[j].LastUsedAt) })
+	return out, nil
+}
+
+func (r *fakeAuthRepo) DeleteOtherRefreshTokenFamilies(ctx context.Context, userID, keepFamilyID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, rt := range r.refreshTokens {
+		if rt.UserID == userID && rt.FamilyID != keepFamilyID {
+			delete(r.refreshTokens, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeAuthRepo) DeleteRefreshTokensByUserID(ctx context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, rt := range r.refreshTokens {
+		if rt.UserID == userID {
+			delete(r.refreshTokens, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeAuthRepo) UpdateUserRole(ctx context.Context, userID uuid.UUID, role string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok {
+		return false, nil
+	}
+	u.Role = role
+	return true, nil
+}
+
+func (r *fakeAuthRepo) SetUserBanned(ctx context.Context, userID uuid.UUID, bannedAt *time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if ok {
+		u.BannedAt = bannedAt
+	}
+	return ok, nil
+}
+
+func (r *fakeAuthRepo) DeleteRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, rt := range r.refreshTokens {
+		if rt.FamilyID == familyID {
+			delete(r.refreshTokens, id)
+		}
+	}
+	return nil
+}
+
+// retireAt backdates the retirement of refresh token id.
+func (r *fakeAuthRepo) retireAt(id uuid.UUID, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshTokens[id].RevokedAt = &at
+}
+
+func (r *fakeAuthRepo) refreshTokenCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.refreshTokens)
+}
+
+func (r *fakeAuthRepo) UpdateUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].Password = passwordHash
+	return nil
+}
+
+func (r *fakeAuthRepo) MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok || u.Email != email {
+		return false, nil
+	}
+	u.EmailVerified = true
+	return true, nil
+}
+
+func (r *fakeAuthRepo) ConfirmEmailChange(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[userID]
+	if !ok || u.PendingEmail == "" || u.PendingEmail != email {
+		return false, nil
+	}
+	u.Email, u.PendingEmail, u.EmailVerified = email, "", true
+	return true, nil
+}
+
+func (r *fakeAuthRepo) UpdateVerificationSentAt(ctx context.Context, userID uuid.UUID, sentAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[userID]; ok {
+		u.VerificationSentAt = &sentAt
+	}
+	return nil
+}
+
+func (r *fakeAuthRepo) UpdateTwoFactor(ctx context.Context, userID uuid.UUID, secret string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.users[userID]
+	u.TOTPSecret, u.TwoFactorEnabled, u.TOTPLastCounter = secret, enabled, 0
+	return nil
+}
+
+func (r *fakeAuthRepo) AdvanceTOTPCounter(ctx context.Context, userID uuid.UUID, counter int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.users[userID]
+	if u.TOTPLastCounter >= counter {
+		return false, nil
+	}
+	u.TOTPLastCounter = counter
+	return true, nil
+}
+
+func (r *fakeAuthRepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []user.RecoveryCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.recoveryCodes[:0]
+	for _, c := range r.recoveryCodes {
+		if c.UserID != userID {
+			kept = append(kept, c)
+		}
+	}
+	r.recoveryCodes = append(kept, codes...)
+	return nil
+}
+
+func (r *fakeAuthRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.recoveryCodes {
+		if c.UserID == userID && c.CodeHash == codeHash && c.UsedAt == nil {
+			now := time.Now()
+			r.recoveryCodes[i].UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeAuthRepo) CreateAPIKey(ctx context.Context, k *user.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k.ID = uuid.New()
+	c := *k
+	r.apiKeys[k.ID] = &c
+	return nil
+}
+
+func (r *fakeAuthRepo) FindAPIKeyByHash(ctx context.Context, keyHash string) (*user.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.apiKeys {
+		if k.KeyHash == keyHash {
+			c := *k
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAuthRepo) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if k, ok := r.apiKeys[id]; ok {
+		k.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+func (r *fakeAuthRepo) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if k, ok := r.apiKeys[id]; !ok || k.UserID != userID {
+		return false, nil
+	}
+	delete(r.apiKeys, id)
+	return true, nil
+}
+
+// ListAuthEvents records filter and returns the events of filter.UserID, or all events, newest
+// first. The other filter fields are left to the repository tests.
+func (r *fakeAuthRepo) ListAuthEvents(ctx context.Context, filter AuthEventFilter, page, limit int) ([]user.AuthEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.eventFilters = append(r.eventFilters, filter)
+	var events []user.AuthEvent
+	for i := len(r.events) - 1; i >= 0; i-- {
+		e := r.events[i]
+		if filter.UserID == uuid.Nil || (e.UserID != nil && *e.UserID == filter.UserID) {
+			events = append(events, e)
+		}
+	}
+	return events, int64(len(events)), nil
+}
+
+// lastEventFilter returns the filter of the most recent ListAuthEvents call.
+func (r *fakeAuthRepo) lastEventFilter(t *testing.T) AuthEventFilter {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.eventFilters) == 0 {
+		t.Fatal("ListAuthEvents not called")
+	}
+	return r.eventFilters[len(r.eventFilters)-1]
+}
+
+// fakeMailer records the messages it is asked to send.
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *fakeMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+// last returns the most recently sent message.
+func (m *fakeMailer) last(t *testing.T) mail.Message {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.sent) == 0 {
+		t.Fatal("no email sent")
+	}
+	return m.sent[len(m.sent)-1]
+}
+
+// testJWTSecret signs HS256 access tokens and the single-purpose tokens in tests.
+const testJWTSecret = "test-secret-test-secret-test-secret"
+
+// newTestService returns an AuthService over repo and mailer with HS256 access tokens and
+// in-memory revocations. cfg may be nil.
+func newTestService(t *testing.T, repo AuthRepository, mailer mail.Sender, cfg *config.Config) *authService {
+	t.Helper()
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	cfg.JWTSecret = testJWTSecret
+	keyring, err := LoadKeyring(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocations := NewMemoryRevocationStore(cache.NewMemoryCache(time.Minute))
+	return NewAuthService(repo, cfg, mailer, keyring, revocations, nil).(*authService)
+}
+
+// waitFor polls cond until it holds, failing the test after thirty seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
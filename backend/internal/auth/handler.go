@@ -1,8 +1,13 @@
 package auth
 
 import (
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Rakesh2908/shopgo/pkg/response"
 	"github.com/gin-gonic/gin"
@@ -26,13 +31,98 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ForgotPasswordRequest is the request body for POST /auth/password/forgot.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest is the request body for POST /auth/password/reset.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// VerifyEmailRequest is the request body for POST /auth/verify-email.
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// LoginTwoFactorRequest is the request body for POST /auth/login/2fa.
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// EnableTwoFactorRequest is the request body for POST /auth/2fa/enable.
+type EnableTwoFactorRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest is the request body for POST /auth/2fa/disable.
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// UpdateProfileRequest is the request body for PATCH /auth/me. Omitted fields are left unchanged.
+type UpdateProfileRequest struct {
+	FullName *string `json:"fullName" binding:"omitempty,min=1,max=100"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+}
+
+// ChangePasswordRequest is the request body for POST /auth/password/change.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=8"`
+}
+
+// DeleteAccountRequest is the request body for DELETE /auth/me. Accounts with a password send it;
+// accounts without one send the token from the emailed confirmation link.
+type DeleteAccountRequest struct {
+	Password          string `json:"password"`
+	ConfirmationToken string `json:"confirmationToken"`
+}
+
 // RegisterRoutes registers auth routes on the given router group.
 func RegisterRoutes(rg *gin.RouterGroup, svc AuthService, authMiddleware gin.HandlerFunc) {
 	rg.POST("/register", handleRegister(svc))
 	rg.POST("/login", handleLogin(svc))
+	rg.POST("/login/2fa", handleLoginTwoFactor(svc))
 	rg.POST("/refresh", handleRefresh(svc))
 	rg.POST("/logout", handleLogout(svc))
+	rg.POST("/password/forgot", handleForgotPassword(svc))
+	rg.POST("/password/reset", handleResetPassword(svc))
+	rg.POST("/verify-email", handleVerifyEmail(svc))
+	rg.POST("/verify-email/resend", authMiddleware, handleResendVerification(svc))
 	rg.GET("/me", authMiddleware, handleMe(svc))
+	rg.PATCH("/me", authMiddleware, RejectAPIKeys(), handleUpdateProfile(svc))
+	rg.DELETE("/me", authMiddleware, RejectAPIKeys(), handleDeleteAccount(svc))
+	rg.GET("/me/activity", authMiddleware, handleListActivity(svc))
+	rg.POST("/password/change", authMiddleware, RejectAPIKeys(), handleChangePassword(svc))
+	rg.POST("/2fa/setup", authMiddleware, RejectAPIKeys(), handleSetupTwoFactor(svc))
+	rg.POST("/2fa/enable", authMiddleware, RejectAPIKeys(), handleEnableTwoFactor(svc))
+	rg.POST("/2fa/disable", authMiddleware, RejectAPIKeys(), handleDisableTwoFactor(svc))
+	rg.GET("/sessions", authMiddleware, handleListSessions(svc))
+	rg.POST("/sessions/revoke-others", authMiddleware, RejectAPIKeys(), handleRevokeOtherSessions(svc))
+	rg.DELETE("/sessions/:id", authMiddleware, RejectAPIKeys(), handleRevokeSession(svc))
+	keys := rg.Group("/api-keys", authMiddleware, RejectAPIKeys())
+	keys.GET("", handleListAPIKeys(svc))
+	keys.POST("", handleCreateAPIKey(svc))
+	keys.DELETE("/:id", handleRevokeAPIKey(svc))
+	rg.POST("/magic-link", handleRequestMagicLink(svc))
+	rg.POST("/magic-link/consume", handleConsumeMagicLink(svc))
+	rg.GET("/oidc/providers", handleListOIDCProviders(svc))
+	rg.POST("/oidc/:provider/authorize", handleOIDCAuthorize(svc))
+	rg.POST("/oidc/:provider/callback", handleOIDCCallback(svc))
+}
+
+// RegisterJWKSRoute serves the public access token verification keys at GET /.well-known/jwks.json
+// so other services can verify tokens without sharing a secret.
+func RegisterJWKSRoute(r gin.IRoutes, keyring *Keyring) {
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, keyring.JWKS())
+	})
 }
 
 func handleRegister(svc AuthService) gin.HandlerFunc {
@@ -44,7 +134,7 @@ func handleRegister(svc AuthService) gin.HandlerFunc {
 		}
 		user, err := svc.Register(c.Request.Context(), req.Email, req.Password, req.FullName)
 		if err != nil {
-			if err.Error() == "auth: email already registered" {
+			if errors.Is(err, ErrEmailTaken) {
 				response.Error(c, http.StatusConflict, "EMAIL_EXISTS", "email already registered")
 				return
 			}
@@ -62,22 +152,87 @@ func handleLogin(svc AuthService) gin.HandlerFunc {
 			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
 			return
 		}
-		accessToken, refreshCookieValue, err := svc.Login(c.Request.Context(), req.Email, req.Password)
+		result, err := svc.Login(c.Request.Context(), req.Email, req.Password, clientInfo(c))
+		if err != nil {
+			if writeLockedError(c, err) {
+				return
+			}
+			if errors.Is(err, ErrInvalidCredentials) {
+				response.Error(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid email or password")
+				return
+			}
+			if writeDisabledError(c, err) {
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "login failed")
+			return
+		}
+		writeLoginResult(c, result)
+	}
+}
+
+// handleLoginTwoFactor handles POST /auth/login/2fa — second login step for accounts with 2FA.
+func handleLoginTwoFactor(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LoginTwoFactorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		result, err := svc.CompleteTwoFactorLogin(c.Request.Context(), req.ChallengeToken, req.Code, clientInfo(c))
 		if err != nil {
-			response.Error(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid email or password")
+			if writeLockedError(c, err) || writeDisabledError(c, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, ErrInvalidChallenge):
+				response.Error(c, http.StatusUnauthorized, "INVALID_CHALLENGE", "login challenge is invalid or expired")
+			case errors.Is(err, ErrInvalidTwoFactorCode):
+				response.Error(c, http.StatusUnauthorized, "INVALID_2FA_CODE", "invalid two-factor code")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "login failed")
+			}
 			return
 		}
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     RefreshTokenCookieName,
-			Value:    refreshCookieValue,
-			Path:     "/",
-			MaxAge:   7 * 24 * 3600,
-			Secure:   true,
-			HttpOnly: true,
-			SameSite: http.SameSiteStrictMode,
+		writeLoginResult(c, result)
+	}
+}
+
+// writeLockedError writes a 429 ACCOUNT_LOCKED response with a Retry-After header if err is a lockout.
+// It reports whether a response was written.
+func writeLockedError(c *gin.Context, err error) bool {
+	var retryErr *RetryAfterError
+	if !errors.Is(err, ErrAccountLocked) || !errors.As(err, &retryErr) {
+		return false
+	}
+	setRetryAfter(c, retryErr.RetryAfter)
+	secs := int(math.Ceil(retryErr.RetryAfter.Seconds()))
+	response.Error(c, http.StatusTooManyRequests, "ACCOUNT_LOCKED",
+		"too many failed login attempts, try again in "+strconv.Itoa(secs)+" seconds")
+	return true
+}
+
+// writeDisabledError writes a 403 ACCOUNT_DISABLED response if err reports a banned account.
+// It reports whether a response was written.
+func writeDisabledError(c *gin.Context, err error) bool {
+	if !errors.Is(err, ErrAccountDisabled) {
+		return false
+	}
+	response.Error(c, http.StatusForbidden, "ACCOUNT_DISABLED", "this account has been disabled")
+	return true
+}
+
+// writeLoginResult responds with either the access token (setting the refresh cookie) or a 2FA challenge.
+func writeLoginResult(c *gin.Context, result *LoginResult) {
+	if result.TwoFactorChallenge != "" {
+		response.Success(c, http.StatusOK, gin.H{
+			"twoFactorRequired": true,
+			"challengeToken":    result.TwoFactorChallenge,
 		})
-		response.Success(c, http.StatusOK, gin.H{"accessToken": accessToken})
+		return
 	}
+	setRefreshCookie(c, result.RefreshCookieValue)
+	response.Success(c, http.StatusOK, gin.H{"accessToken": result.AccessToken})
 }
 
 func handleRefresh(svc AuthService) gin.HandlerFunc {
@@ -87,30 +242,27 @@ func handleRefresh(svc AuthService) gin.HandlerFunc {
 			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "missing refresh token")
 			return
 		}
-		accessToken, err := svc.RefreshToken(c.Request.Context(), refreshCookieValue)
+		accessToken, newRefreshCookieValue, err := svc.RefreshToken(c.Request.Context(), refreshCookieValue, clientInfo(c))
 		if err != nil {
+			clearRefreshCookie(c)
 			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired refresh token")
 			return
 		}
+		setRefreshCookie(c, newRefreshCookieValue)
 		response.Success(c, http.StatusOK, gin.H{"accessToken": accessToken})
 	}
 }
 
+// handleLogout handles POST /auth/logout — revokes the refresh cookie's session and, when sent as a
+// Bearer header, the access token.
 func handleLogout(svc AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		refreshCookieValue, _ := c.Cookie(RefreshTokenCookieName)
-		if refreshCookieValue != "" {
-			_ = svc.Logout(c.Request.Context(), refreshCookieValue)
-		}
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     RefreshTokenCookieName,
-			Value:    "",
-			Path:     "/",
-			MaxAge:   -1,
-			Secure:   true,
-			HttpOnly: true,
-			SameSite: http.SameSiteStrictMode,
-		})
+		accessToken := bearerToken(c)
+		if refreshCookieValue != "" || accessToken != "" {
+			_ = svc.Logout(c.Request.Context(), refreshCookieValue, accessToken, clientInfo(c))
+		}
+		clearRefreshCookie(c)
 		response.Success(c, http.StatusOK, nil)
 	}
 }
@@ -131,6 +283,380 @@ func handleMe(svc AuthService) gin.HandlerFunc {
 	}
 }
 
+// handleUpdateProfile handles PATCH /auth/me — changes the name and/or starts an email change.
+func handleUpdateProfile(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		var req UpdateProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		u, err := svc.UpdateProfile(c.Request.Context(), userID, ProfileUpdate{FullName: req.FullName, Email: req.Email})
+		if err != nil {
+			var retryErr *RetryAfterError
+			switch {
+			case errors.Is(err, ErrEmailTaken):
+				response.Error(c, http.StatusConflict, "EMAIL_EXISTS", "email already registered")
+			case errors.As(err, &retryErr):
+				setRetryAfter(c, retryErr.RetryAfter)
+				response.Error(c, http.StatusTooManyRequests, "RATE_LIMITED", "please wait before requesting another email")
+			case errors.Is(err, ErrUserNotFound):
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "user not found")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update profile")
+			}
+			return
+		}
+		response.Success(c, http.StatusOK, u)
+	}
+}
+
+// handleChangePassword handles POST /auth/password/change — sets a new password given the current
+// one and signs out every other device.
+func handleChangePassword(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		var req ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		refreshCookieValue, _ := c.Cookie(RefreshTokenCookieName)
+		if err := svc.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword, refreshCookieValue, clientInfo(c)); err != nil {
+			switch {
+			case errors.Is(err, ErrInvalidCredentials):
+				response.Error(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "current password is incorrect")
+			case errors.Is(err, ErrUserNotFound):
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "user not found")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to change password")
+			}
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"changed": true})
+	}
+}
+
+// handleDeleteAccount handles DELETE /auth/me — deletes the caller's account and signs them out.
+// Accounts without a password get 202 and a confirmation email first.
+func handleDeleteAccount(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		var req DeleteAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		if err := svc.DeleteAccount(c.Request.Context(), userID, req.Password, req.ConfirmationToken); err != nil {
+			switch {
+			case errors.Is(err, ErrDeletionConfirmationSent):
+				response.Success(c, http.StatusAccepted, gin.H{"deleted": false, "confirmationSent": true})
+			case errors.Is(err, ErrInvalidDeletionToken):
+				response.Error(c, http.StatusBadRequest, "INVALID_TOKEN", "invalid or expired confirmation link")
+			case errors.Is(err, ErrInvalidCredentials):
+				response.Error(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "password is incorrect")
+			case errors.Is(err, ErrUserNotFound):
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "user not found")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to delete account")
+			}
+			return
+		}
+		clearRefreshCookie(c)
+		response.Success(c, http.StatusOK, gin.H{"deleted": true})
+	}
+}
+
+// handleForgotPassword handles POST /auth/password/forgot — emails a reset link if the account exists.
+func handleForgotPassword(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		if err := svc.RequestPasswordReset(c.Request.Context(), req.Email, clientInfo(c)); err != nil {
+			var retryErr *RetryAfterError
+			if errors.As(err, &retryErr) {
+				setRetryAfter(c, retryErr.RetryAfter)
+				response.Error(c, http.StatusTooManyRequests, "RATE_LIMITED", "too many password reset requests, try again later")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to request password reset")
+			return
+		}
+		// Same response whether or not the email is registered.
+		response.Success(c, http.StatusOK, gin.H{"sent": true})
+	}
+}
+
+// handleResetPassword handles POST /auth/password/reset — sets a new password using a reset token.
+func handleResetPassword(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		if err := svc.ResetPassword(c.Request.Context(), req.Token, req.Password, clientInfo(c)); err != nil {
+			if errors.Is(err, ErrInvalidResetToken) {
+				response.Error(c, http.StatusBadRequest, "INVALID_TOKEN", "invalid or expired reset token")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to reset password")
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"reset": true})
+	}
+}
+
+// handleVerifyEmail handles POST /auth/verify-email — confirms an address using the emailed token.
+func handleVerifyEmail(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		if err := svc.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+			if errors.Is(err, ErrInvalidVerificationToken) {
+				response.Error(c, http.StatusBadRequest, "INVALID_TOKEN", "invalid or expired verification token")
+				return
+			}
+			if errors.Is(err, ErrEmailTaken) {
+				response.Error(c, http.StatusConflict, "EMAIL_EXISTS", "email already registered")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify email")
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"verified": true})
+	}
+}
+
+// handleResendVerification handles POST /auth/verify-email/resend — emails a new verification link.
+func handleResendVerification(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		if err := svc.ResendVerificationEmail(c.Request.Context(), userID); err != nil {
+			var retryErr *RetryAfterError
+			switch {
+			case errors.Is(err, ErrEmailAlreadyVerified):
+				response.Error(c, http.StatusConflict, "ALREADY_VERIFIED", "email already verified")
+			case errors.As(err, &retryErr):
+				setRetryAfter(c, retryErr.RetryAfter)
+				response.Error(c, http.StatusTooManyRequests, "RATE_LIMITED", "please wait before requesting another email")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to send verification email")
+			}
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"sent": true})
+	}
+}
+
+// handleSetupTwoFactor handles POST /auth/2fa/setup — returns a new TOTP secret and otpauth URI.
+func handleSetupTwoFactor(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		setup, err := svc.SetupTwoFactor(c.Request.Context(), userID)
+		if err != nil {
+			if errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+				response.Error(c, http.StatusConflict, "2FA_ALREADY_ENABLED", "two-factor authentication is already enabled")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to set up two-factor authentication")
+			return
+		}
+		response.Success(c, http.StatusOK, setup)
+	}
+}
+
+// handleEnableTwoFactor handles POST /auth/2fa/enable — verifies the first code and returns recovery codes.
+func handleEnableTwoFactor(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		var req EnableTwoFactorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		codes, err := svc.EnableTwoFactor(c.Request.Context(), userID, req.Code)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrTwoFactorAlreadyEnabled):
+				response.Error(c, http.StatusConflict, "2FA_ALREADY_ENABLED", "two-factor authentication is already enabled")
+			case errors.Is(err, ErrTwoFactorNotSetUp):
+				response.Error(c, http.StatusBadRequest, "2FA_NOT_SET_UP", "start two-factor setup first")
+			case errors.Is(err, ErrInvalidTwoFactorCode):
+				response.Error(c, http.StatusBadRequest, "INVALID_2FA_CODE", "invalid two-factor code")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to enable two-factor authentication")
+			}
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"recoveryCodes": codes})
+	}
+}
+
+// handleDisableTwoFactor handles POST /auth/2fa/disable — turns 2FA off given the password and a code.
+func handleDisableTwoFactor(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		var req DisableTwoFactorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		if err := svc.DisableTwoFactor(c.Request.Context(), userID, req.Password, req.Code); err != nil {
+			switch {
+			case errors.Is(err, ErrTwoFactorNotSetUp):
+				response.Error(c, http.StatusBadRequest, "2FA_NOT_ENABLED", "two-factor authentication is not enabled")
+			case errors.Is(err, ErrInvalidCredentials):
+				response.Error(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid password")
+			case errors.Is(err, ErrInvalidTwoFactorCode):
+				response.Error(c, http.StatusBadRequest, "INVALID_2FA_CODE", "invalid two-factor code")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to disable two-factor authentication")
+			}
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"disabled": true})
+	}
+}
+
+// handleListSessions handles GET /auth/sessions — lists the caller's signed-in devices.
+func handleListSessions(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		refreshCookieValue, _ := c.Cookie(RefreshTokenCookieName)
+		sessions, err := svc.ListSessions(c.Request.Context(), userID, refreshCookieValue)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list sessions")
+			return
+		}
+		response.Success(c, http.StatusOK, sessions)
+	}
+}
+
+// handleRevokeSession handles DELETE /auth/sessions/:id — signs out one device.
+func handleRevokeSession(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		sessionID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid session id")
+			return
+		}
+		if err := svc.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "session not found")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke session")
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"revoked": true})
+	}
+}
+
+// handleRevokeOtherSessions handles POST /auth/sessions/revoke-others — signs out every other device.
+func handleRevokeOtherSessions(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			return
+		}
+		refreshCookieValue, _ := c.Cookie(RefreshTokenCookieName)
+		if err := svc.RevokeOtherSessions(c.Request.Context(), userID, refreshCookieValue); err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke sessions")
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"revoked": true})
+	}
+}
+
+// setRetryAfter sets the Retry-After header, rounded up to whole seconds.
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	secs := int(math.Ceil(d.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+}
+
+// clientInfo extracts device metadata from the request.
+func clientInfo(c *gin.Context) ClientInfo {
+	return ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+// setRefreshCookie writes the httpOnly refresh token cookie.
+func setRefreshCookie(c *gin.Context, value string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   7 * 24 * 3600,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clearRefreshCookie expires the refresh token cookie.
+func clearRefreshCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
 func validationMessage(err error) string {
 	if err == nil {
 		return ""
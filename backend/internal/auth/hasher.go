@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/Rakesh2908/shopgo/pkg/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms. The algorithm is recorded in each stored hash, so hashes made
+// with an older algorithm or older parameters keep verifying and can be upgraded on login.
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+// Default argon2id parameters (RFC 9106 second recommended option, with a 64 MiB memory cost).
+const (
+	defaultArgon2Memory      = 64 * 1024 // KiB
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	argon2SaltLen            = 16
+	argon2KeyLen             = 32
+)
+
+// PasswordHasher hashes new passwords with the configured algorithm and verifies stored hashes
+// made with any supported algorithm.
+type PasswordHasher interface {
+	// Hash returns an encoded hash of password.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash, and whether hash should be replaced because
+	// it uses a different algorithm or weaker parameters than the current configuration.
+	// An empty hash never matches but takes as long as one that does not.
+	Verify(hash, password string) (ok, needsRehash bool)
+	// VerifyDummy checks password against a throwaway hash and discards the result. Callers use it
+	// when there is no account to check against, so the response time does not reveal that.
+	VerifyDummy(password string)
+}
+
+// argon2Params are the tunable argon2id costs.
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// passwordHasher implements PasswordHasher for argon2id (PHC string format) and bcrypt.
+// At most cap(slots) hashes are computed at once, since each argon2id hash holds its whole memory
+// cost and the login and registration endpoints can be called by anyone.
+type passwordHasher struct {
+	algorithm  string
+	argon2     argon2Params
+	bcryptCost int
+	slots      chan struct{}
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+// NewPasswordHasher returns a PasswordHasher configured from cfg. Zero values fall back to
+// argon2id with the defaults above, or bcrypt cost 12, and to one concurrent hash per CPU.
+func NewPasswordHasher(cfg *config.Config) PasswordHasher {
+	concurrency := cfg.PasswordHashConcurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	h := &passwordHasher{
+		slots:     make(chan struct{}, concurrency),
+		algorithm: HashArgon2id,
+		argon2: argon2Params{
+			memory:      defaultArgon2Memory,
+			iterations:  defaultArgon2Iterations,
+			parallelism: defaultArgon2Parallelism,
+		},
+		bcryptCost: bcryptCost,
+	}
+	if cfg.PasswordHashAlgorithm == HashBcrypt {
+		h.algorithm = HashBcrypt
+	}
+	if cfg.Argon2MemoryKiB > 0 {
+		h.argon2.memory = uint32(cfg.Argon2MemoryKiB)
+	}
+	if cfg.Argon2Iterations > 0 {
+		h.argon2.iterations = uint32(cfg.Argon2Iterations)
+	}
+	if cfg.Argon2Parallelism > 0 && cfg.Argon2Parallelism <= 255 {
+		h.argon2.parallelism = uint8(cfg.Argon2Parallelism)
+	}
+	if cfg.BcryptCost >= bcrypt.MinCost && cfg.BcryptCost <= bcrypt.MaxCost {
+		h.bcryptCost = cfg.BcryptCost
+	}
+	return h
+}
+
+// acquire waits for a free hashing slot and returns the function that frees it.
+func (h *passwordHasher) acquire() func() {
+	h.slots <- struct{}{}
+	return func() { <-h.slots }
+}
+
+// Hash implements PasswordHasher.
+func (h *passwordHasher) Hash(password string) (string, error) {
+	defer h.acquire()()
+	return h.hash(password)
+}
+
+// hash computes a new hash of password without taking a slot.
+func (h *passwordHasher) hash(password string) (string, error) {
+	if h.algorithm == HashBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := h.argon2
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify implements PasswordHasher.
+func (h *passwordHasher) Verify(hash, password string) (bool, bool) {
+	if hash == "" {
+		// Accounts created through social login have no password.
+		h.VerifyDummy(password)
+		return false, false
+	}
+	defer h.acquire()()
+	return h.verify(hash, password)
+}
+
+// VerifyDummy implements PasswordHasher. The throwaway hash is made on first use with the current
+// algorithm and parameters, so it costs the same as verifying a real, up-to-date hash.
+func (h *passwordHasher) VerifyDummy(password string) {
+	defer h.acquire()()
+	h.dummyOnce.Do(func() {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return
+		}
+		h.dummy, _ = h.hash(base64.RawStdEncoding.EncodeToString(secret))
+	})
+	if h.dummy != "" {
+		h.verify(h.dummy, password)
+	}
+}
+
+// verify checks password against hash without taking a slot.
+func (h *passwordHasher) verify(hash, password string) (bool, bool) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		p, salt, key, err := decodeArgon2Hash(hash)
+		if err != nil {
+			return false, false
+		}
+		got := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, false
+		}
+		return true, h.algorithm != HashArgon2id || p != h.argon2
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false
+	}
+	if h.algorithm != HashBcrypt {
+		return true, true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || cost < h.bcryptCost
+}
+
+// decodeArgon2Hash parses "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>".
+func decodeArgon2Hash(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, fmt.Errorf("auth: malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("auth: unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("auth: malformed argon2id parameters")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("auth: malformed argon2id key")
+	}
+	return p, salt, key, nil
+}
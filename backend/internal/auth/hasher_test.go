@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Rakesh2908/shopgo/pkg/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap hashing costs keep the tests fast; only the relation between them matters.
+func testHasherConfig(algorithm string) *config.Config {
+	return &config.Config{
+		PasswordHashAlgorithm: algorithm,
+		Argon2MemoryKiB:       64,
+		Argon2Iterations:      2,
+		Argon2Parallelism:     1,
+		BcryptCost:            bcrypt.MinCost + 1,
+	}
+}
+
+func mustHash(t *testing.T, h PasswordHasher, password string) string {
+	t.Helper()
+	hash, err := h.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestPasswordHasherVerify(t *testing.T) {
+	argon := NewPasswordHasher(testHasherConfig(HashArgon2id))
+	weakArgonCfg := testHasherConfig(HashArgon2id)
+	weakArgonCfg.Argon2Iterations = 1
+	weakArgon := NewPasswordHasher(weakArgonCfg)
+	bcryptHasher := NewPasswordHasher(testHasherConfig(HashBcrypt))
+	weakBcryptCfg := testHasherConfig(HashBcrypt)
+	weakBcryptCfg.BcryptCost = bcrypt.MinCost
+	weakBcrypt := NewPasswordHasher(weakBcryptCfg)
+
+	argonHash := mustHash(t, argon, "hunter2")
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=64,t=2,p=1$") {
+		t.Fatalf("argon2id hash %q does not record its parameters", argonHash)
+	}
+	tests := []struct {
+		name        string
+		hasher      PasswordHasher
+		hash        string
+		password    string
+		ok, upgrade bool
+	}{
+		{"current argon2id", argon, argonHash, "hunter2", true, false},
+		{"wrong password", argon, argonHash, "hunter3", false, false},
+		{"weaker argon2id", argon, mustHash(t, weakArgon, "hunter2"), "hunter2", true, true},
+		{"bcrypt under argon2id", argon, mustHash(t, bcryptHasher, "hunter2"), "hunter2", true, true},
+		{"current bcrypt", bcryptHasher, mustHash(t, bcryptHasher, "hunter2"), "hunter2", true, false},
+		{"cheaper bcrypt", bcryptHasher, mustHash(t, weakBcrypt, "hunter2"), "hunter2", true, true},
+		{"argon2id under bcrypt", bcryptHasher, argonHash, "hunter2", true, true},
+		{"no password", argon, "", "", false, false},
+		{"malformed argon2id", argon, "$argon2id$v=19$m=64$salt$key", "hunter2", false, false},
+		{"unknown version", argon, strings.Replace(argonHash, "v=19", "v=16", 1), "hunter2", false, false},
+	}
+	for _, tt := range tests {
+		ok, upgrade := tt.hasher.Verify(tt.hash, tt.password)
+		if ok != tt.ok || upgrade != tt.upgrade {
+			t.Errorf("%s: Verify = %v, %v; want %v, %v", tt.name, ok, upgrade, tt.ok, tt.upgrade)
+		}
+	}
+}
+
+func TestLoginUpgradesBcryptHash(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	old := mustHash(t, NewPasswordHasher(testHasherConfig(HashBcrypt)), "correct horse")
+	if err := repo.UpdateUserPassword(context.Background(), u.ID, old); err != nil {
+		t.Fatal(err)
+	}
+	svc := newTestService(t, repo, &fakeMailer{}, testHasherConfig(HashArgon2id))
+
+	if _, err := svc.Login(context.Background(), "member@example.com", "correct horse", ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	upgraded, _ := repo.FindUserByID(context.Background(), u.ID)
+	if !strings.HasPrefix(upgraded.Password, "$argon2id$") {
+		t.Fatalf("stored hash = %q, want an argon2id hash", upgraded.Password)
+	}
+	if _, err := svc.Login(context.Background(), "member@example.com", "correct horse", ClientInfo{}); err != nil {
+		t.Fatalf("login with the upgraded hash: %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/Rakesh2908/shopgo/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// verificationKey is a public key that access tokens may be signed with, identified by kid.
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// Keyring signs access tokens with one active key and verifies them against every configured key,
+// so a new signing key can be rolled out while tokens signed with the previous one stay valid.
+//
+// Without asymmetric keys configured it falls back to HS256 with JWTSecret, as before. JWTSecret is
+// required either way, since single-purpose tokens are always signed with keys derived from it.
+type Keyring struct {
+	activeID     string
+	activeMethod jwt.SigningMethod
+	activeKey    interface{}
+	verifiers    map[string]verificationKey
+	hmacSecret   []byte
+}
+
+// LoadKeyring builds the access token keyring from config. JWT_SIGNING_KEY_FILE is a PEM private key
+// (RSA for RS256, Ed25519 for EdDSA) used for new tokens; JWT_VERIFICATION_KEY_FILES is a comma-separated
+// list of PEM public or private keys from previous rotations that are still accepted.
+// Key IDs are the RFC 7638 thumbprints of the public keys.
+//
+// JWT_SECRET must be set even with asymmetric keys: email verification, 2FA challenge and OIDC state
+// tokens are signed with keys derived from it, and an empty secret would make them forgeable.
+func LoadKeyring(cfg *config.Config) (*Keyring, error) {
+	if cfg.JWTSecret == "" {
+		return nil, errors.New("auth: JWT_SECRET must be set; it signs email verification, 2FA and OIDC state tokens")
+	}
+	if cfg.JWTSigningKeyFile == "" {
+		return &Keyring{activeMethod: jwt.SigningMethodHS256, activeKey: []byte(cfg.JWTSecret), hmacSecret: []byte(cfg.JWTSecret)}, nil
+	}
+	signer, err := readPrivateKey(cfg.JWTSigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	active, err := newVerificationKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	k := &Keyring{
+		activeID:     active.id,
+		activeMethod: active.method,
+		activeKey:    signer,
+		verifiers:    map[string]verificationKey{active.id: active},
+	}
+	for _, path := range strings.Split(cfg.JWTVerificationKeyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		pub, err := readPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		vk, err := newVerificationKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		k.verifiers[vk.id] = vk
+	}
+	return k, nil
+}
+
+// Sign signs claims with the active key and sets the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.activeMethod, claims)
+	if k.activeID != "" {
+		token.Header["kid"] = k.activeID
+	}
+	return token.SignedString(k.activeKey)
+}
+
+// Keyfunc resolves the verification key for a parsed token from its kid header.
+func (k *Keyring) Keyfunc(t *jwt.Token) (interface{}, error) {
+	if k.hmacSecret != nil {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("auth: unexpected signing method")
+		}
+		return k.hmacSecret, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	vk, ok := k.verifiers[kid]
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown signing key")
+	}
+	if t.Method.Alg() != vk.method.Alg() {
+		return nil, fmt.Errorf("auth: unexpected signing method")
+	}
+	return vk.public, nil
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every verification key as a JWK set. It is empty in HS256 mode.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(k.verifiers))}
+	if k.activeID != "" {
+		set.Keys = append(set.Keys, toJWK(k.verifiers[k.activeID]))
+	}
+	for id, vk := range k.verifiers {
+		if id != k.activeID {
+			set.Keys = append(set.Keys, toJWK(vk))
+		}
+	}
+	return set
+}
+
+// newVerificationKey derives the signing method and kid for a supported public key.
+func newVerificationKey(pub crypto.PublicKey) (verificationKey, error) {
+	vk := verificationKey{public: pub}
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		vk.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		vk.method = jwt.SigningMethodEdDSA
+	default:
+		return vk, fmt.Errorf("auth: unsupported key type %T", pub)
+	}
+	vk.id = thumbprint(toJWK(vk))
+	return vk, nil
+}
+
+// toJWK encodes a verification key as a JWK.
+func toJWK(vk verificationKey) JWK {
+	enc := base64.RawURLEncoding
+	jwk := JWK{Kid: vk.id, Use: "sig", Alg: vk.method.Alg()}
+	switch pub := vk.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = enc.EncodeToString(pub.N.Bytes())
+		jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = enc.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// thumbprint returns the RFC 7638 SHA-256 thumbprint of jwk, base64url-encoded.
+func thumbprint(jwk JWK) string {
+	// Required members only, in lexicographic order.
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	b, _ := json.Marshal(members)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// readPrivateKey loads an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) private key from a PEM file.
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parse private key %s: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("auth: unsupported private key in %s", path)
+	}
+	return signer, nil
+}
+
+// readPublicKey loads a public key from a PEM file holding either a public or a private key.
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(block.Type, "PRIVATE KEY") {
+		signer, err := readPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parse public key %s: %w", path, err)
+	}
+	return pub, nil
+}
+
+// readPEM returns the first PEM block in the file at path.
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("auth: no PEM data in %s", path)
+	}
+	return block, nil
+}
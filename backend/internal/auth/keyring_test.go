@@ -0,0 +1,289 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writePrivateKey stores key as a PKCS#8 PEM file in dir and returns its path.
+func writePrivateKey(t *testing.T, dir, name string, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, name, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// writePublicKey stores the public half of key as a PKIX PEM file in dir and returns its path.
+func writePublicKey(t *testing.T, dir, name string, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, name, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// writePEM stores block in dir under name and returns the path.
+func writePEM(t *testing.T, dir, name string, block *pem.Block) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newRSAKey generates an RSA key for RS256.
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// newEd25519Key generates an Ed25519 key for EdDSA.
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// loadTestKeyring loads a keyring that signs with signingKeyFile, or HS256 if it is empty, and
+// also accepts verificationKeyFiles.
+func loadTestKeyring(t *testing.T, signingKeyFile string, verificationKeyFiles ...string) *Keyring {
+	t.Helper()
+	k, err := LoadKeyring(&config.Config{
+		JWTSecret:               testJWTSecret,
+		JWTSigningKeyFile:       signingKeyFile,
+		JWTVerificationKeyFiles: strings.Join(verificationKeyFiles, ","),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// testClaims returns claims valid for a minute.
+func testClaims() jwt.Claims {
+	return jwt.RegisteredClaims{Subject: "user", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+}
+
+// verify parses token with k and returns the error, if any.
+func verify(k *Keyring, token string) error {
+	_, err := jwt.Parse(token, k.Keyfunc)
+	return err
+}
+
+func TestKeyringSignsAndVerifies(t *testing.T) {
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+		kty  string
+	}{
+		{"RS256", newRSAKey(t), "RS256", "RSA"},
+		{"EdDSA", newEd25519Key(t), "EdDSA", "OKP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			k := loadTestKeyring(t, writePrivateKey(t, dir, "signing.pem", tt.key))
+
+			token, err := k.Sign(testClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := jwt.Parse(token, k.Keyfunc)
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if parsed.Method.Alg() != tt.alg {
+				t.Errorf("alg = %s, want %s", parsed.Method.Alg(), tt.alg)
+			}
+			kid, _ := parsed.Header["kid"].(string)
+
+			set := k.JWKS()
+			if len(set.Keys) != 1 {
+				t.Fatalf("JWKS has %d keys, want 1", len(set.Keys))
+			}
+			jwk := set.Keys[0]
+			if jwk.Kid != kid || jwk.Kty != tt.kty || jwk.Alg != tt.alg || jwk.Use != "sig" {
+				t.Errorf("JWK = %+v, want kid %s, kty %s, alg %s", jwk, kid, tt.kty, tt.alg)
+			}
+			if !jwkPublicKey(t, jwk).(interface{ Equal(crypto.PublicKey) bool }).Equal(tt.key.Public()) {
+				t.Error("JWK does not encode the signing key's public key")
+			}
+
+			// The kid is the key's thumbprint, whichever form the key is loaded from.
+			fromPublic := loadTestKeyring(t, writePrivateKey(t, dir, "other.pem", newEd25519Key(t)), writePublicKey(t, dir, "public.pem", tt.key))
+			if err := verify(fromPublic, token); err != nil {
+				t.Errorf("verify with the public key file: %v", err)
+			}
+		})
+	}
+}
+
+// jwkPublicKey decodes the public key a JWK describes.
+func jwkPublicKey(t *testing.T, jwk JWK) crypto.PublicKey {
+	t.Helper()
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	switch jwk.Kty {
+	case "RSA":
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(decode(jwk.N)), E: int(new(big.Int).SetBytes(decode(jwk.E)).Int64())}
+	case "OKP":
+		return ed25519.PublicKey(decode(jwk.X))
+	}
+	t.Fatalf("unexpected kty %q", jwk.Kty)
+	return nil
+}
+
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, newKey, strangerKey := newRSAKey(t), newEd25519Key(t), newEd25519Key(t)
+	oldPath := writePrivateKey(t, dir, "old.pem", oldKey)
+	newPath := writePrivateKey(t, dir, "new.pem", newKey)
+
+	before := loadTestKeyring(t, oldPath)
+	oldToken, err := before.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After the rotation the old key is only listed for verification, here as its public half.
+	after := loadTestKeyring(t, newPath, " "+writePublicKey(t, dir, "old.pub.pem", oldKey), "")
+	newToken, err := after.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(after, oldToken); err != nil {
+		t.Errorf("token signed by the retired key: %v", err)
+	}
+	if err := verify(after, newToken); err != nil {
+		t.Errorf("token signed by the active key: %v", err)
+	}
+	if err := verify(before, newToken); err == nil {
+		t.Error("the old keyring accepted a token signed with a key it does not know")
+	}
+
+	oldKid, newKid := kidOf(t, oldToken), kidOf(t, newToken)
+	if oldKid == newKid {
+		t.Fatal("both keys have the same kid")
+	}
+	set := after.JWKS()
+	if len(set.Keys) != 2 || set.Keys[0].Kid != newKid || set.Keys[1].Kid != oldKid {
+		t.Errorf("JWKS kids = %v, want the active %s first, then %s", jwksKids(set), newKid, oldKid)
+	}
+
+	stranger := loadTestKeyring(t, writePrivateKey(t, dir, "stranger.pem", strangerKey))
+	strangerToken, err := stranger.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(after, strangerToken); err == nil {
+		t.Error("accepted a token signed by an unknown key")
+	}
+}
+
+func TestKeyringRejectsMismatchedTokens(t *testing.T) {
+	dir := t.TempDir()
+	k := loadTestKeyring(t, writePrivateKey(t, dir, "signing.pem", newRSAKey(t)))
+	kid := k.JWKS().Keys[0].Kid
+
+	// An HS256 token naming the RSA key must not be checked with the key's public bytes as a secret.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	forged.Header["kid"] = kid
+	forgedToken, err := forged.SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(k, forgedToken); err == nil {
+		t.Error("accepted an HS256 token for an RS256 key")
+	}
+
+	// A token without a kid cannot be matched to a key.
+	anon := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	anonToken, err := anon.SignedString(newEd25519Key(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(k, anonToken); err == nil {
+		t.Error("accepted a token without a kid")
+	}
+}
+
+func TestKeyringHS256Fallback(t *testing.T) {
+	if _, err := LoadKeyring(&config.Config{}); err == nil {
+		t.Fatal("LoadKeyring without JWT_SECRET succeeded")
+	}
+
+	k := loadTestKeyring(t, "")
+	token, err := k.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jwt.Parse(token, k.Keyfunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Method.Alg() != "HS256" || parsed.Header["kid"] != nil {
+		t.Errorf("alg %s, kid %v; want HS256 without a kid", parsed.Method.Alg(), parsed.Header["kid"])
+	}
+	if keys := k.JWKS().Keys; len(keys) != 0 {
+		t.Errorf("JWKS = %v, want no keys in HS256 mode", keys)
+	}
+
+	rsaSigned, err := loadTestKeyring(t, writePrivateKey(t, t.TempDir(), "signing.pem", newRSAKey(t))).Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(k, rsaSigned); err == nil {
+		t.Error("the HS256 keyring accepted an RS256 token")
+	}
+}
+
+// kidOf returns the kid header of token without verifying it.
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+// jwksKids returns the kids in set, in order.
+func jwksKids(set JWKSet) []string {
+	kids := make([]string, len(set.Keys))
+	for i, k := range set.Keys {
+		kids[i] = k.Kid
+	}
+	return kids
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/mail"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MagicLinkNonceCookieName is the cookie that binds a magic link to the browser that requested it.
+	MagicLinkNonceCookieName = "magic_link_nonce"
+	// MagicLinkTTL is how long a magic link stays valid.
+	MagicLinkTTL = 15 * time.Minute
+	// magicLinkCooldown is the minimum time between two magic link emails for one user.
+	magicLinkCooldown = time.Minute
+)
+
+// RequestMagicLink emails a single-use login link to the user with the given email and returns the
+// nonce to store in the requesting browser. The link only works together with that nonce. The
+// browser's existing nonce is reused when given, so earlier links keep working. A nonce is returned
+// even when no such user exists, or when a link was sent within the last minute, so callers cannot
+// probe for registered addresses.
+func (s *authService) RequestMagicLink(ctx context.Context, email, nonce string) (string, error) {
+	if nonce == "" {
+		var err error
+		if nonce, err = randomURLToken(); err != nil {
+			return "", err
+		}
+	}
+	u, err := s.repo.FindUserByEmail(ctx, email)
+	if err != nil {
+		return "", err
+	}
+	if u == nil || u.BannedAt != nil {
+		return nonce, nil
+	}
+	now := time.Now()
+	recent, err := s.repo.CountMagicLinkTokensSince(ctx, u.ID, now.Add(-magicLinkCooldown))
+	if err != nil {
+		return "", err
+	}
+	if recent > 0 {
+		return nonce, nil
+	}
+	rawHex, hash, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+	t := &user.MagicLinkToken{
+		UserID:    u.ID,
+		TokenHash: hash,
+		NonceHash: hashNonce(nonce),
+		Email:     u.Email,
+		ExpiresAt: now.Add(MagicLinkTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateMagicLinkToken(ctx, t); err != nil {
+		return "", err
+	}
+	link := s.config.AppBaseURL + "/magic-link?token=" + url.QueryEscape(t.ID.String()+cookieSeparator+rawHex)
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Your ShopGo sign-in link",
+		Body: "Open this link within 15 minutes to sign in to ShopGo:\n" + link + "\n\n" +
+			"The link only works in the browser where you asked for it.\n" +
+			"If you did not request this, you can ignore this email.",
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("auth: send magic link email: %v", err)
+	}
+	return nonce, nil
+}
+
+// ConsumeMagicLink exchanges a magic link token and the requesting browser's nonce for tokens, or a
+// 2FA challenge if the account requires one. Because the link proves control of the inbox, the
+// address it was sent to is marked verified.
+func (s *authService) ConsumeMagicLink(ctx context.Context, token, nonce string, client ClientInfo) (*LoginResult, error) {
+	tokenID, rawToken, err := parseTokenValue(token)
+	if err != nil || nonce == "" {
+		return nil, ErrInvalidMagicLink
+	}
+	t, err := s.repo.FindMagicLinkTokenByID(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.UsedAt != nil || t.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidMagicLink
+	}
+	if subtle.ConstantTimeCompare([]byte(t.NonceHash), []byte(hashNonce(nonce))) != 1 {
+		return nil, ErrInvalidMagicLink
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(t.TokenHash), []byte(rawToken)); err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+	used, err := s.repo.MarkMagicLinkTokenUsed(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidMagicLink
+	}
+	u, err := s.repo.FindUserByID(ctx, t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidMagicLink
+	}
+	if !u.EmailVerified && u.Email == t.Email {
+		if _, err := s.repo.MarkEmailVerified(ctx, u.ID, t.Email); err != nil {
+			return nil, err
+		}
+		u.EmailVerified = true
+	}
+	result, err := s.firstFactorResult(ctx, u, client)
+	s.recordLogin(ctx, EventLoginMagicLink, u, client, result, err)
+	return result, err
+}
+
+// hashNonce returns the hex SHA-256 of a browser nonce for storage.
+func hashNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
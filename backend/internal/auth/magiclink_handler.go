@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// MagicLinkRequest is the request body for POST /auth/magic-link.
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ConsumeMagicLinkRequest is the request body for POST /auth/magic-link/consume.
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// handleRequestMagicLink handles POST /auth/magic-link — emails a sign-in link if the account exists
+// and sets the nonce cookie the link must be opened with.
+func handleRequestMagicLink(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MagicLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		existing, _ := c.Cookie(MagicLinkNonceCookieName)
+		nonce, err := svc.RequestMagicLink(c.Request.Context(), req.Email, existing)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to send sign-in link")
+			return
+		}
+		setMagicLinkNonceCookie(c, nonce, int(MagicLinkTTL.Seconds()))
+		// Same response whether or not the email is registered.
+		response.Success(c, http.StatusOK, gin.H{"sent": true})
+	}
+}
+
+// handleConsumeMagicLink handles POST /auth/magic-link/consume — signs in with an emailed link.
+func handleConsumeMagicLink(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ConsumeMagicLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		nonce, _ := c.Cookie(MagicLinkNonceCookieName)
+		result, err := svc.ConsumeMagicLink(c.Request.Context(), req.Token, nonce, clientInfo(c))
+		if err != nil {
+			if writeDisabledError(c, err) {
+				return
+			}
+			if errors.Is(err, ErrInvalidMagicLink) {
+				response.Error(c, http.StatusBadRequest, "INVALID_TOKEN",
+					"sign-in link is invalid or expired, or was opened in a different browser")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "login failed")
+			return
+		}
+		setMagicLinkNonceCookie(c, "", -1)
+		writeLoginResult(c, result)
+	}
+}
+
+// setMagicLinkNonceCookie writes (or, with maxAge < 0, expires) the httpOnly magic link nonce cookie.
+func setMagicLinkNonceCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     MagicLinkNonceCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
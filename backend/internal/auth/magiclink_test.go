@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// magicLinkBrowser is one browser using the magic link routes; it keeps the nonce cookie it is given.
+type magicLinkBrowser struct {
+	r     http.Handler
+	nonce string
+}
+
+// newMagicLinkRouter serves the magic link request and consume routes.
+func newMagicLinkRouter(svc AuthService) http.Handler {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/magic-link", handleRequestMagicLink(svc))
+	r.POST("/magic-link/consume", handleConsumeMagicLink(svc))
+	return r
+}
+
+// post sends body to path with the browser's nonce cookie and keeps any nonce cookie set in reply.
+func (b *magicLinkBrowser) post(path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if b.nonce != "" {
+		req.AddCookie(&http.Cookie{Name: MagicLinkNonceCookieName, Value: b.nonce})
+	}
+	w := httptest.NewRecorder()
+	b.r.ServeHTTP(w, req)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == MagicLinkNonceCookieName {
+			b.nonce = c.Value
+		}
+	}
+	return w
+}
+
+// request asks for a sign-in link for email.
+func (b *magicLinkBrowser) request(t *testing.T, email string) {
+	t.Helper()
+	if w := b.post("/magic-link", MagicLinkRequest{Email: email}); w.Code != http.StatusOK {
+		t.Fatalf("request link: status %d, body %s", w.Code, w.Body)
+	}
+	if b.nonce == "" {
+		t.Fatal("no nonce cookie set")
+	}
+}
+
+// consume opens the link carrying token.
+func (b *magicLinkBrowser) consume(token string) *httptest.ResponseRecorder {
+	return b.post("/magic-link/consume", ConsumeMagicLinkRequest{Token: token})
+}
+
+// wantInvalidLink fails the test unless w is the response to a rejected link.
+func wantInvalidLink(t *testing.T, what string, w *httptest.ResponseRecorder) {
+	t.Helper()
+	if w.Code != http.StatusBadRequest || !bytes.Contains(w.Body.Bytes(), []byte("INVALID_TOKEN")) {
+		t.Errorf("%s: status %d, body %s; want 400 INVALID_TOKEN", what, w.Code, w.Body)
+	}
+}
+
+func TestMagicLinkSignsInOnce(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", false)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	browser := &magicLinkBrowser{r: newMagicLinkRouter(svc)}
+
+	browser.request(t, u.Email)
+	token, nonce := emailedToken(t, mailer, u.Email), browser.nonce
+	w := browser.consume(token)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte("accessToken")) {
+		t.Fatalf("consume: status %d, body %s", w.Code, w.Body)
+	}
+	if browser.nonce != "" {
+		t.Errorf("nonce cookie %q not cleared after signing in", browser.nonce)
+	}
+	repo.mu.Lock()
+	last := repo.events[len(repo.events)-1]
+	repo.mu.Unlock()
+	if last.Type != EventLoginMagicLink || last.Outcome != OutcomeSuccess {
+		t.Errorf("last event = %s/%s, want a successful magic link login", last.Type, last.Outcome)
+	}
+
+	// Even with the right nonce the link works only once.
+	again := &magicLinkBrowser{r: browser.r, nonce: nonce}
+	wantInvalidLink(t, "second use", again.consume(token))
+}
+
+func TestMagicLinkVerifiesEmail(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", false)
+	moved := repo.addUser("moved@example.com", false)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	r := newMagicLinkRouter(svc)
+	ctx := context.Background()
+
+	browser := &magicLinkBrowser{r: r}
+	browser.request(t, u.Email)
+	if w := browser.consume(emailedToken(t, mailer, u.Email)); w.Code != http.StatusOK {
+		t.Fatalf("consume: status %d, body %s", w.Code, w.Body)
+	}
+	if got, _ := repo.FindUserByID(ctx, u.ID); !got.EmailVerified {
+		t.Error("signing in with a magic link did not verify the address it was sent to")
+	}
+
+	// A link sent to an address the account no longer has proves nothing about the current one.
+	other := &magicLinkBrowser{r: r}
+	other.request(t, moved.Email)
+	token := emailedToken(t, mailer, moved.Email)
+	repo.mu.Lock()
+	repo.users[moved.ID].Email = "new@example.com"
+	repo.mu.Unlock()
+	if w := other.consume(token); w.Code != http.StatusOK {
+		t.Fatalf("consume after an email change: status %d, body %s", w.Code, w.Body)
+	}
+	if got, _ := repo.FindUserByID(ctx, moved.ID); got.EmailVerified {
+		t.Error("a link for the old address verified the new one")
+	}
+}
+
+func TestMagicLinkIsBoundToTheRequestingBrowser(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	r := newMagicLinkRouter(svc)
+
+	browser := &magicLinkBrowser{r: r}
+	browser.request(t, u.Email)
+	token := emailedToken(t, mailer, u.Email)
+
+	// A forwarded link opened elsewhere fails, with no nonce or with that browser's own.
+	wantInvalidLink(t, "without a nonce", (&magicLinkBrowser{r: r}).consume(token))
+	elsewhere := &magicLinkBrowser{r: r}
+	elsewhere.request(t, "someone@example.com")
+	wantInvalidLink(t, "with another browser's nonce", elsewhere.consume(token))
+
+	// The failed attempts do not use the link up for its owner.
+	if w := browser.consume(token); w.Code != http.StatusOK {
+		t.Fatalf("consume in the requesting browser: status %d, body %s", w.Code, w.Body)
+	}
+}
+
+func TestMagicLinkExpires(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+
+	browser := &magicLinkBrowser{r: newMagicLinkRouter(svc)}
+	browser.request(t, u.Email)
+	token := emailedToken(t, mailer, u.Email)
+	repo.expireMagicLinks()
+	wantInvalidLink(t, "expired link", browser.consume(token))
+}
+
+func TestRequestMagicLinkDoesNotRevealAccounts(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	r := newMagicLinkRouter(svc)
+
+	// An unknown address gets the same response and a nonce cookie, but no email.
+	(&magicLinkBrowser{r: r}).request(t, "nobody@example.com")
+	if n := mailer.count(); n != 0 {
+		t.Fatalf("%d emails sent for an unknown address", n)
+	}
+
+	// Within the cooldown a second request sends nothing, and the browser keeps its nonce so the
+	// first link still works.
+	browser := &magicLinkBrowser{r: r}
+	browser.request(t, u.Email)
+	nonce := browser.nonce
+	browser.request(t, u.Email)
+	if n := mailer.count(); n != 1 {
+		t.Fatalf("%d emails sent, want 1 within the cooldown", n)
+	}
+	if browser.nonce != nonce {
+		t.Error("a repeated request replaced the browser's nonce")
+	}
+	if w := browser.consume(emailedToken(t, mailer, u.Email)); w.Code != http.StatusOK {
+		t.Errorf("consume: status %d, body %s", w.Code, w.Body)
+	}
+}
@@ -12,8 +12,16 @@ import (
 // ContextKeyUserID is the gin context key for the authenticated user's ID.
 const ContextKeyUserID = "userID"
 
-// JWTMiddleware returns a gin handler that validates the Bearer token and sets userID in context.
-// Returns 401 if the Authorization header is missing or the token is invalid.
+// ContextKeyRole is the gin context key for the authenticated user's role.
+const ContextKeyRole = "role"
+
+// ContextKeyAPIKeyID is the gin context key for the ID of the API key used, if any.
+const ContextKeyAPIKeyID = "apiKeyID"
+
+// JWTMiddleware returns a gin handler that authenticates the caller and sets userID and role in context.
+// It accepts either "Bearer <access token>" or "ApiKey <key>". Requests made with a read-only API key
+// are limited to safe methods.
+// Returns 401 if the Authorization header is missing or the credential is invalid.
 func JWTMiddleware(svc AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -23,23 +31,73 @@ func JWTMiddleware(svc AuthService) gin.HandlerFunc {
 			return
 		}
 		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		if len(parts) != 2 {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "invalid authorization format")
+			c.Abort()
+			return
+		}
+		var identity *Identity
+		var err error
+		switch {
+		case strings.EqualFold(parts[0], "Bearer"):
+			identity, err = svc.ParseAccessToken(c.Request.Context(), parts[1])
+		case strings.EqualFold(parts[0], "ApiKey"):
+			identity, err = svc.ParseAPIKey(c.Request.Context(), parts[1])
+		default:
 			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "invalid authorization format")
 			c.Abort()
 			return
 		}
-		tokenString := parts[1]
-		userID, err := svc.ParseAccessToken(tokenString)
 		if err != nil {
 			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired token")
 			c.Abort()
 			return
 		}
-		c.Set(ContextKeyUserID, userID)
+		if !identity.HasScope(requiredScope(c.Request.Method)) {
+			response.Error(c, http.StatusForbidden, "INSUFFICIENT_SCOPE", "api key does not allow this request")
+			c.Abort()
+			return
+		}
+		c.Set(ContextKeyUserID, identity.UserID)
+		c.Set(ContextKeyRole, identity.Role)
+		if identity.APIKeyID != uuid.Nil {
+			c.Set(ContextKeyAPIKeyID, identity.APIKeyID)
+		}
 		c.Next()
 	}
 }
 
+// requiredScope returns the API key scope needed for an HTTP method.
+func requiredScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ScopeRead
+	}
+	return ScopeWrite
+}
+
+// RejectAPIKeys returns a gin handler that refuses requests authenticated with an API key with 403,
+// so a leaked key cannot be used to mint or manage other credentials. It must run after JWTMiddleware.
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(ContextKeyAPIKeyID); ok {
+			response.Error(c, http.StatusForbidden, "FORBIDDEN", "this endpoint requires an interactive login")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// bearerToken returns the token from an "Authorization: Bearer <token>" header, or "".
+func bearerToken(c *gin.Context) string {
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return parts[1]
+}
+
 // GetUserIDFromContext returns the user ID from gin context set by JWTMiddleware.
 // Call only after JWTMiddleware has run. Returns uuid.Nil if not set.
 func GetUserIDFromContext(c *gin.Context) uuid.UUID {
@@ -50,3 +108,49 @@ func GetUserIDFromContext(c *gin.Context) uuid.UUID {
 	id, _ := v.(uuid.UUID)
 	return id
 }
+
+// GetRoleFromContext returns the role set by JWTMiddleware, or "" if not set.
+func GetRoleFromContext(c *gin.Context) string {
+	return c.GetString(ContextKeyRole)
+}
+
+// RequireRole returns a gin handler that rejects callers whose role is not one of roles with 403.
+// It must run after JWTMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := GetRoleFromContext(c)
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		response.Error(c, http.StatusForbidden, "FORBIDDEN", "insufficient permissions")
+		c.Abort()
+	}
+}
+
+// RequireVerifiedEmail returns a gin handler that rejects users whose email is not verified with 403.
+// It must run after JWTMiddleware.
+func RequireVerifiedEmail(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated")
+			c.Abort()
+			return
+		}
+		u, err := svc.Me(c.Request.Context(), userID)
+		if err != nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "user not found")
+			c.Abort()
+			return
+		}
+		if !u.EmailVerified {
+			response.Error(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "verify your email address to continue")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
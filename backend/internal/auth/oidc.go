@@ -0,0 +1,552 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// OIDCStateCookieName is the cookie that binds an OIDC login to the browser that started it.
+	OIDCStateCookieName = "oidc_state"
+
+	oidcStateTTL        = 10 * time.Minute
+	oidcHTTPTimeout     = 10 * time.Second
+	oidcMetadataTTL     = time.Hour
+	oidcJWKSMinRefresh  = time.Minute
+	oidcMaxResponseSize = 1 << 20
+)
+
+// oidcStateClaims is the signed content of the OIDC state cookie.
+type oidcStateClaims struct {
+	jwt.RegisteredClaims
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// idTokenClaims are the ID token claims used to find or create the local user.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// flexBool decodes booleans that some providers send as strings ("true").
+type flexBool bool
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// oidcMetadata is the subset of the discovery document we use.
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider talks to one OpenID Connect issuer using the authorization code flow with PKCE.
+// Discovery metadata and signing keys are fetched lazily and cached. mu guards the cache only; the
+// requests run without it, so a slow issuer holds up just the callers waiting for its documents.
+type oidcProvider struct {
+	cfg    config.OIDCProvider
+	client *http.Client
+
+	mu           sync.Mutex
+	meta         *oidcMetadata
+	metaAt       time.Time
+	metaFetch    *oidcFetch
+	keys         map[string]crypto.PublicKey
+	keysAt       time.Time
+	keysFetch    time.Time
+	keysFetching *oidcFetch
+}
+
+// oidcFetch is a discovery or JWKS request in progress, shared by every caller that needs it.
+type oidcFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// newOIDCProviders builds a provider client for every configured issuer, keyed by name.
+func newOIDCProviders(cfgs []config.OIDCProvider) map[string]*oidcProvider {
+	providers := make(map[string]*oidcProvider, len(cfgs))
+	for _, c := range cfgs {
+		providers[c.Name] = &oidcProvider{cfg: c, client: &http.Client{Timeout: oidcHTTPTimeout}}
+	}
+	return providers
+}
+
+// OIDCProviders returns the names of the configured social login providers.
+func (s *authService) OIDCProviders() []string {
+	names := make([]string, 0, len(s.oidc))
+	for name := range s.oidc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartOIDCLogin returns the provider's authorization URL and a signed state cookie value holding
+// the state, nonce and PKCE verifier. The cookie must be sent back to CompleteOIDCLogin.
+func (s *authService) StartOIDCLogin(ctx context.Context, providerName string) (authorizationURL, stateCookie string, err error) {
+	p, ok := s.oidc[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, err := randomURLToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomURLToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomURLToken()
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	stateCookie, err = s.signPurposeClaims(purposeOIDCState, oidcStateClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{purposeOIDCState},
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Provider: providerName,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", p.cfg.Scopes)
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), stateCookie, nil
+}
+
+// CompleteOIDCLogin exchanges the authorization code, verifies the ID token and signs in the matching
+// local user. Unknown subjects are linked to an existing account with the same verified email, or a new
+// account is created. The result is the same as Login, including the 2FA challenge when enabled.
+func (s *authService) CompleteOIDCLogin(ctx context.Context, providerName, code, state, stateCookie string, client ClientInfo) (*LoginResult, error) {
+	p, ok := s.oidc[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	var st oidcStateClaims
+	if err := s.parsePurposeClaims(purposeOIDCState, stateCookie, &st); err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+	if st.Provider != providerName || subtle.ConstantTimeCompare([]byte(st.State), []byte(state)) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+	rawIDToken, err := p.exchange(ctx, code, st.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := p.verifyIDToken(ctx, rawIDToken, st.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.findOrCreateOIDCUser(ctx, providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.firstFactorResult(ctx, u, client)
+	s.recordLogin(ctx, EventLoginOIDC, u, client, result, err)
+	return result, err
+}
+
+// findOrCreateOIDCUser resolves the local user for a verified ID token.
+func (s *authService) findOrCreateOIDCUser(ctx context.Context, providerName string, claims *idTokenClaims) (*user.User, error) {
+	identity, err := s.repo.FindExternalIdentity(ctx, providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		u, err := s.repo.FindUserByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return nil, ErrUserNotFound
+		}
+		return u, nil
+	}
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		return nil, ErrOIDCEmailNotVerified
+	}
+	now := time.Now()
+	identity = &user.ExternalIdentity{
+		Provider:  providerName,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: now,
+	}
+	existing, err := s.repo.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// Linking to an unverified account would hand it to whoever registered the address first.
+		if !existing.EmailVerified {
+			return nil, ErrAccountLinkConflict
+		}
+		identity.UserID = existing.ID
+		if err := s.repo.CreateExternalIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+	u := &user.User{
+		Email:         claims.Email,
+		FullName:      name,
+		Role:          user.RoleCustomer,
+		EmailVerified: true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.repo.CreateUserWithIdentity(ctx, u, identity); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// metadata returns the provider's discovery document, refreshing it hourly.
+func (p *oidcProvider) metadata(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	if p.meta != nil && time.Since(p.metaAt) < oidcMetadataTTL {
+		meta := p.meta
+		p.mu.Unlock()
+		return meta, nil
+	}
+	f := p.startFetch(&p.metaFetch, p.fetchMetadata)
+	p.mu.Unlock()
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.meta, nil
+}
+
+// fetchMetadata downloads and caches the discovery document.
+func (p *oidcProvider) fetchMetadata(ctx context.Context) error {
+	endpoint := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	var meta oidcMetadata
+	if err := p.getJSON(ctx, endpoint, &meta); err != nil {
+		return err
+	}
+	if meta.Issuer != p.cfg.Issuer || meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return fmt.Errorf("auth: invalid discovery document from %s", p.cfg.Issuer)
+	}
+	p.mu.Lock()
+	p.meta, p.metaAt = &meta, time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// startFetch runs fetch in the background unless *slot already holds a fetch in progress, and
+// returns the one in progress. The fetch is not tied to any caller's context, so one caller giving
+// up does not fail it for the others; the client timeout bounds it. p.mu must be held.
+func (p *oidcProvider) startFetch(slot **oidcFetch, fetch func(ctx context.Context) error) *oidcFetch {
+	if *slot != nil {
+		return *slot
+	}
+	f := &oidcFetch{done: make(chan struct{})}
+	*slot = f
+	go func() {
+		f.err = fetch(context.Background())
+		p.mu.Lock()
+		*slot = nil
+		p.mu.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+// wait blocks until the fetch completes or ctx ends, and returns the fetch's error.
+func (f *oidcFetch) wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// exchange redeems the authorization code at the token endpoint and returns the raw ID token.
+func (p *oidcProvider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseSize))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: token endpoint returned %d", ErrOIDCExchange, resp.StatusCode)
+	}
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", err
+	}
+	if tok.IDToken == "" {
+		return "", fmt.Errorf("%w: no id_token in response", ErrOIDCExchange)
+	}
+	return tok.IDToken, nil
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, expiry and nonce.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, raw, nonce string) (*idTokenClaims, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := &idTokenClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCExchange, err)
+	}
+	if claims.Subject == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCExchange)
+	}
+	return claims, nil
+}
+
+// publicKey returns the provider's signing key for kid, refetching the JWKS when kid is unknown.
+func (p *oidcProvider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	if key, ok := p.lookupKey(kid); ok && time.Since(p.keysAt) < oidcMetadataTTL {
+		p.mu.Unlock()
+		return key, nil
+	}
+	f := p.keysFetching
+	if f == nil {
+		if time.Since(p.keysFetch) < oidcJWKSMinRefresh {
+			key, ok := p.lookupKey(kid)
+			p.mu.Unlock()
+			if !ok {
+				return nil, fmt.Errorf("auth: unknown signing key %q", kid)
+			}
+			return key, nil
+		}
+		p.keysFetch = time.Now()
+		f = p.startFetch(&p.keysFetching, func(ctx context.Context) error { return p.fetchKeys(ctx, meta.JWKSURI) })
+	}
+	p.mu.Unlock()
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("auth: unknown signing key %q", kid)
+}
+
+// fetchKeys downloads and caches the signing keys published at jwksURI.
+func (p *oidcProvider) fetchKeys(ctx context.Context, jwksURI string) error {
+	var set JWKSet
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		pub, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = pub
+	}
+	p.mu.Lock()
+	p.keys, p.keysAt = keys, time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// lookupKey finds kid in the cached keys. An empty kid matches only if the set has a single key.
+func (p *oidcProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+// getJSON fetches url and decodes the JSON body into v.
+func (p *oidcProvider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth: %s returned %d", endpoint, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseSize))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// publicKey decodes an RSA, EC or Ed25519 JWK.
+func (j JWK) publicKey() (crypto.PublicKey, error) {
+	dec := base64.RawURLEncoding
+	switch j.Kty {
+	case "RSA":
+		n, err := dec.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := dec.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("auth: unsupported curve %s", j.Crv)
+		}
+		x, err := dec.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := dec.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("auth: unsupported curve %s", j.Crv)
+		}
+		x, err := dec.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("auth: invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("auth: unsupported key type %s", j.Kty)
+}
+
+// randomURLToken returns 32 random bytes encoded as unpadded base64url (43 characters).
+func randomURLToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
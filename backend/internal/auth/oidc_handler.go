@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCCallbackRequest is the request body for POST /auth/oidc/:provider/callback.
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// handleListOIDCProviders handles GET /auth/oidc/providers — lists the configured social login providers.
+func handleListOIDCProviders(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Success(c, http.StatusOK, gin.H{"providers": svc.OIDCProviders()})
+	}
+}
+
+// handleOIDCAuthorize handles POST /auth/oidc/:provider/authorize — returns the provider's login URL
+// and sets the state cookie that the callback step checks.
+func handleOIDCAuthorize(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authURL, stateCookie, err := svc.StartOIDCLogin(c.Request.Context(), c.Param("provider"))
+		if err != nil {
+			if errors.Is(err, ErrUnknownProvider) {
+				response.Error(c, http.StatusNotFound, "UNKNOWN_PROVIDER", "unknown login provider")
+				return
+			}
+			response.Error(c, http.StatusBadGateway, "OIDC_FAILED", "login provider is unavailable")
+			return
+		}
+		setOIDCStateCookie(c, stateCookie, int(oidcStateTTL.Seconds()))
+		response.Success(c, http.StatusOK, gin.H{"authorizationUrl": authURL})
+	}
+}
+
+// handleOIDCCallback handles POST /auth/oidc/:provider/callback — exchanges the authorization code
+// returned by the provider for an access token and refresh cookie.
+func handleOIDCCallback(svc AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req OIDCCallbackRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		stateCookie, _ := c.Cookie(OIDCStateCookieName)
+		setOIDCStateCookie(c, "", -1)
+		result, err := svc.CompleteOIDCLogin(c.Request.Context(), c.Param("provider"), req.Code, req.State, stateCookie, clientInfo(c))
+		if err != nil {
+			if writeDisabledError(c, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, ErrUnknownProvider):
+				response.Error(c, http.StatusNotFound, "UNKNOWN_PROVIDER", "unknown login provider")
+			case errors.Is(err, ErrInvalidOIDCState):
+				response.Error(c, http.StatusBadRequest, "INVALID_STATE", "login state is invalid or expired")
+			case errors.Is(err, ErrOIDCEmailNotVerified):
+				response.Error(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "provider did not return a verified email")
+			case errors.Is(err, ErrAccountLinkConflict):
+				response.Error(c, http.StatusConflict, "ACCOUNT_EXISTS", "an unverified account already uses this email")
+			case errors.Is(err, ErrOIDCExchange):
+				response.Error(c, http.StatusUnauthorized, "OIDC_FAILED", "could not verify login with provider")
+			default:
+				response.Error(c, http.StatusBadGateway, "OIDC_FAILED", "login provider is unavailable")
+			}
+			return
+		}
+		writeLoginResult(c, result)
+	}
+}
+
+// setOIDCStateCookie writes (or, with maxAge < 0, expires) the httpOnly OIDC state cookie.
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
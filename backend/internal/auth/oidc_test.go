@@ -0,0 +1,527 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/cache"
+	"github.com/Rakesh2908/shopgo/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	testProvider = "test"
+	testClientID = "shopgo-client"
+	testKeyID    = "test-key"
+)
+
+// stubIssuer is an OpenID Connect provider serving discovery, a token endpoint and a JWKS. Each
+// authorization code is redeemed once, for the ID token registered with it, and only with the PKCE
+// verifier matching the challenge the login started with.
+type stubIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu          sync.Mutex
+	codes       map[string]stubCode
+	discoveries int
+	// hold, when set, keeps discovery requests waiting until it is closed.
+	hold chan struct{}
+}
+
+// stubCode is an authorization code waiting to be redeemed.
+type stubCode struct {
+	challenge string
+	idToken   string
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubIssuer{t: t, key: key, codes: make(map[string]stubCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("/token", s.handleToken)
+	mux.HandleFunc("/jwks", s.handleJWKS)
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stubIssuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.discoveries++
+	hold := s.hold
+	s.mu.Unlock()
+	if hold != nil {
+		<-hold
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.server.URL,
+		"authorization_endpoint": s.server.URL + "/authorize",
+		"token_endpoint":         s.server.URL + "/token",
+		"jwks_uri":               s.server.URL + "/jwks",
+	})
+}
+
+func (s *stubIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	s.mu.Lock()
+	c, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok, r.PostForm.Get("grant_type") != "authorization_code", r.PostForm.Get("client_id") != testClientID:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+	case base64.RawURLEncoding.EncodeToString(verifier[:]) != c.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": c.idToken})
+	}
+}
+
+func (s *stubIssuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, JWKSet{Keys: []JWK{{
+		Kty: "RSA",
+		Kid: testKeyID,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+// idToken returns an ID token for sub, signed with key, with claims overriding the defaults.
+func (s *stubIssuer) idToken(key *rsa.PrivateKey, sub, nonce string, claims jwt.MapClaims) string {
+	s.t.Helper()
+	now := time.Now()
+	c := jwt.MapClaims{
+		"iss":            s.server.URL,
+		"aud":            testClientID,
+		"sub":            sub,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          nonce,
+		"email_verified": true,
+	}
+	for k, v := range claims {
+		c[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return signed
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// fakeOIDCRepo keeps the users and identities the OIDC login touches in memory. Other
+// AuthRepository methods are not expected to be called and panic through the nil embedded interface.
+type fakeOIDCRepo struct {
+	AuthRepository
+
+	mu         sync.Mutex
+	users      map[uuid.UUID]*user.User
+	identities []user.ExternalIdentity
+	events     []user.AuthEvent
+}
+
+func newFakeOIDCRepo() *fakeOIDCRepo {
+	return &fakeOIDCRepo{users: make(map[uuid.UUID]*user.User)}
+}
+
+func (r *fakeOIDCRepo) addUser(email string, verified bool) *user.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := &user.User{ID: uuid.New(), Email: email, Password: "x", Role: user.RoleCustomer, EmailVerified: verified}
+	r.users[u.ID] = u
+	return u
+}
+
+func (r *fakeOIDCRepo) FindUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == email {
+			c := *u
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOIDCRepo) FindUserByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[id]; ok {
+		c := *u
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeOIDCRepo) FindExternalIdentity(ctx context.Context, provider, subject string) (*user.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, i := range r.identities {
+		if i.Provider == provider && i.Subject == subject {
+			c := i
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOIDCRepo) CreateExternalIdentity(ctx context.Context, identity *user.ExternalIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identity.ID = uuid.New()
+	r.identities = append(r.identities, *identity)
+	return nil
+}
+
+func (r *fakeOIDCRepo) CreateUserWithIdentity(ctx context.Context, u *user.User, identity *user.ExternalIdentity) error {
+	r.mu.Lock()
+	u.ID = uuid.New()
+	c := *u
+	r.users[u.ID] = &c
+	r.mu.Unlock()
+	identity.UserID = u.ID
+	return r.CreateExternalIdentity(ctx, identity)
+}
+
+func (r *fakeOIDCRepo) CreateRefreshToken(ctx context.Context, rt *user.RefreshToken) error {
+	return nil
+}
+
+func (r *fakeOIDCRepo) CreateAuthEvent(ctx context.Context, e *user.AuthEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, *e)
+	return nil
+}
+
+// newOIDCTestService returns an AuthService whose only social login provider is issuer.
+func newOIDCTestService(t *testing.T, issuer *stubIssuer, repo AuthRepository) AuthService {
+	t.Helper()
+	cfg := &config.Config{
+		JWTSecret: testJWTSecret,
+		OIDCProviders: []config.OIDCProvider{{
+			Name:        testProvider,
+			Issuer:      issuer.server.URL,
+			ClientID:    testClientID,
+			RedirectURL: "http://localhost:5173/oidc/callback",
+			Scopes:      "openid email profile",
+		}},
+	}
+	keyring, err := LoadKeyring(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocations := NewMemoryRevocationStore(cache.NewMemoryCache(time.Minute))
+	return NewAuthService(repo, cfg, nil, keyring, revocations, nil)
+}
+
+// oidcLogin runs a login through the stub issuer. idToken builds the ID token the issuer returns
+// from the nonce the login started with.
+func oidcLogin(t *testing.T, svc AuthService, issuer *stubIssuer, idToken func(nonce string) string) (*LoginResult, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, stateCookie, err := svc.StartOIDCLogin(ctx, testProvider)
+	if err != nil {
+		t.Fatalf("StartOIDCLogin: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if got, want := u.Scheme+"://"+u.Host+u.Path, issuer.server.URL+"/authorize"; got != want {
+		t.Fatalf("authorization endpoint = %s, want %s", got, want)
+	}
+	if q.Get("client_id") != testClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization parameters: %v", q)
+	}
+	if q.Get("state") == "" || q.Get("nonce") == "" || q.Get("code_challenge") == "" {
+		t.Fatalf("missing state, nonce or PKCE challenge: %v", q)
+	}
+	code := uuid.NewString()
+	token := idToken(q.Get("nonce"))
+	issuer.mu.Lock()
+	issuer.codes[code] = stubCode{challenge: q.Get("code_challenge"), idToken: token}
+	issuer.mu.Unlock()
+	return svc.CompleteOIDCLogin(ctx, testProvider, code, q.Get("state"), stateCookie, ClientInfo{})
+}
+
+func TestOIDCLoginCreatesAndFindsUser(t *testing.T) {
+	issuer := newStubIssuer(t)
+	repo := newFakeOIDCRepo()
+	svc := newOIDCTestService(t, issuer, repo)
+
+	idToken := func(nonce string) string {
+		return issuer.idToken(issuer.key, "subject-1", nonce, jwt.MapClaims{"email": "new@example.com", "name": "New User"})
+	}
+	result, err := oidcLogin(t, svc, issuer, idToken)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	identity, err := svc.ParseAccessToken(context.Background(), result.AccessToken)
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	created, _ := repo.FindUserByID(context.Background(), identity.UserID)
+	if created == nil || created.Email != "new@example.com" || created.FullName != "New User" || !created.EmailVerified || created.Password != "" {
+		t.Fatalf("unexpected created user: %+v", created)
+	}
+
+	result, err = oidcLogin(t, svc, issuer, idToken)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	again, err := svc.ParseAccessToken(context.Background(), result.AccessToken)
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	if again.UserID != identity.UserID {
+		t.Fatalf("second login signed in %s, want %s", again.UserID, identity.UserID)
+	}
+	if len(repo.users) != 1 || len(repo.identities) != 1 {
+		t.Fatalf("got %d users and %d identities, want 1 of each", len(repo.users), len(repo.identities))
+	}
+}
+
+func TestOIDCLoginRejectsWrongState(t *testing.T) {
+	issuer := newStubIssuer(t)
+	svc := newOIDCTestService(t, issuer, newFakeOIDCRepo())
+	ctx := context.Background()
+
+	_, stateCookie, err := svc.StartOIDCLogin(ctx, testProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.CompleteOIDCLogin(ctx, testProvider, "code", "another-state", stateCookie, ClientInfo{})
+	if !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("err = %v, want ErrInvalidOIDCState", err)
+	}
+	_, err = svc.CompleteOIDCLogin(ctx, testProvider, "code", "state", "not-a-cookie", ClientInfo{})
+	if !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("err = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestOIDCLoginRejectsWrongPKCEVerifier(t *testing.T) {
+	issuer := newStubIssuer(t)
+	repo := newFakeOIDCRepo()
+	svc := newOIDCTestService(t, issuer, repo)
+
+	// A code issued for another login's challenge, e.g. one intercepted by an attacker, cannot be
+	// redeemed with this login's verifier.
+	ctx := context.Background()
+	authURL, stateCookie, err := svc.StartOIDCLogin(ctx, testProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	token := issuer.idToken(issuer.key, "subject-1", q.Get("nonce"), jwt.MapClaims{"email": "a@example.com"})
+	issuer.mu.Lock()
+	issuer.codes["stolen"] = stubCode{
+		challenge: base64.RawURLEncoding.EncodeToString([]byte("another login's challenge")),
+		idToken:   token,
+	}
+	issuer.mu.Unlock()
+	_, err = svc.CompleteOIDCLogin(ctx, testProvider, "stolen", q.Get("state"), stateCookie, ClientInfo{})
+	if !errors.Is(err, ErrOIDCExchange) {
+		t.Fatalf("err = %v, want ErrOIDCExchange", err)
+	}
+	if len(repo.users) != 0 {
+		t.Fatal("account created without a valid PKCE verifier")
+	}
+}
+
+func TestOIDCLoginLinksVerifiedEmail(t *testing.T) {
+	issuer := newStubIssuer(t)
+	repo := newFakeOIDCRepo()
+	existing := repo.addUser("member@example.com", true)
+	svc := newOIDCTestService(t, issuer, repo)
+
+	result, err := oidcLogin(t, svc, issuer, func(nonce string) string {
+		return issuer.idToken(issuer.key, "subject-2", nonce, jwt.MapClaims{"email": "member@example.com"})
+	})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	identity, err := svc.ParseAccessToken(context.Background(), result.AccessToken)
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	if identity.UserID != existing.ID {
+		t.Fatalf("signed in %s, want existing user %s", identity.UserID, existing.ID)
+	}
+	if len(repo.users) != 1 || len(repo.identities) != 1 || repo.identities[0].UserID != existing.ID {
+		t.Fatalf("identity not linked to the existing user: %+v", repo.identities)
+	}
+}
+
+func TestOIDCLoginDoesNotLinkUnverifiedAccount(t *testing.T) {
+	issuer := newStubIssuer(t)
+	repo := newFakeOIDCRepo()
+	repo.addUser("squatted@example.com", false)
+	svc := newOIDCTestService(t, issuer, repo)
+
+	_, err := oidcLogin(t, svc, issuer, func(nonce string) string {
+		return issuer.idToken(issuer.key, "subject-3", nonce, jwt.MapClaims{"email": "squatted@example.com"})
+	})
+	if !errors.Is(err, ErrAccountLinkConflict) {
+		t.Fatalf("err = %v, want ErrAccountLinkConflict", err)
+	}
+	if len(repo.identities) != 0 {
+		t.Fatalf("identity was linked: %+v", repo.identities)
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedEmail(t *testing.T) {
+	for _, verified := range []interface{}{false, "false", nil} {
+		issuer := newStubIssuer(t)
+		repo := newFakeOIDCRepo()
+		svc := newOIDCTestService(t, issuer, repo)
+
+		_, err := oidcLogin(t, svc, issuer, func(nonce string) string {
+			return issuer.idToken(issuer.key, "subject-4", nonce, jwt.MapClaims{"email": "new@example.com", "email_verified": verified})
+		})
+		if !errors.Is(err, ErrOIDCEmailNotVerified) {
+			t.Fatalf("email_verified=%v: err = %v, want ErrOIDCEmailNotVerified", verified, err)
+		}
+		if len(repo.users) != 0 || len(repo.identities) != 0 {
+			t.Fatalf("email_verified=%v: account created", verified)
+		}
+	}
+}
+
+func TestOIDCLoginRejectsBadSignature(t *testing.T) {
+	issuer := newStubIssuer(t)
+	repo := newFakeOIDCRepo()
+	svc := newOIDCTestService(t, issuer, repo)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = oidcLogin(t, svc, issuer, func(nonce string) string {
+		return issuer.idToken(otherKey, "subject-5", nonce, jwt.MapClaims{"email": "new@example.com"})
+	})
+	if !errors.Is(err, ErrOIDCExchange) {
+		t.Fatalf("err = %v, want ErrOIDCExchange", err)
+	}
+	if len(repo.users) != 0 {
+		t.Fatal("account created from a forged ID token")
+	}
+}
+
+func TestOIDCLoginRejectsWrongNonce(t *testing.T) {
+	issuer := newStubIssuer(t)
+	repo := newFakeOIDCRepo()
+	svc := newOIDCTestService(t, issuer, repo)
+
+	_, err := oidcLogin(t, svc, issuer, func(nonce string) string {
+		return issuer.idToken(issuer.key, "subject-6", "replayed-nonce", jwt.MapClaims{"email": "new@example.com"})
+	})
+	if !errors.Is(err, ErrOIDCExchange) {
+		t.Fatalf("err = %v, want ErrOIDCExchange", err)
+	}
+	if len(repo.users) != 0 {
+		t.Fatal("account created from an ID token for another login")
+	}
+}
+
+func TestOIDCLoginRejectsWrongAudience(t *testing.T) {
+	issuer := newStubIssuer(t)
+	svc := newOIDCTestService(t, issuer, newFakeOIDCRepo())
+
+	_, err := oidcLogin(t, svc, issuer, func(nonce string) string {
+		return issuer.idToken(issuer.key, "subject-7", nonce, jwt.MapClaims{"email": "new@example.com", "aud": "another-client"})
+	})
+	if !errors.Is(err, ErrOIDCExchange) {
+		t.Fatalf("err = %v, want ErrOIDCExchange", err)
+	}
+}
+
+func TestOIDCDiscoveryIsSharedAndRunsWithoutTheLock(t *testing.T) {
+	issuer := newStubIssuer(t)
+	issuer.hold = make(chan struct{})
+	release := sync.OnceFunc(func() { close(issuer.hold) })
+	t.Cleanup(release) // before the server closes, which waits for held requests
+	p := newOIDCProviders([]config.OIDCProvider{{Name: testProvider, Issuer: issuer.server.URL, ClientID: testClientID}})[testProvider]
+	discoveries := func() int {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		return issuer.discoveries
+	}
+
+	const callers = 5
+	results := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			meta, err := p.metadata(context.Background())
+			if err == nil && meta.TokenEndpoint != issuer.server.URL+"/token" {
+				err = errors.New("unexpected token endpoint " + meta.TokenEndpoint)
+			}
+			results <- err
+		}()
+	}
+	waitFor(t, "the discovery request", func() bool { return discoveries() == 1 })
+
+	// While the issuer is slow the cache lock is free and a caller can give up on its own deadline.
+	if !p.mu.TryLock() {
+		t.Fatal("the provider lock is held during the discovery request")
+	}
+	p.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.metadata(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("impatient caller: err = %v, want context.DeadlineExceeded", err)
+	}
+
+	release()
+	for i := 0; i < callers; i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := p.metadata(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := discoveries(); n != 1 {
+		t.Errorf("%d discovery requests, want 1 shared by every caller", n)
+	}
+}
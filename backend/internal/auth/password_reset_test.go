@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRequestPasswordResetSendsInBackground(t *testing.T) {
+	repo := newFakeAuthRepo()
+	repo.addUser("member@example.com", true)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	ctx := context.Background()
+
+	if err := svc.RequestPasswordReset(ctx, "member@example.com", ClientInfo{IPAddress: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the reset email", func() bool { return mailer.count() == 1 })
+	if repo.resetTokenCount() != 1 {
+		t.Fatalf("%d reset tokens, want 1", repo.resetTokenCount())
+	}
+
+	if err := svc.RequestPasswordReset(ctx, "nobody@example.com", ClientInfo{IPAddress: "192.0.2.1"}); err != nil {
+		t.Fatalf("unknown email: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if mailer.count() != 1 || repo.resetTokenCount() != 1 {
+		t.Fatal("unknown email got a reset token or email")
+	}
+}
+
+func TestRequestPasswordResetIsRateLimitedPerEmail(t *testing.T) {
+	repo := newFakeAuthRepo()
+	repo.addUser("member@example.com", true)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	ctx := context.Background()
+
+	for _, email := range []string{"member@example.com", "nobody@example.com"} {
+		for i := 0; i < passwordResetMaxPerEmail; i++ {
+			client := ClientInfo{IPAddress: "192.0.2." + strconv.Itoa(i)}
+			if err := svc.RequestPasswordReset(ctx, email, client); err != nil {
+				t.Fatalf("%s request %d: %v", email, i+1, err)
+			}
+		}
+		// Registered or not, the address is limited the same way, from any IP and in any case.
+		err := svc.RequestPasswordReset(ctx, "  "+email, ClientInfo{IPAddress: "198.51.100.1"})
+		var retryErr *RetryAfterError
+		if !errors.Is(err, ErrTooManyRequests) || !errors.As(err, &retryErr) || retryErr.RetryAfter <= 0 {
+			t.Fatalf("%s: err = %v, want a *RetryAfterError wrapping ErrTooManyRequests", email, err)
+		}
+	}
+	waitFor(t, "the reset emails", func() bool { return mailer.count() == passwordResetMaxPerEmail })
+	time.Sleep(20 * time.Millisecond)
+	if mailer.count() != passwordResetMaxPerEmail {
+		t.Fatalf("%d emails sent, want %d", mailer.count(), passwordResetMaxPerEmail)
+	}
+}
+
+func TestRequestPasswordResetIsRateLimitedPerIP(t *testing.T) {
+	repo := newFakeAuthRepo()
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+	client := ClientInfo{IPAddress: "203.0.113.7"}
+
+	for i := 0; i < passwordResetMaxPerIP; i++ {
+		if err := svc.RequestPasswordReset(ctx, "user"+strconv.Itoa(i)+"@example.com", client); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	if err := svc.RequestPasswordReset(ctx, "another@example.com", client); !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("err = %v, want ErrTooManyRequests", err)
+	}
+	// Reset requests are not failed logins: the IP can still sign in.
+	if throttles, _ := repo.FindLoginThrottles(ctx, []string{"ip:" + client.IPAddress}); len(throttles) != 0 {
+		t.Errorf("reset requests left login throttles %+v", throttles)
+	}
+	if err := svc.RequestPasswordReset(ctx, "another@example.com", ClientInfo{IPAddress: "203.0.113.8"}); err != nil {
+		t.Fatalf("other IP: %v", err)
+	}
+}
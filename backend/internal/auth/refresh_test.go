@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newRefreshCookie signs in a fresh user and returns the user's first refresh cookie value.
+func newRefreshCookie(t *testing.T, svc *authService, repo *fakeAuthRepo) string {
+	t.Helper()
+	u := repo.addUser("member@example.com", true)
+	cookie, err := svc.issueRefreshToken(context.Background(), u.ID, uuid.New(), ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cookie
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	repo := newFakeAuthRepo()
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+	cookie := newRefreshCookie(t, svc, repo)
+
+	access, next, err := svc.RefreshToken(ctx, cookie, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if access == "" || next == "" || next == cookie {
+		t.Fatalf("RefreshToken returned %q, %q; want a new access token and cookie", access, next)
+	}
+	if _, _, err := svc.RefreshToken(ctx, next, ClientInfo{}); err != nil {
+		t.Fatalf("refreshing with the successor: %v", err)
+	}
+}
+
+// retireAt pins the retirement of cookie's token to at and sets the service clock to at+elapsed,
+// so the grace period does not depend on how long the bcrypt calls in between take.
+func retireAt(t *testing.T, svc *authService, repo *fakeAuthRepo, cookie string, at time.Time, elapsed time.Duration) {
+	t.Helper()
+	repo.retireAt(mustTokenID(t, cookie), at)
+	svc.now = func() time.Time { return at.Add(elapsed) }
+}
+
+func TestRefreshTokenReuseWithinGrace(t *testing.T) {
+	repo := newFakeAuthRepo()
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+	cookie := newRefreshCookie(t, svc, repo)
+
+	// Two tabs send the same cookie: both get a working successor in the same family.
+	_, first, err := svc.RefreshToken(ctx, cookie, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retireAt(t, svc, repo, cookie, time.Now(), refreshReuseGrace/2)
+	_, second, err := svc.RefreshToken(ctx, cookie, ClientInfo{})
+	if err != nil {
+		t.Fatalf("second refresh within the grace period: %v", err)
+	}
+	svc.now = time.Now
+	for _, c := range []string{first, second} {
+		if _, _, err := svc.RefreshToken(ctx, c, ClientInfo{}); err != nil {
+			t.Fatalf("refreshing with a successor: %v", err)
+		}
+	}
+}
+
+func TestRefreshTokenReissuedOnlyOnce(t *testing.T) {
+	repo := newFakeAuthRepo()
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+	cookie := newRefreshCookie(t, svc, repo)
+	userID := repo.refreshTokens[mustTokenID(t, cookie)].UserID
+
+	if _, _, err := svc.RefreshToken(ctx, cookie, ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	retireAt(t, svc, repo, cookie, time.Now(), time.Second)
+	if _, _, err := svc.RefreshToken(ctx, cookie, ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := svc.ListSessions(ctx, userID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("%d sessions listed, want the family once", len(sessions))
+	}
+
+	// A third presentation, still within the grace period, is a replay.
+	if _, _, err := svc.RefreshToken(ctx, cookie, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("err = %v, want ErrRefreshTokenReused", err)
+	}
+	if n := repo.refreshTokenCount(); n != 0 {
+		t.Fatalf("%d refresh tokens left, want the family deleted", n)
+	}
+}
+
+func TestRefreshTokenReuseAfterGraceRevokesFamily(t *testing.T) {
+	repo := newFakeAuthRepo()
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+	cookie := newRefreshCookie(t, svc, repo)
+
+	_, next, err := svc.RefreshToken(ctx, cookie, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retireAt(t, svc, repo, cookie, time.Now(), refreshReuseGrace+time.Second)
+
+	if _, _, err := svc.RefreshToken(ctx, cookie, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("err = %v, want ErrRefreshTokenReused", err)
+	}
+	if n := repo.refreshTokenCount(); n != 0 {
+		t.Fatalf("%d refresh tokens left, want the family deleted", n)
+	}
+	if _, _, err := svc.RefreshToken(ctx, next, ClientInfo{}); err == nil {
+		t.Fatal("the successor still works after the family was revoked")
+	}
+}
+
+func mustTokenID(t *testing.T, cookie string) uuid.UUID {
+	t.Helper()
+	id, _, err := parseRefreshCookie(cookie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
@@ -2,11 +2,13 @@ package auth
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/Rakesh2908/shopgo/internal/user"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AuthRepository defines the interface for auth-related persistence.
@@ -14,21 +16,70 @@ type AuthRepository interface {
 	CreateUser(ctx context.Context, u *user.User) error
 	FindUserByEmail(ctx context.Context, email string) (*user.User, error)
 	FindUserByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+	ListUsers(ctx context.Context, page, limit int) ([]user.User, int64, error)
+	UpdateUserRole(ctx context.Context, userID uuid.UUID, role string) (bool, error)
+	SetUserBanned(ctx context.Context, userID uuid.UUID, bannedAt *time.Time) (bool, error)
 	CreateRefreshToken(ctx context.Context, rt *user.RefreshToken) error
 	FindRefreshTokenByID(ctx context.Context, id uuid.UUID) (*user.RefreshToken, error)
 	FindRefreshTokenByUserID(ctx context.Context, userID uuid.UUID) ([]user.RefreshToken, error)
+	RetireRefreshToken(ctx context.Context, id uuid.UUID) (bool, error)
+	MarkRefreshTokenReissued(ctx context.Context, id uuid.UUID) (bool, error)
 	DeleteRefreshToken(ctx context.Context, id uuid.UUID) error
+	DeleteRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	DeleteOtherRefreshTokenFamilies(ctx context.Context, userID, keepFamilyID uuid.UUID) error
+	DeleteRefreshTokensByUserID(ctx context.Context, userID uuid.UUID) error
 	DeleteExpiredTokens(ctx context.Context) error
+	UpdateUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) (bool, error)
+	UpdateFullName(ctx context.Context, userID uuid.UUID, fullName string) error
+	SetPendingEmail(ctx context.Context, userID uuid.UUID, email string) error
+	ConfirmEmailChange(ctx context.Context, userID uuid.UUID, email string) (bool, error)
+	DeleteAccount(ctx context.Context, userID uuid.UUID) (bool, error)
+	UpdateVerificationSentAt(ctx context.Context, userID uuid.UUID, sentAt time.Time) error
+	UpdateTwoFactor(ctx context.Context, userID uuid.UUID, secret string, enabled bool) error
+	AdvanceTOTPCounter(ctx context.Context, userID uuid.UUID, counter int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []user.RecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	CreatePasswordResetToken(ctx context.Context, t *user.PasswordResetToken) error
+	FindPasswordResetTokenByID(ctx context.Context, id uuid.UUID) (*user.PasswordResetToken, error)
+	MarkPasswordResetTokenUsed(ctx context.Context, id uuid.UUID) (bool, error)
+	DeletePasswordResetTokensByUserID(ctx context.Context, userID uuid.UUID) error
+	CreateMagicLinkToken(ctx context.Context, t *user.MagicLinkToken) error
+	FindMagicLinkTokenByID(ctx context.Context, id uuid.UUID) (*user.MagicLinkToken, error)
+	MarkMagicLinkTokenUsed(ctx context.Context, id uuid.UUID) (bool, error)
+	CountMagicLinkTokensSince(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error)
+	FindLoginThrottles(ctx context.Context, keys []string) ([]user.LoginThrottle, error)
+	IncrementLoginFailures(ctx context.Context, key string, resetBefore time.Time) (int, error)
+	SetLoginLockedUntil(ctx context.Context, key string, until time.Time) error
+	DeleteLoginThrottle(ctx context.Context, key string) error
+	IncrementRequestCount(ctx context.Context, key string, resetBefore time.Time) (int, error)
+	CreateLoginLockout(ctx context.Context, l *user.LoginLockout) error
+	CreateAuthEvent(ctx context.Context, e *user.AuthEvent) error
+	ListAuthEvents(ctx context.Context, filter AuthEventFilter, page, limit int) ([]user.AuthEvent, int64, error)
+	FindExternalIdentity(ctx context.Context, provider, subject string) (*user.ExternalIdentity, error)
+	CreateExternalIdentity(ctx context.Context, identity *user.ExternalIdentity) error
+	CreateUserWithIdentity(ctx context.Context, u *user.User, identity *user.ExternalIdentity) error
+	CreateAPIKey(ctx context.Context, k *user.APIKey) error
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (*user.APIKey, error)
+	ListAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]user.APIKey, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+	DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error)
 }
 
+// AccountCleanup removes or anonymizes the rows another package keeps for a user. DeleteAccount
+// calls it inside its transaction, so every statement must run on tx.
+type AccountCleanup func(ctx context.Context, tx *gorm.DB, userID uuid.UUID) error
+
 // gormAuthRepository implements AuthRepository using GORM.
 type gormAuthRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	cleanups []AccountCleanup
 }
 
-// NewAuthRepository returns a new AuthRepository backed by GORM.
-func NewAuthRepository(db *gorm.DB) AuthRepository {
-	return &gormAuthRepository{db: db}
+// NewAuthRepository returns a new AuthRepository backed by GORM. cleanups are run by DeleteAccount
+// for the data other packages keep about a user.
+func NewAuthRepository(db *gorm.DB, cleanups ...AccountCleanup) AuthRepository {
+	return &gormAuthRepository{db: db, cleanups: cleanups}
 }
 
 // CreateUser inserts a new user.
@@ -62,6 +113,40 @@ func (r *gormAuthRepository) FindUserByID(ctx context.Context, id uuid.UUID) (*u
 	return &u, nil
 }
 
+// ListUsers returns a page of users ordered by signup date (newest first) and the total count.
+func (r *gormAuthRepository) ListUsers(ctx context.Context, page, limit int) ([]user.User, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&user.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []user.User
+	err := r.db.WithContext(ctx).
+		Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&users).Error
+	return users, total, err
+}
+
+// UpdateUserRole sets the user's role. It returns false if the user does not exist.
+func (r *gormAuthRepository) UpdateUserRole(ctx context.Context, userID uuid.UUID, role string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"role": role, "updated_at": time.Now()})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// SetUserBanned sets or, with a nil bannedAt, clears the user's ban. It reports whether the user exists.
+func (r *gormAuthRepository) SetUserBanned(ctx context.Context, userID uuid.UUID, bannedAt *time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"banned_at": bannedAt, "updated_at": time.Now()})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
 // CreateRefreshToken inserts a new refresh token record.
 func (r *gormAuthRepository) CreateRefreshToken(ctx context.Context, rt *user.RefreshToken) error {
 	return r.db.WithContext(ctx).Create(rt).Error
@@ -80,11 +165,12 @@ func (r *gormAuthRepository) FindRefreshTokenByID(ctx context.Context, id uuid.U
 	return &rt, nil
 }
 
-// FindRefreshTokenByUserID returns all unexpired refresh tokens for the user.
+// FindRefreshTokenByUserID returns all unexpired, unretired refresh tokens for the user.
 func (r *gormAuthRepository) FindRefreshTokenByUserID(ctx context.Context, userID uuid.UUID) ([]user.RefreshToken, error) {
 	var tokens []user.RefreshToken
 	err := r.db.WithContext(ctx).
-		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Where("user_id = ? AND expires_at > ? AND revoked_at IS NULL", userID, time.Now()).
+		Order("last_used_at DESC").
 		Find(&tokens).Error
 	if err != nil {
 		return nil, err
@@ -92,12 +178,495 @@ func (r *gormAuthRepository) FindRefreshTokenByUserID(ctx context.Context, userI
 	return tokens, nil
 }
 
+// RetireRefreshToken marks a refresh token as used. It returns false if the token was
+// already retired, in which case the original retirement time is kept, so the reuse grace
+// period always counts from the first rotation.
+func (r *gormAuthRepository) RetireRefreshToken(ctx context.Context, id uuid.UUID) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// MarkRefreshTokenReissued records that a retired refresh token was exchanged again within the
+// reuse grace period. It returns false if that already happened, so a retired token yields at
+// most one more successor.
+func (r *gormAuthRepository) MarkRefreshTokenReissued(ctx context.Context, id uuid.UUID) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NOT NULL AND reissued_at IS NULL", id).
+		Update("reissued_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
 // DeleteRefreshToken removes a refresh token by ID.
 func (r *gormAuthRepository) DeleteRefreshToken(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&user.RefreshToken{}, "id = ?", id).Error
 }
 
-// DeleteExpiredTokens removes all refresh tokens that have expired.
+// DeleteRefreshTokenFamily removes every refresh token (active or retired) in the family.
+func (r *gormAuthRepository) DeleteRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("family_id = ?", familyID).Delete(&user.RefreshToken{}).Error
+}
+
+// DeleteOtherRefreshTokenFamilies removes every refresh token of the user except those in keepFamilyID.
+func (r *gormAuthRepository) DeleteOtherRefreshTokenFamilies(ctx context.Context, userID, keepFamilyID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND family_id <> ?", userID, keepFamilyID).
+		Delete(&user.RefreshToken{}).Error
+}
+
+// DeleteRefreshTokensByUserID removes every refresh token of the user, signing out all sessions.
+func (r *gormAuthRepository) DeleteRefreshTokensByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&user.RefreshToken{}).Error
+}
+
+// DeleteExpiredTokens removes all refresh tokens, password reset tokens, magic link tokens and API keys
+// that have expired.
 func (r *gormAuthRepository) DeleteExpiredTokens(ctx context.Context) error {
-	return r.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&user.RefreshToken{}).Error
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&user.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&user.PasswordResetToken{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&user.MagicLinkToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&user.APIKey{}).Error
+}
+
+// UpdateUserPassword replaces the user's password hash.
+func (r *gormAuthRepository) UpdateUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	return r.db.WithContext(ctx).Model(&user.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"password": passwordHash, "updated_at": time.Now()}).Error
+}
+
+// MarkEmailVerified flags the user's email as verified if it still equals email.
+// It returns false if the user does not exist or has since changed address.
+func (r *gormAuthRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.User{}).
+		Where("id = ? AND email = ?", userID, email).
+		Updates(map[string]interface{}{"email_verified": true, "updated_at": time.Now()})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// UpdateFullName changes the user's display name.
+func (r *gormAuthRepository) UpdateFullName(ctx context.Context, userID uuid.UUID, fullName string) error {
+	return r.db.WithContext(ctx).Model(&user.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"full_name": fullName, "updated_at": time.Now()}).Error
+}
+
+// SetPendingEmail records a requested new address that becomes active once verified.
+func (r *gormAuthRepository) SetPendingEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	return r.db.WithContext(ctx).Model(&user.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"pending_email": email, "updated_at": time.Now()}).Error
+}
+
+// ConfirmEmailChange makes the pending address the user's verified email if it still equals email.
+// It returns false if the user does not exist or has since requested a different address.
+func (r *gormAuthRepository) ConfirmEmailChange(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.User{}).
+		Where("id = ? AND pending_email = ?", userID, email).
+		Updates(map[string]interface{}{
+			"email":          email,
+			"pending_email":  "",
+			"email_verified": true,
+			"updated_at":     time.Now(),
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// DeleteAccount removes the user's credentials and login throttles, runs the registered
+// AccountCleanups, scrubs the user row of personal data, role and ban, and soft-deletes it, all in
+// one transaction. Orders are kept unchanged for bookkeeping: they hold only the purchased items,
+// totals and Stripe PaymentIntent ID, and reference the anonymized row.
+// It returns false if the user does not exist.
+func (r *gormAuthRepository) DeleteAccount(ctx context.Context, userID uuid.UUID) (bool, error) {
+	deleted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var u user.User
+		err := tx.Select("email").Where("id = ?", userID).First(&u).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		anonymized := "deleted-" + userID.String() + "@deleted.invalid"
+		if err := tx.Model(&user.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"email":                anonymized,
+			"full_name":            "Deleted user",
+			"password":             "",
+			"pending_email":        "",
+			"email_verified":       false,
+			"verification_sent_at": nil,
+			"two_factor_enabled":   false,
+			"totp_secret":          "",
+			"role":                 user.RoleCustomer,
+			"banned_at":            nil,
+			"updated_at":           time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		// Data exports are left to the export package, which removes the archive files along with
+		// the records once the account is gone.
+		for _, cleanup := range r.cleanups {
+			if err := cleanup(ctx, tx, userID); err != nil {
+				return err
+			}
+		}
+		// Throttles and request counters are keyed by the address, so a new account with it starts
+		// with a clean slate. Lockout records stay for security reviews without the address.
+		email := strings.ToLower(strings.TrimSpace(u.Email))
+		if err := tx.Where("key = ?", "email:"+email).Delete(&user.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("key = ?", "reset:email:"+email).Delete(&user.RequestCounter{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user.LoginLockout{}).Where("key = ?", "email:"+email).Updates(map[string]interface{}{
+			"key":   "email:" + anonymized,
+			"email": "",
+		}).Error; err != nil {
+			return err
+		}
+		owned := []interface{}{
+			&user.RefreshToken{},
+			&user.PasswordResetToken{},
+			&user.MagicLinkToken{},
+			&user.RecoveryCode{},
+			&user.ExternalIdentity{},
+			&user.APIKey{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		// Audit events are kept for security reviews but redacted, the only mutation they allow:
+		// nothing left in them identifies the person behind the account.
+		if err := tx.Model(&user.AuthEvent{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"email":      "",
+			"ip_address": "",
+			"user_agent": "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", userID).Delete(&user.User{}).Error; err != nil {
+			return err
+		}
+		deleted = true
+		return nil
+	})
+	return deleted, err
+}
+
+// UpdateVerificationSentAt records when the last verification email was sent to the user.
+func (r *gormAuthRepository) UpdateVerificationSentAt(ctx context.Context, userID uuid.UUID, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&user.User{}).Where("id = ?", userID).
+		Update("verification_sent_at", sentAt).Error
+}
+
+// UpdateTwoFactor stores the user's TOTP secret and whether 2FA is enforced, resetting the replay counter.
+func (r *gormAuthRepository) UpdateTwoFactor(ctx context.Context, userID uuid.UUID, secret string, enabled bool) error {
+	return r.db.WithContext(ctx).Model(&user.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"totp_secret":        secret,
+			"two_factor_enabled": enabled,
+			"totp_last_counter":  0,
+			"updated_at":         time.Now(),
+		}).Error
+}
+
+// AdvanceTOTPCounter records counter as the last accepted TOTP time step. It returns false if a code
+// for the same or a later step was already accepted, which means the code is being replayed.
+func (r *gormAuthRepository) AdvanceTOTPCounter(ctx context.Context, userID uuid.UUID, counter int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.User{}).
+		Where("id = ? AND totp_last_counter < ?", userID, counter).
+		Update("totp_last_counter", counter)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes deletes the user's recovery codes and inserts codes in a single transaction.
+func (r *gormAuthRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []user.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&user.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode marks the user's unused recovery code with codeHash as used. It returns false if no such code exists.
+func (r *gormAuthRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// CreatePasswordResetToken inserts a new password reset token record.
+func (r *gormAuthRepository) CreatePasswordResetToken(ctx context.Context, t *user.PasswordResetToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// FindPasswordResetTokenByID returns the password reset token by ID, or nil if not found.
+func (r *gormAuthRepository) FindPasswordResetTokenByID(ctx context.Context, id uuid.UUID) (*user.PasswordResetToken, error) {
+	var t user.PasswordResetToken
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&t).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// MarkPasswordResetTokenUsed marks a reset token as consumed. It returns false if the token was already used.
+func (r *gormAuthRepository) MarkPasswordResetTokenUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// DeletePasswordResetTokensByUserID removes every password reset token of the user.
+func (r *gormAuthRepository) DeletePasswordResetTokensByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&user.PasswordResetToken{}).Error
+}
+
+// CreateMagicLinkToken inserts a new magic link token record.
+func (r *gormAuthRepository) CreateMagicLinkToken(ctx context.Context, t *user.MagicLinkToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// FindMagicLinkTokenByID returns the magic link token by ID, or nil if not found.
+func (r *gormAuthRepository) FindMagicLinkTokenByID(ctx context.Context, id uuid.UUID) (*user.MagicLinkToken, error) {
+	var t user.MagicLinkToken
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&t).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// MarkMagicLinkTokenUsed marks a magic link token as consumed. It returns false if the token was already used.
+func (r *gormAuthRepository) MarkMagicLinkTokenUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&user.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// CountMagicLinkTokensSince returns how many magic links were issued to the user since the given time.
+func (r *gormAuthRepository) CountMagicLinkTokensSince(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&user.MagicLinkToken{}).
+		Where("user_id = ? AND created_at > ?", userID, since).Count(&n).Error
+	return n, err
+}
+
+// FindLoginThrottles returns the throttle rows that exist for keys.
+func (r *gormAuthRepository) FindLoginThrottles(ctx context.Context, keys []string) ([]user.LoginThrottle, error) {
+	var throttles []user.LoginThrottle
+	err := r.db.WithContext(ctx).Where("key IN ?", keys).Find(&throttles).Error
+	return throttles, err
+}
+
+// IncrementLoginFailures atomically records a failed login for key and returns the new count.
+// Failures older than resetBefore are forgotten, so the count restarts at 1.
+func (r *gormAuthRepository) IncrementLoginFailures(ctx context.Context, key string, resetBefore time.Time) (int, error) {
+	t := user.LoginThrottle{Key: key, Failures: 1, LastFailureAt: time.Now()}
+	err := r.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"failures":        gorm.Expr("CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END", resetBefore),
+					"last_failure_at": t.LastFailureAt,
+				}),
+			},
+			clause.Returning{Columns: []clause.Column{{Name: "failures"}}},
+		).
+		Create(&t).Error
+	if err != nil {
+		return 0, err
+	}
+	return t.Failures, nil
+}
+
+// SetLoginLockedUntil blocks logins for key until the given time.
+func (r *gormAuthRepository) SetLoginLockedUntil(ctx context.Context, key string, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&user.LoginThrottle{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+// DeleteLoginThrottle forgets all failed logins for key.
+func (r *gormAuthRepository) DeleteLoginThrottle(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Where("key = ?", key).Delete(&user.LoginThrottle{}).Error
+}
+
+// IncrementRequestCount atomically records a rate-limited request, such as a password reset, for
+// key and returns the new count.
+// Requests older than resetBefore are forgotten, so the count restarts at 1.
+func (r *gormAuthRepository) IncrementRequestCount(ctx context.Context, key string, resetBefore time.Time) (int, error) {
+	c := user.RequestCounter{Key: key, Requests: 1, LastRequestAt: time.Now()}
+	err := r.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"requests":        gorm.Expr("CASE WHEN request_counters.last_request_at < ? THEN 1 ELSE request_counters.requests + 1 END", resetBefore),
+					"last_request_at": c.LastRequestAt,
+				}),
+			},
+			clause.Returning{Columns: []clause.Column{{Name: "requests"}}},
+		).
+		Create(&c).Error
+	if err != nil {
+		return 0, err
+	}
+	return c.Requests, nil
+}
+
+// CreateLoginLockout inserts a lockout audit record.
+func (r *gormAuthRepository) CreateLoginLockout(ctx context.Context, l *user.LoginLockout) error {
+	return r.db.WithContext(ctx).Create(l).Error
+}
+
+// CreateAuthEvent appends an audit event. Events are never deleted, and only DeleteAccount updates
+// them, to redact personal data.
+func (r *gormAuthRepository) CreateAuthEvent(ctx context.Context, e *user.AuthEvent) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// ListAuthEvents returns a page of audit events matching filter, newest first, and the total match count.
+func (r *gormAuthRepository) ListAuthEvents(ctx context.Context, filter AuthEventFilter, page, limit int) ([]user.AuthEvent, int64, error) {
+	q := r.db.WithContext(ctx).Model(&user.AuthEvent{})
+	if filter.UserID != uuid.Nil {
+		q = q.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Email != "" {
+		q = q.Where("email = ?", filter.Email)
+	}
+	if filter.Type != "" {
+		q = q.Where("type = ?", filter.Type)
+	}
+	if filter.Outcome != "" {
+		q = q.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.IPAddress != "" {
+		q = q.Where("ip_address = ?", filter.IPAddress)
+	}
+	if !filter.Since.IsZero() {
+		q = q.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		q = q.Where("created_at < ?", filter.Until)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var events []user.AuthEvent
+	err := q.Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&events).Error
+	return events, total, err
+}
+
+// FindExternalIdentity returns the identity for provider and subject, or nil if not found.
+func (r *gormAuthRepository) FindExternalIdentity(ctx context.Context, provider, subject string) (*user.ExternalIdentity, error) {
+	var identity user.ExternalIdentity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateExternalIdentity links an existing user to a provider account.
+func (r *gormAuthRepository) CreateExternalIdentity(ctx context.Context, identity *user.ExternalIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+// CreateUserWithIdentity inserts a new user and its provider identity in a single transaction.
+func (r *gormAuthRepository) CreateUserWithIdentity(ctx context.Context, u *user.User, identity *user.ExternalIdentity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return err
+		}
+		identity.UserID = u.ID
+		return tx.Create(identity).Error
+	})
+}
+
+// CreateAPIKey inserts a new API key.
+func (r *gormAuthRepository) CreateAPIKey(ctx context.Context, k *user.APIKey) error {
+	return r.db.WithContext(ctx).Create(k).Error
+}
+
+// FindAPIKeyByHash returns the API key with the given hash, or nil if not found.
+func (r *gormAuthRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (*user.APIKey, error) {
+	var k user.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(&k).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListAPIKeysByUserID returns the user's API keys, newest first.
+func (r *gormAuthRepository) ListAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]user.APIKey, error) {
+	var keys []user.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// TouchAPIKey records when the key was last used.
+func (r *gormAuthRepository) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&user.APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
+
+// DeleteAPIKey removes one of the user's API keys. It reports whether a key was deleted.
+func (r *gormAuthRepository) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	res := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&user.APIKey{})
+	return res.RowsAffected > 0, res.Error
 }
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the Postgres database in TEST_DATABASE_URL, skipping the test if it is
+// not set.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user.User{}, &user.RefreshToken{}, &user.PasswordResetToken{}, &user.MagicLinkToken{},
+		&user.RecoveryCode{}, &user.LoginThrottle{}, &user.RequestCounter{}, &user.LoginLockout{}, &user.AuthEvent{},
+		&user.ExternalIdentity{}, &user.APIKey{}, &user.RevokedAccessToken{}, &user.AccessTokenCutoff{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createBannedAdmin inserts a banned admin with a login throttle and a password reset counter,
+// removing everything when the test ends.
+func createBannedAdmin(t *testing.T, db *gorm.DB) (*user.User, []string) {
+	t.Helper()
+	now := time.Now()
+	email := uuid.NewString() + "@example.com"
+	u := &user.User{Email: email, Password: "x", Role: user.RoleAdmin, BannedAt: &now}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"email:" + email, "reset:email:" + email}
+	if err := db.Create(&user.LoginThrottle{Key: keys[0], Failures: 3, LastFailureAt: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&user.RequestCounter{Key: keys[1], Requests: 3, LastRequestAt: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Where("key IN ?", keys).Delete(&user.LoginThrottle{})
+		db.Where("key IN ?", keys).Delete(&user.RequestCounter{})
+		db.Unscoped().Delete(u)
+	})
+	return u, keys
+}
+
+func TestDeleteAccountScrubsUser(t *testing.T) {
+	db := openTestDB(t)
+	u, keys := createBannedAdmin(t, db)
+	var cleaned uuid.UUID
+	repo := NewAuthRepository(db, func(ctx context.Context, tx *gorm.DB, userID uuid.UUID) error {
+		cleaned = userID
+		return nil
+	})
+
+	deleted, err := repo.DeleteAccount(context.Background(), u.ID)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteAccount = %v, %v; want true, nil", deleted, err)
+	}
+	if cleaned != u.ID {
+		t.Errorf("cleanup ran for %s, want %s", cleaned, u.ID)
+	}
+	var got user.User
+	if err := db.Unscoped().First(&got, "id = ?", u.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Role != user.RoleCustomer || got.BannedAt != nil || !strings.HasSuffix(got.Email, "@deleted.invalid") {
+		t.Errorf("deleted user kept role %q, ban %v, email %q", got.Role, got.BannedAt, got.Email)
+	}
+	var throttles, counters int64
+	db.Model(&user.LoginThrottle{}).Where("key IN ?", keys).Count(&throttles)
+	db.Model(&user.RequestCounter{}).Where("key IN ?", keys).Count(&counters)
+	if throttles != 0 || counters != 0 {
+		t.Errorf("%d login throttles and %d request counters left for the deleted address", throttles, counters)
+	}
+}
+
+func TestDeleteAccountRollsBackOnCleanupError(t *testing.T) {
+	db := openTestDB(t)
+	u, _ := createBannedAdmin(t, db)
+	failure := errors.New("cleanup failed")
+	repo := NewAuthRepository(db, func(ctx context.Context, tx *gorm.DB, userID uuid.UUID) error {
+		return failure
+	})
+
+	if _, err := repo.DeleteAccount(context.Background(), u.ID); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want the cleanup error", err)
+	}
+	var got user.User
+	if err := db.First(&got, "id = ?", u.ID).Error; err != nil {
+		t.Fatalf("user was deleted despite the failed cleanup: %v", err)
+	}
+	if got.Email != u.Email || got.Role != user.RoleAdmin {
+		t.Errorf("user changed despite the failed cleanup: %q, %q", got.Email, got.Role)
+	}
+}
+
+func TestListAuthEventsFiltersAndPages(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAuthRepository(db)
+	ctx := context.Background()
+	email := uuid.NewString() + "@example.com"
+	userID := uuid.New()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	events := []user.AuthEvent{
+		{Type: EventLogin, Email: email, Outcome: OutcomeFailure, IPAddress: "192.0.2.1", CreatedAt: base},
+		{Type: EventLogin, UserID: &userID, Email: email, Outcome: OutcomeSuccess, IPAddress: "192.0.2.1", CreatedAt: base.Add(time.Minute)},
+		{Type: EventTokenRefresh, UserID: &userID, Email: email, Outcome: OutcomeSuccess, IPAddress: "192.0.2.2", CreatedAt: base.Add(2 * time.Minute)},
+		{Type: EventLogout, UserID: &userID, Email: email, Outcome: OutcomeSuccess, IPAddress: "192.0.2.2", CreatedAt: base.Add(3 * time.Minute)},
+	}
+	for i := range events {
+		if err := repo.CreateAuthEvent(ctx, &events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { db.Where("email = ?", email).Delete(&user.AuthEvent{}) })
+
+	tests := []struct {
+		name   string
+		filter AuthEventFilter
+		want   []string
+	}{
+		{"email", AuthEventFilter{Email: email}, []string{EventLogout, EventTokenRefresh, EventLogin, EventLogin}},
+		{"user", AuthEventFilter{UserID: userID}, []string{EventLogout, EventTokenRefresh, EventLogin}},
+		{"type", AuthEventFilter{Email: email, Type: EventLogin}, []string{EventLogin, EventLogin}},
+		{"outcome", AuthEventFilter{Email: email, Outcome: OutcomeFailure}, []string{EventLogin}},
+		{"ip", AuthEventFilter{Email: email, IPAddress: "192.0.2.2"}, []string{EventLogout, EventTokenRefresh}},
+		{"since is inclusive, until exclusive", AuthEventFilter{Email: email, Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute)},
+			[]string{EventTokenRefresh, EventLogin}},
+	}
+	for _, tt := range tests {
+		got, total, err := repo.ListAuthEvents(ctx, tt.filter, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types := make([]string, len(got))
+		for i, e := range got {
+			types[i] = e.Type
+		}
+		if strings.Join(types, ",") != strings.Join(tt.want, ",") || total != int64(len(tt.want)) {
+			t.Errorf("%s: events %v (total %d), want %v", tt.name, types, total, tt.want)
+		}
+	}
+
+	page, total, err := repo.ListAuthEvents(ctx, AuthEventFilter{Email: email}, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Outcome != OutcomeFailure || total != 4 {
+		t.Errorf("second page = %+v (total %d), want the oldest event of 4", page, total)
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/cache"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore records access tokens that must be rejected before they expire. Entries only
+// need to live as long as the tokens they cover, so implementations may drop them after that.
+type RevocationStore interface {
+	// RevokeToken rejects the token with the given jti until expiresAt.
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeUserTokens rejects every token of the user issued before issuedBefore. The entry is
+	// kept for ttl, the longest lifetime of such a token.
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID, issuedBefore time.Time, ttl time.Duration) error
+	// IsRevoked reports whether a token with the given jti, subject and issue time was revoked.
+	IsRevoked(ctx context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error)
+}
+
+// memoryRevocationStore keeps revocations in a MemoryCache. Entries expire with the cache TTL.
+// Revocations are per process, so they are lost on restart and not shared between instances; it
+// only suits a single instance, e.g. in development. Use NewDBRevocationStore otherwise.
+type memoryRevocationStore struct {
+	cache *cache.MemoryCache
+}
+
+// NewMemoryRevocationStore returns a RevocationStore backed by c.
+func NewMemoryRevocationStore(c *cache.MemoryCache) RevocationStore {
+	return &memoryRevocationStore{cache: c}
+}
+
+// RevokeToken implements RevocationStore.
+func (m *memoryRevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	m.cache.Set("revoked:jti:"+jti, true, ttl)
+	return nil
+}
+
+// RevokeUserTokens implements RevocationStore. A later cutoff replaces an earlier one.
+func (m *memoryRevocationStore) RevokeUserTokens(ctx context.Context, userID uuid.UUID, issuedBefore time.Time, ttl time.Duration) error {
+	key := "revoked:user:" + userID.String()
+	if v, ok := m.cache.Get(key); ok {
+		if prev, _ := v.(time.Time); prev.After(issuedBefore) {
+			issuedBefore = prev
+		}
+	}
+	m.cache.Set(key, issuedBefore, ttl)
+	return nil
+}
+
+// IsRevoked implements RevocationStore.
+func (m *memoryRevocationStore) IsRevoked(ctx context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	if _, ok := m.cache.Get("revoked:jti:" + jti); ok {
+		return true, nil
+	}
+	if v, ok := m.cache.Get("revoked:user:" + userID.String()); ok {
+		if cutoff, _ := v.(time.Time); issuedAt.Before(cutoff) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dbRevocationStore keeps revocations in Postgres, so they survive restarts and apply to every
+// instance. Expired entries are deleted whenever a new revocation is written.
+type dbRevocationStore struct {
+	db *gorm.DB
+}
+
+// NewDBRevocationStore returns a RevocationStore backed by the revoked_access_tokens and
+// access_token_cutoffs tables.
+func NewDBRevocationStore(db *gorm.DB) RevocationStore {
+	return &dbRevocationStore{db: db}
+}
+
+// RevokeToken implements RevocationStore.
+func (d *dbRevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" || !expiresAt.After(time.Now()) {
+		return nil
+	}
+	err := d.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&user.RevokedAccessToken{JTI: jti, ExpiresAt: expiresAt}).Error
+	if err != nil {
+		return err
+	}
+	return d.deleteExpired(ctx)
+}
+
+// RevokeUserTokens implements RevocationStore. A later cutoff replaces an earlier one.
+func (d *dbRevocationStore) RevokeUserTokens(ctx context.Context, userID uuid.UUID, issuedBefore time.Time, ttl time.Duration) error {
+	c := user.AccessTokenCutoff{UserID: userID, IssuedBefore: issuedBefore, ExpiresAt: time.Now().Add(ttl)}
+	err := d.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"issued_before": gorm.Expr("GREATEST(access_token_cutoffs.issued_before, EXCLUDED.issued_before)"),
+			"expires_at":    gorm.Expr("GREATEST(access_token_cutoffs.expires_at, EXCLUDED.expires_at)"),
+		}),
+	}).Create(&c).Error
+	if err != nil {
+		return err
+	}
+	return d.deleteExpired(ctx)
+}
+
+// IsRevoked implements RevocationStore.
+func (d *dbRevocationStore) IsRevoked(ctx context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	now := time.Now()
+	err := d.db.WithContext(ctx).Raw(
+		"SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = ? AND expires_at > ?)"+
+			" OR EXISTS (SELECT 1 FROM access_token_cutoffs WHERE user_id = ? AND issued_before > ? AND expires_at > ?)",
+		jti, now, userID, issuedAt, now,
+	).Scan(&revoked).Error
+	return revoked, err
+}
+
+// deleteExpired removes revocations whose tokens have all expired.
+func (d *dbRevocationStore) deleteExpired(ctx context.Context) error {
+	now := time.Now()
+	if err := d.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&user.RevokedAccessToken{}).Error; err != nil {
+		return err
+	}
+	return d.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&user.AccessTokenCutoff{}).Error
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/Rakesh2908/shopgo/pkg/cache"
+	"github.com/google/uuid"
+)
+
+// testRevocationStore checks the behaviour every RevocationStore must share.
+func testRevocationStore(t *testing.T, store RevocationStore) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	userID := uuid.New()
+	revoked := func(jti string, issuedAt time.Time) bool {
+		t.Helper()
+		ok, err := store.IsRevoked(ctx, jti, userID, issuedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	jti := uuid.NewString()
+	if err := store.RevokeToken(ctx, jti, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if !revoked(jti, now) {
+		t.Error("revoked jti is accepted")
+	}
+	if revoked(uuid.NewString(), now) {
+		t.Error("unrelated jti is rejected")
+	}
+
+	if err := store.RevokeUserTokens(ctx, userID, now, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !revoked(uuid.NewString(), now.Add(-time.Second)) {
+		t.Error("token issued before the cutoff is accepted")
+	}
+	if revoked(uuid.NewString(), now) {
+		t.Error("token issued at the cutoff is rejected")
+	}
+
+	// An earlier cutoff written later does not move the existing one back.
+	if err := store.RevokeUserTokens(ctx, userID, now.Add(-time.Hour), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !revoked(uuid.NewString(), now.Add(-time.Second)) {
+		t.Error("earlier cutoff replaced the later one")
+	}
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	c := cache.NewMemoryCache(time.Minute)
+	t.Cleanup(c.Stop)
+	testRevocationStore(t, NewMemoryRevocationStore(c))
+}
+
+func TestDBRevocationStore(t *testing.T) {
+	testRevocationStore(t, NewDBRevocationStore(openTestDB(t)))
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	token, err := svc.generateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := svc.generateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ParseAccessToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Logout(ctx, "", token, ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ParseAccessToken(ctx, token); err == nil {
+		t.Error("access token still works after logout")
+	}
+	if _, err := svc.ParseAccessToken(ctx, other); err != nil {
+		t.Errorf("another session's token stopped working: %v", err)
+	}
+}
+
+func TestBanRevokesTokensIssuedThisSecond(t *testing.T) {
+	repo := newFakeAuthRepo()
+	admin := repo.addUser("admin@example.com", true)
+	u := repo.addUser("member@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	token, err := svc.generateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.BanUser(ctx, admin.ID, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ParseAccessToken(ctx, token); err == nil {
+		t.Error("access token issued before the ban still works")
+	}
+}
+
+func TestDemotionRevokesAccessTokens(t *testing.T) {
+	repo := newFakeAuthRepo()
+	actor := repo.addUser("admin@example.com", true)
+	u := repo.addUser("staff@example.com", true)
+	repo.users[u.ID].Role = user.RoleAdmin
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	token, err := svc.generateAccessToken(repo.users[u.ID])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie, err := svc.issueRefreshToken(ctx, u.ID, uuid.New(), ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.SetUserRole(ctx, actor.ID, actor.ID, user.RoleCustomer); !errors.Is(err, ErrCannotChangeOwnRole) {
+		t.Fatalf("changing own role: err = %v, want ErrCannotChangeOwnRole", err)
+	}
+	if err := svc.SetUserRole(ctx, actor.ID, uuid.New(), user.RoleCustomer); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+	if _, err := svc.ParseAccessToken(ctx, token); err != nil {
+		t.Fatalf("a rejected role change revoked the token: %v", err)
+	}
+
+	if err := svc.SetUserRole(ctx, actor.ID, u.ID, user.RoleCustomer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ParseAccessToken(ctx, token); err == nil {
+		t.Error("the admin access token still works after the demotion")
+	}
+	// The session is kept and refreshes into a token with the new role.
+	access, _, err := svc.RefreshToken(ctx, cookie, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.parseJWT(access)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != user.RoleCustomer {
+		t.Errorf("refreshed token role = %q, want %q", claims.Role, user.RoleCustomer)
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/Rakesh2908/shopgo/internal/user"
 	"github.com/Rakesh2908/shopgo/pkg/config"
+	"github.com/Rakesh2908/shopgo/pkg/mail"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -17,144 +21,426 @@ import (
 
 const (
 	bcryptCost       = 12
-	accessTokenTTL  = 15 * time.Minute
-	refreshTokenTTL = 7 * 24 * time.Hour
+	accessTokenTTL   = 15 * time.Minute
+	refreshTokenTTL  = 7 * 24 * time.Hour
 	refreshTokenSize = 32
 	cookieSeparator  = ":"
+	maxUserAgentLen  = 512
+	passwordResetTTL = time.Hour
+	// emailVerificationTTL is how long a verification link stays valid.
+	emailVerificationTTL = 48 * time.Hour
+	// verificationResendCooldown is the minimum time between two verification emails for one user.
+	verificationResendCooldown = time.Minute
+	// twoFactorChallengeTTL is how long the second login step may take.
+	twoFactorChallengeTTL = 5 * time.Minute
+	// accountDeletionTTL is how long an emailed account deletion confirmation stays valid.
+	accountDeletionTTL = time.Hour
+	// refreshReuseGrace is how long a rotated refresh token is still accepted, so that several
+	// tabs refreshing at the same moment are not mistaken for a stolen token being replayed.
+	refreshReuseGrace = 10 * time.Second
 )
 
+// ClientInfo describes the device a request came from. It is stored with refresh tokens
+// so users can recognise their sessions.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// Session is a signed-in device, i.e. one refresh token family.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+// LoginResult is the outcome of a successful first login step. Either the tokens are set, or,
+// when the account uses two-factor authentication, only TwoFactorChallenge is.
+type LoginResult struct {
+	AccessToken        string
+	RefreshCookieValue string
+	TwoFactorChallenge string
+}
+
+// TwoFactorSetup holds a pending TOTP secret and the otpauth URI to show as a QR code.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+// Identity is the authenticated caller described by a valid access token or API key.
+// APIKeyID and Scopes are only set for API keys; access tokens are not limited by scope.
+type Identity struct {
+	UserID   uuid.UUID
+	Role     string
+	APIKeyID uuid.UUID
+	Scopes   []string
+}
+
 // AuthService defines the interface for authentication operations.
 type AuthService interface {
 	Register(ctx context.Context, email, password, fullName string) (*user.User, error)
-	Login(ctx context.Context, email, password string) (accessToken, refreshCookieValue string, err error)
-	RefreshToken(ctx context.Context, refreshCookieValue string) (accessToken string, err error)
-	Logout(ctx context.Context, refreshCookieValue string) error
-	ParseAccessToken(tokenString string) (userID uuid.UUID, err error)
+	Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error)
+	CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, client ClientInfo) (*LoginResult, error)
+	RefreshToken(ctx context.Context, refreshCookieValue string, client ClientInfo) (accessToken, newRefreshCookieValue string, err error)
+	Logout(ctx context.Context, refreshCookieValue, accessToken string, client ClientInfo) error
+	ParseAccessToken(ctx context.Context, tokenString string) (*Identity, error)
 	Me(ctx context.Context, userID uuid.UUID) (*user.User, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, update ProfileUpdate) (*user.User, error)
+	ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword, refreshCookieValue string, client ClientInfo) error
+	DeleteAccount(ctx context.Context, userID uuid.UUID, password, confirmationToken string) error
+	ListSessions(ctx context.Context, userID uuid.UUID, refreshCookieValue string) ([]Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID uuid.UUID, refreshCookieValue string) error
+	RequestPasswordReset(ctx context.Context, email string, client ClientInfo) error
+	ResetPassword(ctx context.Context, token, newPassword string, client ClientInfo) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error
+	SetupTwoFactor(ctx context.Context, userID uuid.UUID) (*TwoFactorSetup, error)
+	EnableTwoFactor(ctx context.Context, userID uuid.UUID, code string) (recoveryCodes []string, err error)
+	DisableTwoFactor(ctx context.Context, userID uuid.UUID, password, code string) error
+	CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string, ttl time.Duration) (*CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKeyInfo, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error
+	ParseAPIKey(ctx context.Context, key string) (*Identity, error)
+	RequestMagicLink(ctx context.Context, email, nonce string) (string, error)
+	ConsumeMagicLink(ctx context.Context, token, nonce string, client ClientInfo) (*LoginResult, error)
+	OIDCProviders() []string
+	StartOIDCLogin(ctx context.Context, provider string) (authorizationURL, stateCookie string, err error)
+	CompleteOIDCLogin(ctx context.Context, provider, code, state, stateCookie string, client ClientInfo) (*LoginResult, error)
+	ListActivity(ctx context.Context, userID uuid.UUID, page, limit int) ([]user.AuthEvent, int64, error)
+	ListUsers(ctx context.Context, page, limit int) ([]user.User, int64, error)
+	ListAuthEvents(ctx context.Context, filter AuthEventFilter, page, limit int) ([]user.AuthEvent, int64, error)
+	SetUserRole(ctx context.Context, actorID, userID uuid.UUID, role string) error
+	BanUser(ctx context.Context, actorID, userID uuid.UUID) error
+	UnbanUser(ctx context.Context, userID uuid.UUID) error
+	PromoteAdmins(ctx context.Context, emails []string) error
 }
 
 // authService implements AuthService.
 type authService struct {
-	repo   AuthRepository
-	config *config.Config
+	repo        AuthRepository
+	config      *config.Config
+	mailer      mail.Sender
+	keyring     *Keyring
+	revocations RevocationStore
+	hasher      PasswordHasher
+	oidc        map[string]*oidcProvider
+	purger      AccountDataPurger
+	now         func() time.Time
 }
 
-// NewAuthService returns a new AuthService.
-func NewAuthService(repo AuthRepository, cfg *config.Config) AuthService {
-	return &authService{repo: repo, config: cfg}
+// NewAuthService returns a new AuthService. Transactional emails are delivered through mailer,
+// access tokens are signed with the active key in keyring and checked against revocations.
+// purger, if not nil, removes what a deleted account leaves outside the auth tables.
+func NewAuthService(repo AuthRepository, cfg *config.Config, mailer mail.Sender, keyring *Keyring, revocations RevocationStore, purger AccountDataPurger) AuthService {
+	return &authService{
+		repo:        repo,
+		purger:      purger,
+		config:      cfg,
+		mailer:      mailer,
+		keyring:     keyring,
+		revocations: revocations,
+		hasher:      NewPasswordHasher(cfg),
+		oidc:        newOIDCProviders(cfg.OIDCProviders),
+		now:         time.Now,
+	}
 }
 
-// Register creates a new user. Returns error if email already exists.
+// Register creates a new user and emails them a verification link. Returns error if email already exists.
 func (s *authService) Register(ctx context.Context, email, password, fullName string) (*user.User, error) {
 	existing, err := s.repo.FindUserByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 	if existing != nil {
-		return nil, fmt.Errorf("auth: email already registered")
+		return nil, ErrEmailTaken
 	}
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	hashed, err := s.hasher.Hash(password)
 	if err != nil {
 		return nil, err
 	}
 	now := time.Now()
 	u := &user.User{
 		Email:     email,
-		Password:  string(hashed),
+		Password:  hashed,
 		FullName:  fullName,
+		Role:      user.RoleCustomer,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 	if err := s.repo.CreateUser(ctx, u); err != nil {
 		return nil, err
 	}
+	if err := s.sendVerificationEmail(ctx, u, u.Email); err != nil {
+		// The account exists; the user can ask for another link.
+		log.Printf("auth: send verification email: %v", err)
+	}
 	return userWithoutPassword(u), nil
 }
 
-// Login authenticates the user and returns access token and refresh cookie value (id:rawToken).
-func (s *authService) Login(ctx context.Context, email, password string) (accessToken, refreshCookieValue string, err error) {
+// Login authenticates the user and returns an access token and refresh cookie value (id:rawToken).
+// If the user has 2FA enabled, only a short-lived challenge token for CompleteTwoFactorLogin is returned.
+func (s *authService) Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error) {
+	keys := s.loginThrottleKeys(email, client)
+	if err := s.checkLoginThrottle(ctx, keys); err != nil {
+		if errors.Is(err, ErrAccountLocked) {
+			s.recordFailure(ctx, EventLogin, uuid.Nil, email, "locked", client)
+		}
+		return nil, err
+	}
 	u, err := s.repo.FindUserByEmail(ctx, email)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 	if u == nil {
-		return "", "", fmt.Errorf("auth: invalid email or password")
+		// Take as long as a wrong password would, so the response time does not reveal the account.
+		s.hasher.VerifyDummy(password)
+		s.recordLoginFailure(ctx, email, client, keys)
+		s.recordFailure(ctx, EventLogin, uuid.Nil, email, "unknown_user", client)
+		return nil, ErrInvalidCredentials
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
-		return "", "", fmt.Errorf("auth: invalid email or password")
+	ok, needsRehash := s.hasher.Verify(u.Password, password)
+	if !ok {
+		s.recordLoginFailure(ctx, email, client, keys)
+		s.recordFailure(ctx, EventLogin, u.ID, u.Email, "invalid_credentials", client)
+		return nil, ErrInvalidCredentials
 	}
-	accessToken, err = s.generateAccessToken(u.ID, u.Email)
+	if needsRehash {
+		s.rehashPassword(ctx, u, password)
+	}
+	if !u.TwoFactorEnabled {
+		s.clearLoginFailures(ctx, keys)
+	}
+	result, err := s.firstFactorResult(ctx, u, client)
+	s.recordLogin(ctx, EventLogin, u, client, result, err)
+	return result, err
+}
+
+// rehashPassword replaces u's stored hash with one made by the current hasher. Failures are only
+// logged: the old hash still verifies and the upgrade is retried on the next login.
+func (s *authService) rehashPassword(ctx context.Context, u *user.User, password string) {
+	hashed, err := s.hasher.Hash(password)
 	if err != nil {
-		return "", "", err
+		log.Printf("auth: rehash password: %v", err)
+		return
 	}
-	rawRefresh := make([]byte, refreshTokenSize)
-	if _, err := rand.Read(rawRefresh); err != nil {
-		return "", "", err
+	if err := s.repo.UpdateUserPassword(ctx, u.ID, hashed); err != nil {
+		log.Printf("auth: store rehashed password: %v", err)
+		return
+	}
+	u.Password = hashed
+}
+
+// firstFactorResult finishes a successful first login step for u: it returns a 2FA challenge if the
+// account requires one and issues a session otherwise.
+func (s *authService) firstFactorResult(ctx context.Context, u *user.User, client ClientInfo) (*LoginResult, error) {
+	if u.BannedAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if u.TwoFactorEnabled {
+		challenge, err := s.signPurposeToken(purposeTwoFactorLogin, u.ID.String(), u.Email, twoFactorChallengeTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{TwoFactorChallenge: challenge}, nil
 	}
-	rawHex := hex.EncodeToString(rawRefresh)
-	hash, err := bcrypt.GenerateFromPassword([]byte(rawHex), bcryptCost)
+	return s.issueSession(ctx, u, client)
+}
+
+// CompleteTwoFactorLogin exchanges a login challenge and a TOTP or recovery code for tokens.
+func (s *authService) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, client ClientInfo) (*LoginResult, error) {
+	claims, err := s.parsePurposeToken(purposeTwoFactorLogin, challengeToken)
 	if err != nil {
-		return "", "", err
+		return nil, ErrInvalidChallenge
 	}
-	ttl := refreshTokenTTL
-	if s.config.JWTRefreshTTL != 0 {
-		ttl = time.Duration(s.config.JWTRefreshTTL)
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidChallenge
 	}
-	rt := &user.RefreshToken{
-		UserID:    u.ID,
-		TokenHash: string(hash),
-		ExpiresAt: time.Now().Add(ttl),
-		CreatedAt: time.Now(),
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
-	if err := s.repo.CreateRefreshToken(ctx, rt); err != nil {
-		return "", "", err
+	if u == nil || !u.TwoFactorEnabled {
+		return nil, ErrInvalidChallenge
+	}
+	// Wrong codes count against the same keys as wrong passwords.
+	keys := s.loginThrottleKeys(u.Email, client)
+	if err := s.checkLoginThrottle(ctx, keys); err != nil {
+		if errors.Is(err, ErrAccountLocked) {
+			s.recordFailure(ctx, EventLoginTwoFactor, u.ID, u.Email, "locked", client)
+		}
+		return nil, err
+	}
+	if err := s.checkSecondFactor(ctx, u, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.recordLoginFailure(ctx, u.Email, client, keys)
+			s.recordFailure(ctx, EventLoginTwoFactor, u.ID, u.Email, "invalid_code", client)
+		}
+		return nil, err
+	}
+	s.clearLoginFailures(ctx, keys)
+	result, err := s.issueSession(ctx, u, client)
+	s.recordLogin(ctx, EventLoginTwoFactor, u, client, result, err)
+	return result, err
+}
+
+// issueSession creates an access token and a refresh token in a new family for u.
+func (s *authService) issueSession(ctx context.Context, u *user.User, client ClientInfo) (*LoginResult, error) {
+	if u.BannedAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	accessToken, err := s.generateAccessToken(u)
+	if err != nil {
+		return nil, err
+	}
+	refreshCookieValue, err := s.issueRefreshToken(ctx, u.ID, uuid.New(), client)
+	if err != nil {
+		return nil, err
 	}
-	refreshCookieValue = rt.ID.String() + cookieSeparator + rawHex
-	return accessToken, refreshCookieValue, nil
+	return &LoginResult{AccessToken: accessToken, RefreshCookieValue: refreshCookieValue}, nil
 }
 
-// RefreshToken validates the refresh cookie and returns a new access token.
-func (s *authService) RefreshToken(ctx context.Context, refreshCookieValue string) (string, error) {
+// RefreshToken validates the refresh cookie, retires it and returns a new access token together
+// with a new refresh cookie value in the same token family. A token retired less than
+// refreshReuseGrace ago is accepted once more and gets its own successor in the family, so
+// concurrent tabs stay signed in; presenting it again, or after the grace period, is treated as
+// theft: the whole family is revoked and an error is returned.
+func (s *authService) RefreshToken(ctx context.Context, refreshCookieValue string, client ClientInfo) (string, string, error) {
 	tokenID, rawToken, err := parseRefreshCookie(refreshCookieValue)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	rt, err := s.repo.FindRefreshTokenByID(ctx, tokenID)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	if rt == nil {
-		return "", fmt.Errorf("auth: invalid refresh token")
-	}
-	if rt.ExpiresAt.Before(time.Now()) {
-		return "", fmt.Errorf("auth: refresh token expired")
+		return "", "", fmt.Errorf("auth: invalid refresh token")
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(rt.TokenHash), []byte(rawToken)); err != nil {
-		return "", fmt.Errorf("auth: invalid refresh token")
+		s.recordFailure(ctx, EventTokenRefresh, rt.UserID, "", "invalid_token", client)
+		return "", "", fmt.Errorf("auth: invalid refresh token")
+	}
+	now := s.now()
+	if rt.RevokedAt != nil && now.Sub(*rt.RevokedAt) > refreshReuseGrace {
+		return "", "", s.revokeFamily(ctx, rt, client)
+	}
+	if rt.ExpiresAt.Before(now) {
+		s.recordFailure(ctx, EventTokenRefresh, rt.UserID, "", "expired", client)
+		return "", "", fmt.Errorf("auth: refresh token expired")
+	}
+	reissue := rt.RevokedAt != nil
+	if !reissue {
+		retired, err := s.repo.RetireRefreshToken(ctx, rt.ID)
+		if err != nil {
+			return "", "", err
+		}
+		// Another request retired this token between our read and update, just now.
+		reissue = !retired
 	}
-	return s.generateAccessToken(rt.UserID, "")
+	if reissue {
+		ok, err := s.repo.MarkRefreshTokenReissued(ctx, rt.ID)
+		if err != nil {
+			return "", "", err
+		}
+		if !ok {
+			return "", "", s.revokeFamily(ctx, rt, client)
+		}
+	}
+	u, err := s.repo.FindUserByID(ctx, rt.UserID)
+	if err != nil {
+		return "", "", err
+	}
+	if u == nil {
+		return "", "", ErrUserNotFound
+	}
+	if u.BannedAt != nil {
+		s.recordFailure(ctx, EventTokenRefresh, u.ID, u.Email, "account_disabled", client)
+		return "", "", ErrAccountDisabled
+	}
+	accessToken, err := s.generateAccessToken(u)
+	if err != nil {
+		return "", "", err
+	}
+	newCookieValue, err := s.issueRefreshToken(ctx, rt.UserID, rt.FamilyID, client)
+	if err != nil {
+		return "", "", err
+	}
+	e := user.AuthEvent{Type: EventTokenRefresh, UserID: &u.ID, Email: u.Email, Outcome: OutcomeSuccess}
+	if reissue {
+		e.Reason = "reissued_within_grace"
+	}
+	s.recordEvent(ctx, client, e)
+	return accessToken, newCookieValue, nil
 }
 
-// Logout removes the refresh token from the database.
-func (s *authService) Logout(ctx context.Context, refreshCookieValue string) error {
-	tokenID, _, err := parseRefreshCookie(refreshCookieValue)
-	if err != nil {
-		return err
+// Logout revokes the refresh token family that the cookie belongs to and, if given, the access
+// token so it stops working immediately. Either value may be empty.
+func (s *authService) Logout(ctx context.Context, refreshCookieValue, accessToken string, client ClientInfo) error {
+	var userID uuid.UUID
+	if accessToken != "" {
+		// An invalid or expired access token needs no revoking.
+		if claims, err := s.parseJWT(accessToken); err == nil && claims.ExpiresAt != nil {
+			if err := s.revocations.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+				return err
+			}
+			userID, _ = uuid.Parse(claims.Subject)
+		}
+	}
+	if refreshCookieValue != "" {
+		tokenID, _, err := parseRefreshCookie(refreshCookieValue)
+		if err != nil {
+			return err
+		}
+		rt, err := s.repo.FindRefreshTokenByID(ctx, tokenID)
+		if err != nil {
+			return err
+		}
+		if rt != nil {
+			if err := s.repo.DeleteRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+				return err
+			}
+			userID = rt.UserID
+		}
+	}
+	if userID != uuid.Nil {
+		s.recordEvent(ctx, client, user.AuthEvent{Type: EventLogout, UserID: &userID, Outcome: OutcomeSuccess})
 	}
-	return s.repo.DeleteRefreshToken(ctx, tokenID)
+	return nil
 }
 
-// ParseAccessToken parses the JWT and returns the user ID. Returns error if invalid.
-func (s *authService) ParseAccessToken(tokenString string) (uuid.UUID, error) {
+// ParseAccessToken parses the JWT and returns the caller's identity. Returns error if invalid or revoked.
+// Tokens issued before roles existed carry no role claim and are treated as customers.
+func (s *authService) ParseAccessToken(ctx context.Context, tokenString string) (*Identity, error) {
 	claims, err := s.parseJWT(tokenString)
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 	id, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("auth: invalid token subject")
+		return nil, fmt.Errorf("auth: invalid token subject")
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
 	}
-	return id, nil
+	revoked, err := s.revocations.IsRevoked(ctx, claims.ID, id, issuedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, fmt.Errorf("auth: token revoked")
+	}
+	role := claims.Role
+	if role == "" {
+		role = user.RoleCustomer
+	}
+	return &Identity{UserID: id, Role: role}, nil
 }
 
 // Me returns the current user by ID without the password field.
@@ -169,37 +455,506 @@ func (s *authService) Me(ctx context.Context, userID uuid.UUID) (*user.User, err
 	return userWithoutPassword(u), nil
 }
 
+// RequestPasswordReset emails a single-use reset link to the user with the given email.
+// It returns nil when no such user exists so callers cannot probe for registered addresses: the
+// link is created and sent in the background, so known and unknown addresses take the same time.
+// Requests are limited per email and per client IP; over the limit a *RetryAfterError wrapping
+// ErrTooManyRequests is returned, whether or not the address is registered.
+func (s *authService) RequestPasswordReset(ctx context.Context, email string, client ClientInfo) error {
+	if err := s.checkPasswordResetRate(ctx, email, client); err != nil {
+		return err
+	}
+	u, err := s.repo.FindUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return nil
+	}
+	go s.sendPasswordReset(context.WithoutCancel(ctx), u)
+	return nil
+}
+
+// sendPasswordReset creates a reset token for u and emails the link. Failures are only logged,
+// since the request has already been answered.
+func (s *authService) sendPasswordReset(ctx context.Context, u *user.User) {
+	rawHex, hash, err := newSecret()
+	if err != nil {
+		log.Printf("auth: create password reset token: %v", err)
+		return
+	}
+	now := time.Now()
+	t := &user.PasswordResetToken{
+		UserID:    u.ID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(passwordResetTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreatePasswordResetToken(ctx, t); err != nil {
+		log.Printf("auth: create password reset token: %v", err)
+		return
+	}
+	link := s.config.AppBaseURL + "/reset-password?token=" + url.QueryEscape(t.ID.String()+cookieSeparator+rawHex)
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Reset your ShopGo password",
+		Body: "Someone asked to reset the password for your ShopGo account.\n\n" +
+			"Open this link within the next hour to choose a new password:\n" + link + "\n\n" +
+			"If you did not request this, you can ignore this email.",
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("auth: send password reset email: %v", err)
+	}
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the user out of every session,
+// including access tokens already handed out.
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string, client ClientInfo) error {
+	tokenID, rawToken, err := parseTokenValue(token)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	t, err := s.repo.FindPasswordResetTokenByID(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if t == nil || t.UsedAt != nil || t.ExpiresAt.Before(time.Now()) {
+		return ErrInvalidResetToken
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(t.TokenHash), []byte(rawToken)); err != nil {
+		return ErrInvalidResetToken
+	}
+	used, err := s.repo.MarkPasswordResetTokenUsed(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidResetToken
+	}
+	hashed, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateUserPassword(ctx, t.UserID, hashed); err != nil {
+		return err
+	}
+	if err := s.repo.DeletePasswordResetTokensByUserID(ctx, t.UserID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRefreshTokensByUserID(ctx, t.UserID); err != nil {
+		return err
+	}
+	if err := s.revokeUserAccessTokens(ctx, t.UserID, time.Now()); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, client, user.AuthEvent{Type: EventPasswordReset, UserID: &t.UserID, Outcome: OutcomeSuccess})
+	return nil
+}
+
+// VerifyEmail consumes an email verification token and marks the address as verified, or makes
+// a pending new address the user's email. Verifying an already verified address succeeds.
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := s.parsePurposeToken(purposeEmailVerification, token)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+	ok, err := s.repo.MarkEmailVerified(ctx, userID, claims.Email)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	// Not the current address, so it may confirm a pending email change.
+	taken, err := s.repo.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return err
+	}
+	if taken != nil && taken.ID != userID {
+		return ErrEmailTaken
+	}
+	ok, err = s.repo.ConfirmEmailChange(ctx, userID, claims.Email)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}
+
+// ResendVerificationEmail sends a new verification link to the user's pending address if an email
+// change is in progress, or to their unverified current address. It returns a *RetryAfterError
+// wrapping ErrTooManyRequests if the previous email was sent less than a minute ago.
+func (s *authService) ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return fmt.Errorf("auth: user not found")
+	}
+	to := u.PendingEmail
+	if to == "" {
+		if u.EmailVerified {
+			return ErrEmailAlreadyVerified
+		}
+		to = u.Email
+	}
+	if err := checkVerificationCooldown(u); err != nil {
+		return err
+	}
+	return s.sendVerificationEmail(ctx, u, to)
+}
+
+// checkVerificationCooldown returns a *RetryAfterError if u was sent a verification email too recently.
+func checkVerificationCooldown(u *user.User) error {
+	if u.VerificationSentAt != nil {
+		if wait := time.Until(u.VerificationSentAt.Add(verificationResendCooldown)); wait > 0 {
+			return &RetryAfterError{Err: ErrTooManyRequests, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// sendVerificationEmail emails a signed verification link for address to u and records when it was sent.
+func (s *authService) sendVerificationEmail(ctx context.Context, u *user.User, address string) error {
+	token, err := s.signPurposeToken(purposeEmailVerification, u.ID.String(), address, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := s.config.AppBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      address,
+		Subject: "Confirm your ShopGo email address",
+		Body: "Welcome to ShopGo!\n\n" +
+			"Please confirm your email address by opening this link:\n" + link + "\n\n" +
+			"The link expires in 48 hours.",
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+	return s.repo.UpdateVerificationSentAt(ctx, u.ID, time.Now())
+}
+
+// SetupTwoFactor generates a new TOTP secret for the user. 2FA is not enforced until
+// EnableTwoFactor confirms that the user's authenticator produces valid codes.
+func (s *authService) SetupTwoFactor(ctx context.Context, userID uuid.UUID) (*TwoFactorSetup, error) {
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("auth: user not found")
+	}
+	if u.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateTwoFactor(ctx, userID, secret, false); err != nil {
+		return nil, err
+	}
+	return &TwoFactorSetup{Secret: secret, OTPAuthURI: totpURI(secret, u.Email)}, nil
+}
+
+// EnableTwoFactor verifies the first code from the pending secret, turns 2FA on and returns
+// freshly generated recovery codes. The plaintext codes are never stored and cannot be shown again.
+func (s *authService) EnableTwoFactor(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("auth: user not found")
+	}
+	if u.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if u.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	counter, ok := validateTOTP(u.TOTPSecret, normalizeCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	records := make([]user.RecoveryCode, len(codes))
+	for i, c := range codes {
+		records[i] = user.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(c), CreatedAt: now}
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateTwoFactor(ctx, userID, u.TOTPSecret, true); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.AdvanceTOTPCounter(ctx, userID, counter); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a current TOTP or recovery code.
+func (s *authService) DisableTwoFactor(ctx context.Context, userID uuid.UUID, password, code string) error {
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return fmt.Errorf("auth: user not found")
+	}
+	if !u.TwoFactorEnabled {
+		return ErrTwoFactorNotSetUp
+	}
+	if ok, _ := s.hasher.Verify(u.Password, password); !ok {
+		return ErrInvalidCredentials
+	}
+	if err := s.checkSecondFactor(ctx, u, code); err != nil {
+		return err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, nil); err != nil {
+		return err
+	}
+	return s.repo.UpdateTwoFactor(ctx, userID, "", false)
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery code for u.
+// Accepted codes are consumed so they cannot be replayed.
+func (s *authService) checkSecondFactor(ctx context.Context, u *user.User, code string) error {
+	code = normalizeCode(code)
+	if len(code) == totpDigits {
+		counter, ok := validateTOTP(u.TOTPSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		advanced, err := s.repo.AdvanceTOTPCounter(ctx, u.ID, counter)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	used, err := s.repo.UseRecoveryCode(ctx, u.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// ListSessions returns the user's active sessions, most recently used first.
+// The session the refresh cookie belongs to, if any, is flagged as current. A family can hold
+// two live tokens after a refresh within the reuse grace period; it is listed once.
+func (s *authService) ListSessions(ctx context.Context, userID uuid.UUID, refreshCookieValue string) ([]Session, error) {
+	tokens, err := s.repo.FindRefreshTokenByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	current := s.currentFamily(ctx, userID, refreshCookieValue)
+	sessions := make([]Session, 0, len(tokens))
+	seen := make(map[uuid.UUID]bool, len(tokens))
+	for _, rt := range tokens {
+		if seen[rt.FamilyID] {
+			continue
+		}
+		seen[rt.FamilyID] = true
+		sessions = append(sessions, Session{
+			ID:         rt.FamilyID,
+			UserAgent:  rt.UserAgent,
+			IPAddress:  rt.IPAddress,
+			LastUsedAt: rt.LastUsedAt,
+			ExpiresAt:  rt.ExpiresAt,
+			Current:    rt.FamilyID == current,
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs out a single session of the user. Returns ErrSessionNotFound if the
+// session does not belong to the user.
+func (s *authService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	tokens, err := s.repo.FindRefreshTokenByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, rt := range tokens {
+		if rt.FamilyID == sessionID {
+			return s.repo.DeleteRefreshTokenFamily(ctx, sessionID)
+		}
+	}
+	return ErrSessionNotFound
+}
+
+// RevokeOtherSessions signs out every session of the user except the one the refresh cookie belongs to.
+// Without a valid refresh cookie every session is revoked.
+func (s *authService) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, refreshCookieValue string) error {
+	current := s.currentFamily(ctx, userID, refreshCookieValue)
+	return s.repo.DeleteOtherRefreshTokenFamilies(ctx, userID, current)
+}
+
+// currentFamily returns the token family of the refresh cookie if it belongs to userID, else uuid.Nil.
+func (s *authService) currentFamily(ctx context.Context, userID uuid.UUID, refreshCookieValue string) uuid.UUID {
+	if refreshCookieValue == "" {
+		return uuid.Nil
+	}
+	tokenID, _, err := parseRefreshCookie(refreshCookieValue)
+	if err != nil {
+		return uuid.Nil
+	}
+	rt, err := s.repo.FindRefreshTokenByID(ctx, tokenID)
+	if err != nil || rt == nil || rt.UserID != userID {
+		return uuid.Nil
+	}
+	return rt.FamilyID
+}
+
+// ListUsers returns a page of users without password hashes, and the total user count.
+func (s *authService) ListUsers(ctx context.Context, page, limit int) ([]user.User, int64, error) {
+	users, total, err := s.repo.ListUsers(ctx, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range users {
+		users[i] = *userWithoutPassword(&users[i])
+	}
+	return users, total, nil
+}
+
+// SetUserRole changes userID's role. Access tokens already issued stop working, so the change
+// applies at once rather than when they expire.
+// Admins cannot change their own role so the last admin cannot lock everyone out by accident.
+func (s *authService) SetUserRole(ctx context.Context, actorID, userID uuid.UUID, role string) error {
+	if !user.ValidRole(role) {
+		return ErrInvalidRole
+	}
+	if actorID == userID {
+		return ErrCannotChangeOwnRole
+	}
+	now := time.Now()
+	ok, err := s.repo.UpdateUserRole(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	// Access tokens carry the role, so as for a ban every token up to the end of this second goes;
+	// the user's sessions refresh into tokens with the new role.
+	return s.revokeUserAccessTokens(ctx, userID, now.Truncate(time.Second).Add(time.Second))
+}
+
+// BanUser disables userID's account: it can no longer sign in, refresh or use API keys, and access
+// tokens already issued stop working immediately.
+func (s *authService) BanUser(ctx context.Context, actorID, userID uuid.UUID) error {
+	if actorID == userID {
+		return ErrCannotBanSelf
+	}
+	now := time.Now()
+	ok, err := s.repo.SetUserBanned(ctx, userID, &now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	if err := s.repo.DeleteRefreshTokensByUserID(ctx, userID); err != nil {
+		return err
+	}
+	// A banned user cannot get new tokens, so every token up to the end of this second can go.
+	return s.revokeUserAccessTokens(ctx, userID, now.Truncate(time.Second).Add(time.Second))
+}
+
+// UnbanUser lifts a ban. The user has to sign in again.
+func (s *authService) UnbanUser(ctx context.Context, userID uuid.UUID) error {
+	ok, err := s.repo.SetUserBanned(ctx, userID, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// PromoteAdmins gives the admin role to the users with the given emails, so a new deployment can
+// get its first admin without touching the database. Only verified emails are promoted, so nobody
+// can claim the role by registering a listed address first; emails with no account yet are logged
+// and picked up on a later start.
+func (s *authService) PromoteAdmins(ctx context.Context, emails []string) error {
+	for _, email := range emails {
+		u, err := s.repo.FindUserByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+		switch {
+		case u == nil:
+			log.Printf("auth: admin bootstrap: no account for %s yet", email)
+		case !u.EmailVerified:
+			log.Printf("auth: admin bootstrap: %s has not verified their email, not promoting", email)
+		case u.Role != user.RoleAdmin:
+			if _, err := s.repo.UpdateUserRole(ctx, u.ID, user.RoleAdmin); err != nil {
+				return err
+			}
+			log.Printf("auth: admin bootstrap: promoted %s to admin", email)
+		}
+	}
+	return nil
+}
+
+// revokeUserAccessTokens rejects every access token of the user issued before the given time.
+// Token issue times have whole-second precision, so callers that keep the user signed in should
+// pass a time truncated to the second; tokens from that same second then stay valid.
+func (s *authService) revokeUserAccessTokens(ctx context.Context, userID uuid.UUID, issuedBefore time.Time) error {
+	return s.revocations.RevokeUserTokens(ctx, userID, issuedBefore, s.accessTokenTTL())
+}
+
+// accessTokenTTL returns the configured access token lifetime.
+func (s *authService) accessTokenTTL() time.Duration {
+	if s.config.JWTAccessTTL != 0 {
+		return time.Duration(s.config.JWTAccessTTL)
+	}
+	return accessTokenTTL
+}
+
 // accessClaims holds JWT claims for access tokens.
 type accessClaims struct {
 	jwt.RegisteredClaims
 	Email string `json:"email,omitempty"`
+	Role  string `json:"role,omitempty"`
 }
 
-func (s *authService) generateAccessToken(userID uuid.UUID, email string) (string, error) {
-	ttl := accessTokenTTL
-	if s.config.JWTAccessTTL != 0 {
-		ttl = time.Duration(s.config.JWTAccessTTL)
-	}
+func (s *authService) generateAccessToken(u *user.User) (string, error) {
 	now := time.Now()
 	claims := accessClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   userID.String(),
-			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        uuid.NewString(),
+			Subject:   u.ID.String(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
-		Email: email,
+		Email: u.Email,
+		Role:  u.Role,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWTSecret))
+	return s.keyring.Sign(claims)
 }
 
 func (s *authService) parseJWT(tokenString string) (*accessClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &accessClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("auth: unexpected signing method")
-		}
-		return []byte(s.config.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &accessClaims{}, s.keyring.Keyfunc)
 	if err != nil {
 		return nil, err
 	}
@@ -210,29 +965,99 @@ func (s *authService) parseJWT(tokenString string) (*accessClaims, error) {
 	return claims, nil
 }
 
+// issueRefreshToken creates a new refresh token in the given family and returns the cookie value (id:rawToken).
+func (s *authService) issueRefreshToken(ctx context.Context, userID, familyID uuid.UUID, client ClientInfo) (string, error) {
+	rawHex, hash, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+	ttl := refreshTokenTTL
+	if s.config.JWTRefreshTTL != 0 {
+		ttl = time.Duration(s.config.JWTRefreshTTL)
+	}
+	now := time.Now()
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = userAgent[:maxUserAgentLen]
+	}
+	rt := &user.RefreshToken{
+		UserID:     userID,
+		FamilyID:   familyID,
+		TokenHash:  hash,
+		UserAgent:  userAgent,
+		IPAddress:  client.IPAddress,
+		ExpiresAt:  now.Add(ttl),
+		LastUsedAt: now,
+		CreatedAt:  now,
+	}
+	if err := s.repo.CreateRefreshToken(ctx, rt); err != nil {
+		return "", err
+	}
+	return rt.ID.String() + cookieSeparator + rawHex, nil
+}
+
+// revokeFamily deletes every token in rt's family after reuse was detected, records the reuse and
+// returns the error to report.
+func (s *authService) revokeFamily(ctx context.Context, rt *user.RefreshToken, client ClientInfo) error {
+	if err := s.repo.DeleteRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+		return err
+	}
+	log.Printf("auth: refresh token reuse detected, revoked family %s", rt.FamilyID)
+	s.recordFailure(ctx, EventRefreshTokenReuse, rt.UserID, "", "family_revoked", client)
+	return ErrRefreshTokenReused
+}
+
+// newSecret returns a random hex token and its bcrypt hash for storage.
+func newSecret() (rawHex, hash string, err error) {
+	raw := make([]byte, refreshTokenSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	rawHex = hex.EncodeToString(raw)
+	hashed, err := bcrypt.GenerateFromPassword([]byte(rawHex), bcryptCost)
+	if err != nil {
+		return "", "", err
+	}
+	return rawHex, string(hashed), nil
+}
+
 func parseRefreshCookie(value string) (tokenID uuid.UUID, rawToken string, err error) {
+	tokenID, rawToken, err = parseTokenValue(value)
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("auth: invalid refresh cookie format")
+	}
+	return tokenID, rawToken, nil
+}
+
+// parseTokenValue splits an opaque token of the form id:rawToken.
+func parseTokenValue(value string) (tokenID uuid.UUID, rawToken string, err error) {
 	idx := strings.Index(value, cookieSeparator)
 	if idx == -1 {
-		return uuid.Nil, "", fmt.Errorf("auth: invalid refresh cookie format")
+		return uuid.Nil, "", fmt.Errorf("auth: invalid token format")
 	}
 	idStr := value[:idx]
 	rawToken = value[idx+len(cookieSeparator):]
 	if idStr == "" || rawToken == "" {
-		return uuid.Nil, "", fmt.Errorf("auth: invalid refresh cookie format")
+		return uuid.Nil, "", fmt.Errorf("auth: invalid token format")
 	}
 	tokenID, err = uuid.Parse(idStr)
 	if err != nil {
-		return uuid.Nil, "", fmt.Errorf("auth: invalid refresh cookie format")
+		return uuid.Nil, "", fmt.Errorf("auth: invalid token format")
 	}
 	return tokenID, rawToken, nil
 }
 
 func userWithoutPassword(u *user.User) *user.User {
 	return &user.User{
-		ID:        u.ID,
-		Email:     u.Email,
-		FullName:  u.FullName,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:               u.ID,
+		Email:            u.Email,
+		FullName:         u.FullName,
+		Role:             u.Role,
+		EmailVerified:    u.EmailVerified,
+		PendingEmail:     u.PendingEmail,
+		TwoFactorEnabled: u.TwoFactorEnabled,
+		BannedAt:         u.BannedAt,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
 	}
 }
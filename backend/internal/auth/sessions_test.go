@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// signIn starts a new session for userID from a device with the given user agent and returns its
+// refresh cookie value.
+func signIn(t *testing.T, svc *authService, userID uuid.UUID, userAgent string) string {
+	t.Helper()
+	cookie, err := svc.issueRefreshToken(context.Background(), userID, uuid.New(), ClientInfo{UserAgent: userAgent, IPAddress: "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cookie
+}
+
+func TestListSessions(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	other := repo.addUser("other@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	signIn(t, svc, u.ID, "laptop")
+	phone := signIn(t, svc, u.ID, "phone")
+	signIn(t, svc, other.ID, "someone else")
+
+	sessions, err := svc.ListSessions(ctx, u.ID, phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("%d sessions, want 2", len(sessions))
+	}
+	for _, s := range sessions {
+		if s.Current != (s.UserAgent == "phone") {
+			t.Errorf("session %q current = %v", s.UserAgent, s.Current)
+		}
+		if s.IPAddress != "192.0.2.1" {
+			t.Errorf("session %q IP = %q", s.UserAgent, s.IPAddress)
+		}
+	}
+
+	// Another user's cookie does not mark any of the user's sessions as current.
+	sessions, err = svc.ListSessions(ctx, u.ID, signIn(t, svc, other.ID, "someone else"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sessions {
+		if s.Current {
+			t.Errorf("session %q flagged current for another user's cookie", s.UserAgent)
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	other := repo.addUser("other@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	laptop := signIn(t, svc, u.ID, "laptop")
+	signIn(t, svc, other.ID, "someone else")
+	sessions, err := svc.ListSessions(ctx, other.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.RevokeSession(ctx, u.ID, sessions[0].ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("revoking another user's session: err = %v, want ErrSessionNotFound", err)
+	}
+	if err := svc.RevokeSession(ctx, u.ID, uuid.New()); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("revoking an unknown session: err = %v, want ErrSessionNotFound", err)
+	}
+	if n := repo.refreshTokenCount(); n != 2 {
+		t.Fatalf("%d refresh tokens left, want both", n)
+	}
+
+	mine, err := svc.ListSessions(ctx, u.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RevokeSession(ctx, u.ID, mine[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.RefreshToken(ctx, laptop, ClientInfo{}); err == nil {
+		t.Error("the revoked session can still refresh")
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	other := repo.addUser("other@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	laptop := signIn(t, svc, u.ID, "laptop")
+	signIn(t, svc, u.ID, "phone")
+	signIn(t, svc, u.ID, "tablet")
+	signIn(t, svc, other.ID, "someone else")
+
+	if err := svc.RevokeOtherSessions(ctx, u.ID, laptop); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := svc.ListSessions(ctx, u.ID, laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || !sessions[0].Current || sessions[0].UserAgent != "laptop" {
+		t.Fatalf("sessions = %+v, want only the current laptop session", sessions)
+	}
+	if others, _ := svc.ListSessions(ctx, other.ID, ""); len(others) != 1 {
+		t.Errorf("another user's sessions were revoked: %+v", others)
+	}
+
+	// Without a valid cookie every session goes.
+	if err := svc.RevokeOtherSessions(ctx, u.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, _ := svc.ListSessions(ctx, u.ID, ""); len(sessions) != 0 {
+		t.Errorf("%d sessions left, want none", len(sessions))
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+)
+
+// Login throttling defaults, used when the corresponding config values are zero.
+const (
+	defaultMaxFailuresPerEmail = 10
+	defaultMaxFailuresPerIP    = 50
+	defaultLockoutDuration     = 15 * time.Minute
+	// Once half of a key's threshold is reached, every further failure blocks the key for
+	// loginBackoffBase, doubling with each failure up to loginBackoffMax.
+	loginBackoffBase = time.Second
+	loginBackoffMax  = time.Minute
+)
+
+// Password reset request limits. Requests are counted per email address and per client IP in the
+// request counter table under "reset:" keys; a key that has made more than its limit since it was
+// last quiet for passwordResetWindow is refused.
+const (
+	passwordResetMaxPerEmail = 3
+	passwordResetMaxPerIP    = 20
+	passwordResetWindow      = time.Hour
+)
+
+// throttleKey identifies one dimension (email or client IP) that failed logins are counted against.
+type throttleKey struct {
+	key       string
+	threshold int
+}
+
+// loginThrottleKeys returns the keys a login attempt for email from client is counted against.
+func (s *authService) loginThrottleKeys(email string, client ClientInfo) []throttleKey {
+	perEmail := s.config.LoginMaxFailuresPerEmail
+	if perEmail <= 0 {
+		perEmail = defaultMaxFailuresPerEmail
+	}
+	perIP := s.config.LoginMaxFailuresPerIP
+	if perIP <= 0 {
+		perIP = defaultMaxFailuresPerIP
+	}
+	keys := []throttleKey{{key: "email:" + strings.ToLower(strings.TrimSpace(email)), threshold: perEmail}}
+	if client.IPAddress != "" {
+		keys = append(keys, throttleKey{key: "ip:" + client.IPAddress, threshold: perIP})
+	}
+	return keys
+}
+
+// checkPasswordResetRate counts a password reset request against the email and the client IP and
+// returns a *RetryAfterError wrapping ErrTooManyRequests if either is over its limit.
+func (s *authService) checkPasswordResetRate(ctx context.Context, email string, client ClientInfo) error {
+	keys := []throttleKey{{key: "reset:email:" + strings.ToLower(strings.TrimSpace(email)), threshold: passwordResetMaxPerEmail}}
+	if client.IPAddress != "" {
+		keys = append(keys, throttleKey{key: "reset:ip:" + client.IPAddress, threshold: passwordResetMaxPerIP})
+	}
+	now := time.Now()
+	limited := false
+	for _, k := range keys {
+		requests, err := s.repo.IncrementRequestCount(ctx, k.key, now.Add(-passwordResetWindow))
+		if err != nil {
+			return err
+		}
+		if requests > k.threshold {
+			limited = true
+		}
+	}
+	if limited {
+		return &RetryAfterError{Err: ErrTooManyRequests, RetryAfter: passwordResetWindow}
+	}
+	return nil
+}
+
+// lockoutDuration returns how long a key stays locked once it reaches its threshold.
+func (s *authService) lockoutDuration() time.Duration {
+	if s.config.LoginLockoutDuration > 0 {
+		return time.Duration(s.config.LoginLockoutDuration)
+	}
+	return defaultLockoutDuration
+}
+
+// checkLoginThrottle returns a *RetryAfterError wrapping ErrAccountLocked if any key is currently blocked.
+func (s *authService) checkLoginThrottle(ctx context.Context, keys []throttleKey) error {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.key
+	}
+	throttles, err := s.repo.FindLoginThrottles(ctx, names)
+	if err != nil {
+		return err
+	}
+	var wait time.Duration
+	now := time.Now()
+	for _, t := range throttles {
+		if t.LockedUntil != nil && t.LockedUntil.After(now) {
+			if d := t.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return &RetryAfterError{Err: ErrAccountLocked, RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt against every key, applying exponential backoff and
+// locking a key out (with an audit record) once it reaches its threshold.
+func (s *authService) recordLoginFailure(ctx context.Context, email string, client ClientInfo, keys []throttleKey) {
+	lockout := s.lockoutDuration()
+	now := time.Now()
+	for _, k := range keys {
+		failures, err := s.repo.IncrementLoginFailures(ctx, k.key, now.Add(-lockout))
+		if err != nil {
+			log.Printf("auth: record login failure: %v", err)
+			continue
+		}
+		block := loginBlockDuration(failures, k.threshold, lockout)
+		if block == 0 {
+			continue
+		}
+		until := now.Add(block)
+		if err := s.repo.SetLoginLockedUntil(ctx, k.key, until); err != nil {
+			log.Printf("auth: set login lock: %v", err)
+			continue
+		}
+		if failures >= k.threshold {
+			record := &user.LoginLockout{
+				Key:         k.key,
+				Email:       email,
+				IPAddress:   client.IPAddress,
+				Failures:    failures,
+				LockedUntil: until,
+				CreatedAt:   now,
+			}
+			if err := s.repo.CreateLoginLockout(ctx, record); err != nil {
+				log.Printf("auth: record lockout: %v", err)
+			}
+			s.recordFailure(ctx, EventLoginLockout, uuid.Nil, email, k.key, client)
+		}
+	}
+}
+
+// clearLoginFailures forgets failed attempts for the email after a successful login.
+// The IP key is left alone so one valid account cannot be used to reset an attacker's IP counter.
+func (s *authService) clearLoginFailures(ctx context.Context, keys []throttleKey) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := s.repo.DeleteLoginThrottle(ctx, keys[0].key); err != nil {
+		log.Printf("auth: clear login failures: %v", err)
+	}
+}
+
+// loginBlockDuration returns how long to block a key after its failures-th consecutive failure.
+func loginBlockDuration(failures, threshold int, lockout time.Duration) time.Duration {
+	if failures >= threshold {
+		return lockout
+	}
+	start := threshold / 2
+	if failures < start {
+		return 0
+	}
+	d := loginBackoffBase
+	for i := start; i < failures && d < loginBackoffMax; i++ {
+		d *= 2
+	}
+	if d > loginBackoffMax {
+		d = loginBackoffMax
+	}
+	return d
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/pkg/config"
+)
+
+func TestLoginBlockDuration(t *testing.T) {
+	lockout := 15 * time.Minute
+	tests := []struct {
+		failures, threshold int
+		want                time.Duration
+	}{
+		{1, 10, 0},
+		{4, 10, 0},
+		{5, 10, time.Second},
+		{6, 10, 2 * time.Second},
+		{9, 10, 16 * time.Second},
+		{10, 10, lockout},
+		{25, 10, lockout},
+		{17, 20, time.Minute},
+		{19, 20, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := loginBlockDuration(tt.failures, tt.threshold, lockout); got != tt.want {
+			t.Errorf("loginBlockDuration(%d, %d) = %s, want %s", tt.failures, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottleLocksOutAtThreshold(t *testing.T) {
+	repo := newFakeAuthRepo()
+	cfg := &config.Config{LoginMaxFailuresPerEmail: 4, LoginMaxFailuresPerIP: 100, LoginLockoutDuration: config.Duration(time.Hour)}
+	svc := newTestService(t, repo, &fakeMailer{}, cfg)
+	ctx := context.Background()
+	client := ClientInfo{IPAddress: "192.0.2.1"}
+	keys := svc.loginThrottleKeys(" Member@Example.com", client)
+	if keys[0].key != "email:member@example.com" || keys[1].key != "ip:192.0.2.1" {
+		t.Fatalf("keys = %+v", keys)
+	}
+
+	svc.recordLoginFailure(ctx, "member@example.com", client, keys)
+	if err := svc.checkLoginThrottle(ctx, keys); err != nil {
+		t.Fatalf("blocked after one failure: %v", err)
+	}
+	svc.recordLoginFailure(ctx, "member@example.com", client, keys)
+	var retry *RetryAfterError
+	if err := svc.checkLoginThrottle(ctx, keys); !errors.As(err, &retry) || retry.RetryAfter > loginBackoffBase {
+		t.Fatalf("after two failures: err = %v, want a backoff of at most %s", err, loginBackoffBase)
+	}
+	for i := 0; i < 2; i++ {
+		svc.recordLoginFailure(ctx, "member@example.com", client, keys)
+	}
+	err := svc.checkLoginThrottle(ctx, keys)
+	if !errors.As(err, &retry) || !errors.Is(err, ErrAccountLocked) || retry.RetryAfter < time.Hour-time.Minute {
+		t.Fatalf("at the threshold: err = %v, want a lockout of about an hour", err)
+	}
+	if len(repo.lockouts) != 1 || repo.lockouts[0].Key != keys[0].key {
+		t.Errorf("lockout records = %+v, want one for the email key", repo.lockouts)
+	}
+
+	// A locked account is refused before the password is looked at.
+	if _, err := svc.Login(ctx, "member@example.com", "whatever", client); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Login while locked: err = %v, want ErrAccountLocked", err)
+	}
+}
+
+func TestClearLoginFailuresKeepsIPCount(t *testing.T) {
+	repo := newFakeAuthRepo()
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+	keys := svc.loginThrottleKeys("member@example.com", ClientInfo{IPAddress: "192.0.2.1"})
+
+	svc.recordLoginFailure(ctx, "member@example.com", ClientInfo{IPAddress: "192.0.2.1"}, keys)
+	svc.clearLoginFailures(ctx, keys)
+	throttles, err := repo.FindLoginThrottles(ctx, []string{keys[0].key, keys[1].key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(throttles) != 1 || throttles[0].Key != keys[1].key {
+		t.Errorf("throttles after a successful login = %+v, want only the IP key", throttles)
+	}
+}
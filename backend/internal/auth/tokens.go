@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token purposes. Each purpose signs with its own key derived from JWTSecret, so a token
+// issued for one purpose can never be accepted for another (or as an access token).
+const (
+	purposeEmailVerification = "email-verification"
+	purposeTwoFactorLogin    = "2fa-login"
+	purposeOIDCState         = "oidc-state"
+	purposeAccountDeletion   = "account-deletion"
+)
+
+// purposeClaims holds JWT claims for single-purpose tokens such as email verification links.
+type purposeClaims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email,omitempty"`
+}
+
+// purposeKey derives the HMAC signing key for purpose from the JWT secret.
+func (s *authService) purposeKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(s.config.JWTSecret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// signPurposeToken returns a signed token for purpose carrying subject and email, valid for ttl.
+func (s *authService) signPurposeToken(purpose, subject, email string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := purposeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{purpose},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Email: email,
+	}
+	return s.signPurposeClaims(purpose, claims)
+}
+
+// parsePurposeToken verifies a token issued by signPurposeToken for purpose and returns its claims.
+func (s *authService) parsePurposeToken(purpose, tokenString string) (*purposeClaims, error) {
+	claims := &purposeClaims{}
+	if err := s.parsePurposeClaims(purpose, tokenString, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// signPurposeClaims signs arbitrary claims with the key for purpose. The claims' audience must be purpose.
+func (s *authService) signPurposeClaims(purpose string, claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.purposeKey(purpose))
+}
+
+// parsePurposeClaims verifies tokenString against the key and audience for purpose and decodes it into claims.
+func (s *authService) parsePurposeClaims(purpose, tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("auth: unexpected signing method")
+		}
+		return s.purposeKey(purpose), nil
+	}, jwt.WithAudience(purpose))
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return fmt.Errorf("auth: invalid token")
+	}
+	return nil
+}
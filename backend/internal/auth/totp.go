@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238 defaults understood by every authenticator app).
+const (
+	totpIssuer       = "ShopGo"
+	totpPeriod       = 30
+	totpDigits       = 6
+	totpSecretSize   = 20
+	totpSkewSteps    = 1
+	recoveryCodeSize = 10
+	recoveryCodes    = 10
+)
+
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newTOTPSecret returns a random base32-encoded TOTP secret.
+func newTOTPSecret() (string, error) {
+	raw := make([]byte, totpSecretSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base32NoPad.EncodeToString(raw), nil
+}
+
+// totpURI returns the otpauth:// URI that authenticator apps scan as a QR code.
+func totpURI(secret, accountName string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", totpIssuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(totpIssuer + ":" + accountName)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// totpCode computes the HOTP value (RFC 4226) of secret for counter.
+func totpCode(secret string, counter int64) (string, error) {
+	key, err := base32NoPad.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, bin%mod), nil
+}
+
+// validateTOTP checks code against secret at time t, allowing one step of clock skew either way.
+// It returns the matching time-step counter so callers can reject replays of the same code.
+func validateTOTP(secret, code string, t time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := t.Unix() / totpPeriod
+	for step := -totpSkewSteps; step <= totpSkewSteps; step++ {
+		counter := current + int64(step)
+		expected, err := totpCode(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes returns plaintext recovery codes formatted as xxxxx-xxxxx.
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodes)
+	for i := range codes {
+		raw := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		enc := strings.ToLower(base32NoPad.EncodeToString(raw))[:recoveryCodeSize]
+		codes[i] = enc[:recoveryCodeSize/2] + "-" + enc[recoveryCodeSize/2:]
+	}
+	return codes, nil
+}
+
+// normalizeCode strips separators and whitespace from a user-entered code.
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// hashRecoveryCode hashes a normalized recovery code. Codes carry 50 bits of entropy,
+// so a fast hash is sufficient and allows direct lookup.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeCode(code)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key from the RFC 6238 test vectors, base32-encoded.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := totpCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	code := func(counter int64) string {
+		c, err := totpCode(rfc6238Secret, counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	tests := []struct {
+		name    string
+		code    string
+		counter int64
+		ok      bool
+	}{
+		{"current step", code(current), current, true},
+		{"one step behind", code(current - 1), current - 1, true},
+		{"one step ahead", code(current + 1), current + 1, true},
+		{"two steps behind", code(current - 2), 0, false},
+		{"two steps ahead", code(current + 2), 0, false},
+		{"wrong length", code(current)[:5], 0, false},
+	}
+	for _, tt := range tests {
+		counter, ok := validateTOTP(rfc6238Secret, tt.code, now)
+		if ok != tt.ok || counter != tt.counter {
+			t.Errorf("%s: validateTOTP = %d, %v; want %d, %v", tt.name, counter, ok, tt.counter, tt.ok)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodes {
+		t.Fatalf("%d codes, want %d", len(codes), recoveryCodes)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := make(map[string]bool)
+	for _, c := range codes {
+		if !format.MatchString(c) {
+			t.Errorf("code %q is not formatted as xxxxx-xxxxx", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate code %q", c)
+		}
+		seen[c] = true
+	}
+	if hashRecoveryCode(" ABCDE-FGHIJ ") != hashRecoveryCode("abcdefghij") {
+		t.Error("recovery code hash depends on case, separators or whitespace")
+	}
+}
+
+func TestSecondFactorCodesCannotBeReplayed(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", true)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	ctx := context.Background()
+
+	setup, err := svc.SetupTwoFactor(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enable with the previous step's code so the current one is still unused afterwards.
+	previous, err := totpCode(setup.Secret, time.Now().Unix()/totpPeriod-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := svc.EnableTwoFactor(ctx, u.ID, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled, _ := repo.FindUserByID(ctx, u.ID)
+
+	if err := svc.checkSecondFactor(ctx, enabled, previous); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("replayed enrolment code: err = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	current, err := totpCode(setup.Secret, time.Now().Unix()/totpPeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.checkSecondFactor(ctx, enabled, current); err != nil {
+		t.Errorf("current code: %v", err)
+	}
+	if err := svc.checkSecondFactor(ctx, enabled, current); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("replayed current code: err = %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	if err := svc.checkSecondFactor(ctx, enabled, recovery[0]); err != nil {
+		t.Errorf("recovery code: %v", err)
+	}
+	if err := svc.checkSecondFactor(ctx, enabled, recovery[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("reused recovery code: err = %v, want ErrInvalidTwoFactorCode", err)
+	}
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+var tokenParam = regexp.MustCompile(`[?&]token=([^&\s]+)`)
+
+// emailedToken returns the token query parameter of the link in the last email, which must have
+// been sent to to.
+func emailedToken(t *testing.T, mailer *fakeMailer, to string) string {
+	t.Helper()
+	msg := mailer.last(t)
+	if msg.To != to {
+		t.Fatalf("email sent to %q, want %q", msg.To, to)
+	}
+	m := tokenParam.FindStringSubmatch(msg.Body)
+	if m == nil {
+		t.Fatalf("no token in email body %q", msg.Body)
+	}
+	token, err := url.QueryUnescape(m[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// verificationRouter serves the email verification routes and a checkout route gated on a verified email.
+func verificationRouter(svc AuthService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/verify-email", handleVerifyEmail(svc))
+	r.POST("/verify-email/resend", JWTMiddleware(svc), handleResendVerification(svc))
+	r.POST("/checkout/intent", JWTMiddleware(svc), RequireVerifiedEmail(svc), func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return r
+}
+
+// serve sends a JSON POST to path with an optional bearer token and returns the recorded response.
+func serve(r http.Handler, path, accessToken string, body interface{}) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestVerifyEmail(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", false)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, &config.Config{AppBaseURL: "https://shop.example.com"})
+	r := verificationRouter(svc)
+	ctx := context.Background()
+
+	if err := svc.ResendVerificationEmail(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	token := emailedToken(t, mailer, u.Email)
+	if body := mailer.last(t).Body; !strings.Contains(body, "https://shop.example.com/verify-email?token=") {
+		t.Errorf("email body %q does not link to the app", body)
+	}
+
+	if w := serve(r, "/verify-email", "", VerifyEmailRequest{Token: token}); w.Code != http.StatusOK {
+		t.Fatalf("verify: status %d, body %s", w.Code, w.Body)
+	}
+	if got, _ := repo.FindUserByID(ctx, u.ID); !got.EmailVerified {
+		t.Fatal("email not marked verified")
+	}
+	// Opening the link again, e.g. from another device, is harmless.
+	if w := serve(r, "/verify-email", "", VerifyEmailRequest{Token: token}); w.Code != http.StatusOK {
+		t.Errorf("second verify: status %d, body %s", w.Code, w.Body)
+	}
+}
+
+func TestVerifyEmailRejectsBadTokens(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", false)
+	svc := newTestService(t, repo, &fakeMailer{}, nil)
+	r := verificationRouter(svc)
+
+	expired, err := svc.signPurposeToken(purposeEmailVerification, u.ID.String(), u.Email, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPurpose, err := svc.signPurposeToken(purposeAccountDeletion, u.ID.String(), u.Email, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAddress, err := svc.signPurposeToken(purposeEmailVerification, u.ID.String(), "someone@example.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := svc.signPurposeToken(purposeEmailVerification, u.ID.String(), u.Email, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, token string
+	}{
+		{"expired", expired},
+		{"issued for another purpose", otherPurpose},
+		{"for an address the user does not have", otherAddress},
+		{"tampered", valid[:len(valid)-2] + "xx"},
+		{"garbage", "not-a-token"},
+	}
+	for _, tt := range tests {
+		if err := svc.VerifyEmail(context.Background(), tt.token); !errors.Is(err, ErrInvalidVerificationToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidVerificationToken", tt.name, err)
+		}
+		w := serve(r, "/verify-email", "", VerifyEmailRequest{Token: tt.token})
+		if w.Code != http.StatusBadRequest || !bytes.Contains(w.Body.Bytes(), []byte("INVALID_TOKEN")) {
+			t.Errorf("%s: status %d, body %s; want 400 INVALID_TOKEN", tt.name, w.Code, w.Body)
+		}
+	}
+	if got, _ := repo.FindUserByID(context.Background(), u.ID); got.EmailVerified {
+		t.Error("a rejected token verified the email")
+	}
+}
+
+func TestVerifyEmailConfirmsPendingChange(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("old@example.com", true)
+	repo.users[u.ID].PendingEmail = "new@example.com"
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	ctx := context.Background()
+
+	if err := svc.ResendVerificationEmail(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.VerifyEmail(ctx, emailedToken(t, mailer, "new@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := repo.FindUserByID(ctx, u.ID)
+	if got.Email != "new@example.com" || got.PendingEmail != "" || !got.EmailVerified {
+		t.Errorf("after confirming: email %q, pending %q, verified %v", got.Email, got.PendingEmail, got.EmailVerified)
+	}
+}
+
+func TestResendVerificationIsRateLimited(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", false)
+	verified := repo.addUser("verified@example.com", true)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	r := verificationRouter(svc)
+
+	token, err := svc.generateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, "/verify-email/resend", token, nil); w.Code != http.StatusOK {
+		t.Fatalf("first resend: status %d, body %s", w.Code, w.Body)
+	}
+	w := serve(r, "/verify-email/resend", token, nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("second resend: status %d, Retry-After %q; want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	if mailer.count() != 1 {
+		t.Fatalf("%d emails sent, want 1", mailer.count())
+	}
+
+	// Once the cooldown has passed another email may be sent.
+	sentAt := time.Now().Add(-verificationResendCooldown - time.Second)
+	repo.users[u.ID].VerificationSentAt = &sentAt
+	if w := serve(r, "/verify-email/resend", token, nil); w.Code != http.StatusOK {
+		t.Fatalf("resend after the cooldown: status %d, body %s", w.Code, w.Body)
+	}
+
+	verifiedToken, err := svc.generateAccessToken(verified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, "/verify-email/resend", verifiedToken, nil); w.Code != http.StatusConflict {
+		t.Errorf("resend for a verified address: status %d, want 409", w.Code)
+	}
+	if w := serve(r, "/verify-email/resend", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("resend without a token: status %d, want 401", w.Code)
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	repo := newFakeAuthRepo()
+	u := repo.addUser("member@example.com", false)
+	mailer := &fakeMailer{}
+	svc := newTestService(t, repo, mailer, nil)
+	r := verificationRouter(svc)
+	ctx := context.Background()
+
+	token, err := svc.generateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := serve(r, "/checkout/intent", token, nil)
+	if w.Code != http.StatusForbidden || !bytes.Contains(w.Body.Bytes(), []byte("EMAIL_NOT_VERIFIED")) {
+		t.Fatalf("unverified checkout: status %d, body %s; want 403 EMAIL_NOT_VERIFIED", w.Code, w.Body)
+	}
+
+	if err := svc.ResendVerificationEmail(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.VerifyEmail(ctx, emailedToken(t, mailer, u.Email)); err != nil {
+		t.Fatal(err)
+	}
+	// The gate reads the stored user, so the token issued before verification now passes.
+	if w := serve(r, "/checkout/intent", token, nil); w.Code != http.StatusCreated {
+		t.Errorf("verified checkout: status %d, body %s", w.Code, w.Body)
+	}
+	if w := serve(r, "/checkout/intent", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous checkout: status %d, want 401", w.Code)
+	}
+}
@@ -1,10 +1,12 @@
 package cart
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/Rakesh2908/shopgo/internal/auth"
+	"github.com/Rakesh2908/shopgo/internal/inventory"
 	"github.com/Rakesh2908/shopgo/pkg/response"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -63,6 +65,10 @@ func handleAddItem(svc Service) gin.HandlerFunc {
 				response.Error(c, http.StatusNotFound, "PRODUCT_NOT_FOUND", "product not found")
 				return
 			}
+			if errors.Is(err, inventory.ErrOutOfStock) {
+				inventory.WriteOutOfStock(c, err)
+				return
+			}
 			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to add item")
 			return
 		}
@@ -113,6 +119,10 @@ func handleUpdateQuantity(svc Service) gin.HandlerFunc {
 				response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", "quantity must be at least 1")
 				return
 			}
+			if errors.Is(err, inventory.ErrOutOfStock) {
+				inventory.WriteOutOfStock(c, err)
+				return
+			}
 			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update quantity")
 			return
 		}
@@ -113,6 +113,12 @@ func (r *repository) ClearCart(ctx context.Context, userID uuid.UUID) error {
 	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&CartItem{}).Error
 }
 
+// DeleteUserData removes the user's cart when their account is deleted. tx is the account
+// deletion transaction; it matches auth.AccountCleanup.
+func DeleteUserData(ctx context.Context, tx *gorm.DB, userID uuid.UUID) error {
+	return NewRepository(tx).ClearCart(ctx, userID)
+}
+
 // MergeGuestCart merges guest cart items into the user's cart by upserting each item (adding quantities).
 func (r *repository) MergeGuestCart(ctx context.Context, userID uuid.UUID, items []GuestCartItem) error {
 	for _, gi := range items {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/Rakesh2908/shopgo/internal/inventory"
 	"github.com/Rakesh2908/shopgo/internal/product"
 	"github.com/google/uuid"
 )
@@ -32,16 +33,17 @@ type Service interface {
 
 // service implements Service.
 type service struct {
-	repo    Repository
-	product product.ProductService
+	repo      Repository
+	product   product.ProductService
+	inventory inventory.Service
 }
 
 // NewService returns a new cart Service.
-func NewService(repo Repository, product product.ProductService) Service {
-	return &service{repo: repo, product: product}
+func NewService(repo Repository, product product.ProductService, inv inventory.Service) Service {
+	return &service{repo: repo, product: product, inventory: inv}
 }
 
-// AddItem validates the product exists, then upserts the cart item.
+// AddItem validates the product exists and has enough stock, then upserts the cart item.
 func (s *service) AddItem(ctx context.Context, userID uuid.UUID, productID int, quantity int) (*CartItem, error) {
 	if quantity < 1 {
 		return nil, errors.New("cart: quantity must be at least 1")
@@ -50,6 +52,9 @@ func (s *service) AddItem(ctx context.Context, userID uuid.UUID, productID int,
 	if err != nil {
 		return nil, fmt.Errorf("cart: product not found: %w", err)
 	}
+	if err := s.inventory.CheckAvailable(ctx, []inventory.Line{{ProductID: productID, Quantity: quantity}}); err != nil {
+		return nil, err
+	}
 	return s.repo.UpsertItem(ctx, userID, productID, quantity)
 }
 
@@ -59,11 +64,17 @@ func (s *service) GetCart(ctx context.Context, userID uuid.UUID) ([]CartItemResp
 	if err != nil {
 		return nil, err
 	}
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	// Items whose product no longer exists or could not be loaded are skipped, so the error only
+	// matters for the products that are missing from the map.
+	products, _ := s.product.GetByIDs(ctx, ids)
 	out := make([]CartItemResponse, 0, len(items))
 	for _, item := range items {
-		p, err := s.product.GetByID(ctx, item.ProductID)
-		if err != nil {
-			// Skip items whose product no longer exists, or return error per your preference
+		p, ok := products[item.ProductID]
+		if !ok {
 			continue
 		}
 		subtotal := p.Price * float64(item.Quantity)
@@ -80,7 +91,8 @@ func (s *service) GetCart(ctx context.Context, userID uuid.UUID) ([]CartItemResp
 	return out, nil
 }
 
-// UpdateQuantity validates quantity >= 1 and that the item belongs to the user, then updates.
+// UpdateQuantity validates quantity >= 1, that the item belongs to the user and that there is enough
+// stock, then updates.
 func (s *service) UpdateQuantity(ctx context.Context, userID uuid.UUID, itemID uuid.UUID, quantity int) error {
 	if quantity < 1 {
 		return errors.New("cart: quantity must be at least 1")
@@ -95,6 +107,9 @@ func (s *service) UpdateQuantity(ctx context.Context, userID uuid.UUID, itemID u
 	if item.UserID != userID {
 		return errors.New("cart: item not found")
 	}
+	if err := s.inventory.CheckAvailable(ctx, []inventory.Line{{ProductID: item.ProductID, Quantity: quantity}}); err != nil {
+		return err
+	}
 	return s.repo.UpdateQuantity(ctx, itemID, quantity)
 }
 
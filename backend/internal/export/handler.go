@@ -0,0 +1,105 @@
+package export
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Rakesh2908/shopgo/internal/auth"
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// exportResponse is a DataExport plus the link to download it once ready.
+type exportResponse struct {
+	*DataExport
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+// RegisterRoutes registers personal data export routes on the given router group, which must already
+// require JWT auth and reject API keys, since an archive holds everything about its owner.
+// Group path should be "/auth/me/export" so routes are POST /auth/me/export, GET /auth/me/export
+// and GET /auth/me/export/:id/download.
+func RegisterRoutes(rg *gin.RouterGroup, svc Service) {
+	base := rg.BasePath()
+	rg.POST("", handleRequestExport(svc, base))
+	rg.GET("", handleGetExport(svc, base))
+	rg.GET("/:id/download", handleDownloadExport(svc))
+}
+
+// handleRequestExport handles POST /auth/me/export — starts building an archive of the caller's data.
+func handleRequestExport(svc Service, base string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := auth.GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "missing user context")
+			return
+		}
+		e, err := svc.Request(c.Request.Context(), userID)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start export")
+			return
+		}
+		response.Success(c, http.StatusAccepted, toResponse(e, base))
+	}
+}
+
+// handleGetExport handles GET /auth/me/export — returns the status of the caller's latest export
+// and, once it is ready, its download link.
+func handleGetExport(svc Service, base string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := auth.GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "missing user context")
+			return
+		}
+		e, err := svc.Latest(c.Request.Context(), userID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "no export requested")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get export")
+			return
+		}
+		response.Success(c, http.StatusOK, toResponse(e, base))
+	}
+}
+
+// handleDownloadExport handles GET /auth/me/export/:id/download — streams the finished zip archive.
+func handleDownloadExport(svc Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := auth.GetUserIDFromContext(c)
+		if userID == uuid.Nil {
+			response.Error(c, http.StatusUnauthorized, "UNAUTHORIZED", "missing user context")
+			return
+		}
+		exportID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid export id")
+			return
+		}
+		path, err := svc.ArchivePath(c.Request.Context(), userID, exportID)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrNotFound):
+				response.Error(c, http.StatusNotFound, "NOT_FOUND", "export not found")
+			case errors.Is(err, ErrNotReady):
+				response.Error(c, http.StatusConflict, "EXPORT_NOT_READY", "export is not available for download")
+			default:
+				response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get export")
+			}
+			return
+		}
+		c.FileAttachment(path, "shopgo-data-export.zip")
+	}
+}
+
+// toResponse adds the download link to ready exports.
+func toResponse(e *DataExport, base string) exportResponse {
+	out := exportResponse{DataExport: e}
+	if e.Status == StatusReady {
+		out.DownloadURL = base + "/" + e.ID.String() + "/download"
+	}
+	return out
+}
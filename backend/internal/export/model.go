@@ -0,0 +1,35 @@
+package export
+
+import (
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+)
+
+// Export statuses.
+const (
+	StatusPending = "pending"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+)
+
+// DataExport is one personal data export job. FilePath points at the finished zip archive,
+// which can be downloaded until ExpiresAt.
+type DataExport struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"-"`
+	Status      string     `gorm:"not null;default:pending" json:"status"`
+	FilePath    string     `json:"-"`
+	SizeBytes   int64      `json:"sizeBytes,omitempty"`
+	Error       string     `gorm:"type:text" json:"-"`
+	CreatedAt   time.Time  `gorm:"not null" json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expiresAt,omitempty"`
+	User        user.User  `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName overrides the table name for DataExport.
+func (DataExport) TableName() string {
+	return "data_exports"
+}
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository defines the interface for data export persistence.
+type Repository interface {
+	CreatePending(ctx context.Context, e *DataExport, staleBefore time.Time) (*DataExport, bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*DataExport, error)
+	GetLatestByUserID(ctx context.Context, userID uuid.UUID) (*DataExport, error)
+	Update(ctx context.Context, e *DataExport) (bool, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]DataExport, error)
+	ListExpired(ctx context.Context, now time.Time) ([]DataExport, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// repository implements Repository using GORM.
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository returns a new export Repository.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// CreatePending inserts the pending job e unless the user already has a pending export created
+// after staleBefore, in which case that export is returned instead. The check and the insert run in
+// one transaction that locks the user's row, so concurrent requests start at most one build.
+// The returned bool reports whether e was inserted.
+func (r *repository) CreatePending(ctx context.Context, e *DataExport, staleBefore time.Time) (*DataExport, bool, error) {
+	var running *DataExport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var u user.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("id = ?", e.UserID).First(&u).Error; err != nil {
+			return err
+		}
+		var existing DataExport
+		err := tx.Where("user_id = ? AND status = ? AND created_at > ?", e.UserID, StatusPending, staleBefore).
+			Order("created_at DESC").First(&existing).Error
+		if err == nil {
+			running = &existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Create(e).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if running != nil {
+		return running, false, nil
+	}
+	return e, true, nil
+}
+
+// GetByID returns the export with the given ID, or nil if not found.
+func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*DataExport, error) {
+	var e DataExport
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&e).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetLatestByUserID returns the user's most recent export, or nil if there is none.
+func (r *repository) GetLatestByUserID(ctx context.Context, userID uuid.UUID) (*DataExport, error) {
+	var e DataExport
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").First(&e).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Update saves all fields of the export. Unlike Save it never inserts, so an export deleted in the
+// meantime stays deleted; it returns false in that case.
+func (r *repository) Update(ctx context.Context, e *DataExport) (bool, error) {
+	res := r.db.WithContext(ctx).Model(e).Select("*").Omit("User").Updates(e)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// ListByUserID returns all of the user's exports.
+func (r *repository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]DataExport, error) {
+	var exports []DataExport
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&exports).Error
+	return exports, err
+}
+
+// ListExpired returns exports whose download window ended before now.
+func (r *repository) ListExpired(ctx context.Context, now time.Time) ([]DataExport, error) {
+	var exports []DataExport
+	err := r.db.WithContext(ctx).Where("expires_at <= ?", now).Find(&exports).Error
+	return exports, err
+}
+
+// Delete removes an export record.
+func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&DataExport{}).Error
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the Postgres database in TEST_DATABASE_URL, skipping the test if it is
+// not set.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user.User{}, &DataExport{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCreatePendingStartsOneBuildPerUser(t *testing.T) {
+	db := openTestDB(t)
+	u := &user.User{Email: uuid.NewString() + "@example.com", Password: "x", Role: user.RoleCustomer}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Where("user_id = ?", u.ID).Delete(&DataExport{})
+		db.Unscoped().Delete(u)
+	})
+	repo := NewRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	// A pending job older than the timeout is lost and does not block a new build.
+	lost := &DataExport{UserID: u.ID, Status: StatusPending, CreatedAt: now.Add(-2 * exportTimeout)}
+	if err := db.Create(lost).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	const requests = 8
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+		ids     = make(map[uuid.UUID]bool)
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e, ok, err := repo.CreatePending(ctx, &DataExport{UserID: u.ID, Status: StatusPending, CreatedAt: time.Now()}, now.Add(-exportTimeout))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				created++
+			}
+			ids[e.ID] = true
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 || len(ids) != 1 || ids[lost.ID] {
+		t.Errorf("%d builds started, %d distinct exports returned; want one new export shared by all", created, len(ids))
+	}
+	var pending int64
+	db.Model(&DataExport{}).Where("user_id = ? AND status = ? AND id <> ?", u.ID, StatusPending, lost.ID).Count(&pending)
+	if pending != 1 {
+		t.Errorf("%d pending exports, want 1", pending)
+	}
+}
@@ -0,0 +1,415 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/auth"
+	"github.com/Rakesh2908/shopgo/internal/cart"
+	"github.com/Rakesh2908/shopgo/internal/order"
+	"github.com/Rakesh2908/shopgo/internal/review"
+	"github.com/Rakesh2908/shopgo/internal/wishlist"
+	"github.com/google/uuid"
+)
+
+const (
+	// exportTTL is how long a finished archive can be downloaded.
+	exportTTL = 24 * time.Hour
+	// exportReuseWindow is how long a finished export is handed out again instead of starting a new one.
+	exportReuseWindow = time.Hour
+	// exportTimeout bounds one archive build; pending jobs older than this are considered lost.
+	exportTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrNotFound is returned when the user has no such export.
+	ErrNotFound = errors.New("export: not found")
+	// ErrNotReady is returned when the archive is still being built, failed or has expired.
+	ErrNotReady = errors.New("export: archive not available")
+)
+
+// Sources are the repositories an export reads the user's data from.
+type Sources struct {
+	Auth     auth.AuthRepository
+	Cart     cart.Repository
+	Wishlist wishlist.Repository
+	Order    order.Repository
+	Review   review.Repository
+}
+
+// Service defines the interface for personal data exports.
+type Service interface {
+	Request(ctx context.Context, userID uuid.UUID) (*DataExport, error)
+	Latest(ctx context.Context, userID uuid.UUID) (*DataExport, error)
+	ArchivePath(ctx context.Context, userID, exportID uuid.UUID) (string, error)
+	PurgeUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// service implements Service.
+type service struct {
+	repo    Repository
+	sources Sources
+	dir     string
+}
+
+// NewService returns a new export Service that writes archives into dir
+// (default "shopgo-exports" under os.TempDir()).
+func NewService(repo Repository, sources Sources, dir string) Service {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "shopgo-exports")
+	}
+	return &service{repo: repo, sources: sources, dir: dir}
+}
+
+// Request starts building an archive of the user's data in the background and returns the pending job.
+// If a build is already running, or one finished within the last hour, that export is returned instead.
+// A user has at most one build running, however many requests arrive at once.
+func (s *service) Request(ctx context.Context, userID uuid.UUID) (*DataExport, error) {
+	s.purgeExpired(ctx)
+	latest, err := s.Latest(ctx, userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if latest != nil {
+		switch {
+		case latest.Status == StatusPending:
+			return latest, nil
+		case latest.Status == StatusReady && time.Since(latest.CreatedAt) < exportReuseWindow:
+			return latest, nil
+		}
+	}
+	now := time.Now()
+	e, created, err := s.repo.CreatePending(ctx, &DataExport{UserID: userID, Status: StatusPending, CreatedAt: now}, now.Add(-exportTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return e, nil
+	}
+	job := *e
+	go s.build(&job)
+	return e, nil
+}
+
+// Latest returns the user's most recent export. A pending job that outlived exportTimeout,
+// e.g. because the server restarted, is reported as failed.
+func (s *service) Latest(ctx context.Context, userID uuid.UUID) (*DataExport, error) {
+	e, err := s.repo.GetLatestByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, ErrNotFound
+	}
+	if e.Status == StatusPending && time.Since(e.CreatedAt) > exportTimeout {
+		e.Status = StatusFailed
+		e.Error = "export: build did not finish"
+		if _, err := s.repo.Update(ctx, e); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// ArchivePath returns the path of the finished zip archive for one of the user's exports.
+func (s *service) ArchivePath(ctx context.Context, userID, exportID uuid.UUID) (string, error) {
+	e, err := s.repo.GetByID(ctx, exportID)
+	if err != nil {
+		return "", err
+	}
+	if e == nil || e.UserID != userID {
+		return "", ErrNotFound
+	}
+	if e.Status != StatusReady || e.ExpiresAt == nil || !e.ExpiresAt.After(time.Now()) {
+		return "", ErrNotReady
+	}
+	return e.FilePath, nil
+}
+
+// build writes the archive for e and records the outcome. If the account was deleted while the
+// archive was being built, the archive is thrown away and nothing is recorded.
+func (s *service) build(e *DataExport) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	path, size, err := s.writeArchive(ctx, e)
+	if err == nil {
+		u, uerr := s.sources.Auth.FindUserByID(ctx, e.UserID)
+		if uerr != nil || u == nil {
+			s.removeArchive(path)
+			if uerr != nil {
+				log.Printf("export: build %s: %v", e.ID, uerr)
+			}
+			return
+		}
+	}
+	now := time.Now()
+	e.CompletedAt = &now
+	if err != nil {
+		log.Printf("export: build %s: %v", e.ID, err)
+		e.Status = StatusFailed
+		e.Error = err.Error()
+	} else {
+		expires := now.Add(exportTTL)
+		e.Status = StatusReady
+		e.FilePath = path
+		e.SizeBytes = size
+		e.ExpiresAt = &expires
+	}
+	ok, err := s.repo.Update(ctx, e)
+	if err != nil {
+		log.Printf("export: save %s: %v", e.ID, err)
+	}
+	if !ok && e.FilePath != "" {
+		// The export was deleted with its account while it was being built.
+		s.removeArchive(e.FilePath)
+	}
+}
+
+// writeArchive collects the user's data and writes it as a zip of JSON files.
+// The archive is written to a temporary file first so a partial file is never served.
+func (s *service) writeArchive(ctx context.Context, e *DataExport) (string, int64, error) {
+	files, err := s.collect(ctx, e.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", 0, err
+	}
+	tmp, err := os.CreateTemp(s.dir, "export-*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	zw := zip.NewWriter(tmp)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			tmp.Close()
+			return "", 0, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			tmp.Close()
+			return "", 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(s.dir, e.ID.String()+".zip")
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+// archiveFile is one JSON document in the export archive.
+type archiveFile struct {
+	name string
+	data interface{}
+}
+
+// collect reads everything stored about the user from the existing repositories.
+func (s *service) collect(ctx context.Context, userID uuid.UUID) ([]archiveFile, error) {
+	u, err := s.sources.Auth.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("export: user not found")
+	}
+	tokens, err := s.sources.Auth.FindRefreshTokenByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]sessionRecord, 0, len(tokens))
+	for _, t := range tokens {
+		sessions = append(sessions, sessionRecord{
+			ID:         t.FamilyID,
+			UserAgent:  t.UserAgent,
+			IPAddress:  t.IPAddress,
+			CreatedAt:  t.CreatedAt,
+			LastUsedAt: t.LastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+		})
+	}
+	keys, err := s.sources.Auth.ListAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	apiKeys := make([]apiKeyRecord, 0, len(keys))
+	for _, k := range keys {
+		apiKeys = append(apiKeys, apiKeyRecord{
+			ID:         k.ID,
+			Name:       k.Name,
+			Prefix:     k.Prefix,
+			Scopes:     k.Scopes,
+			ExpiresAt:  k.ExpiresAt,
+			LastUsedAt: k.LastUsedAt,
+			CreatedAt:  k.CreatedAt,
+		})
+	}
+	cartItems, err := s.sources.Cart.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	cartRecords := make([]cartRecord, 0, len(cartItems))
+	for _, item := range cartItems {
+		cartRecords = append(cartRecords, cartRecord{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+	wishlistItems, err := s.sources.Wishlist.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	wishlistRecords := make([]wishlistRecord, 0, len(wishlistItems))
+	for _, item := range wishlistItems {
+		wishlistRecords = append(wishlistRecords, wishlistRecord{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+	orders, err := s.sources.Order.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if orders == nil {
+		orders = []order.Order{}
+	}
+	reviews, err := s.sources.Review.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reviewRecords := make([]reviewRecord, 0, len(reviews))
+	for _, r := range reviews {
+		reviewRecords = append(reviewRecords, reviewRecord{
+			ID:        r.ID,
+			ProductID: r.ProductID,
+			Rating:    r.Rating,
+			Comment:   r.Comment,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	return []archiveFile{
+		{name: "profile.json", data: u},
+		{name: "sessions.json", data: sessions},
+		{name: "api_keys.json", data: apiKeys},
+		{name: "cart.json", data: cartRecords},
+		{name: "wishlist.json", data: wishlistRecords},
+		{name: "orders.json", data: orders},
+		{name: "reviews.json", data: reviewRecords},
+	}, nil
+}
+
+// PurgeUser deletes all of the user's archives and export records. It is called once the user's
+// account has been deleted.
+func (s *service) PurgeUser(ctx context.Context, userID uuid.UUID) error {
+	exports, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range exports {
+		if e.FilePath != "" && !s.removeArchive(e.FilePath) {
+			errs = append(errs, fmt.Errorf("export: remove archive of %s", e.ID))
+			continue
+		}
+		if err := s.repo.Delete(ctx, e.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeArchive deletes an archive file, logging failures. It reports whether the file is gone.
+func (s *service) removeArchive(path string) bool {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("export: remove %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// purgeExpired deletes archives and records whose download window has passed.
+func (s *service) purgeExpired(ctx context.Context) {
+	expired, err := s.repo.ListExpired(ctx, time.Now())
+	if err != nil {
+		log.Printf("export: list expired: %v", err)
+		return
+	}
+	for _, e := range expired {
+		if e.FilePath != "" && !s.removeArchive(e.FilePath) {
+			continue
+		}
+		if err := s.repo.Delete(ctx, e.ID); err != nil {
+			log.Printf("export: delete %s: %v", e.ID, err)
+		}
+	}
+}
+
+// sessionRecord is a signed-in device in sessions.json.
+type sessionRecord struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// apiKeyRecord is an API key (without its hash) in api_keys.json.
+type apiKeyRecord struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// cartRecord is a cart line in cart.json.
+type cartRecord struct {
+	ID        uuid.UUID `json:"id"`
+	ProductID int       `json:"productId"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// wishlistRecord is a wishlist entry in wishlist.json.
+type wishlistRecord struct {
+	ID        uuid.UUID `json:"id"`
+	ProductID int       `json:"productId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// reviewRecord is a product review in reviews.json.
+type reviewRecord struct {
+	ID        uuid.UUID `json:"id"`
+	ProductID int       `json:"productId"`
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+}
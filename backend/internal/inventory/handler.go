@@ -0,0 +1,166 @@
+package inventory
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultPage  = 1
+	defaultLimit = 20
+)
+
+// SetStockRequest is the request body for PUT /admin/inventory/:productId.
+type SetStockRequest struct {
+	OnHand *int `json:"onHand" binding:"required,min=0"`
+}
+
+// stockResponse is a StockLevel with its available count.
+type stockResponse struct {
+	StockLevel
+	Available int `json:"available"`
+}
+
+// RegisterAdminRoutes registers back-office stock routes on the given router group, which must
+// already require a staff or admin access token. Group path should be "/admin/inventory" so routes
+// are GET /admin/inventory and GET/PUT/DELETE /admin/inventory/:productId.
+func RegisterAdminRoutes(rg *gin.RouterGroup, svc Service) {
+	rg.GET("", handleListStock(svc))
+	rg.GET("/:productId", handleGetStock(svc))
+	rg.PUT("/:productId", handleSetStock(svc))
+	rg.DELETE("/:productId", handleStopTracking(svc))
+}
+
+// handleListStock handles GET /admin/inventory?page=&limit=
+func handleListStock(svc Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", strconv.Itoa(defaultPage)))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+		if page < 1 {
+			page = defaultPage
+		}
+		if limit < 1 || limit > 100 {
+			limit = defaultLimit
+		}
+		levels, total, err := svc.ListStock(c.Request.Context(), page, limit)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list stock")
+			return
+		}
+		out := make([]stockResponse, len(levels))
+		for i := range levels {
+			out[i] = toResponse(&levels[i])
+		}
+		response.SuccessWithMeta(c, http.StatusOK, out, gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// handleGetStock handles GET /admin/inventory/:productId
+func handleGetStock(svc Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, ok := productIDParam(c)
+		if !ok {
+			return
+		}
+		lvl, err := svc.GetStock(c.Request.Context(), productID)
+		if err != nil {
+			if errors.Is(err, ErrNotTracked) {
+				response.Error(c, http.StatusNotFound, "NOT_TRACKED", "product stock is not tracked")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to get stock")
+			return
+		}
+		response.Success(c, http.StatusOK, toResponse(lvl))
+	}
+}
+
+// handleSetStock handles PUT /admin/inventory/:productId — sets the on-hand count and starts
+// tracking the product if it was not tracked yet.
+func handleSetStock(svc Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, ok := productIDParam(c)
+		if !ok {
+			return
+		}
+		var req SetStockRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		lvl, err := svc.SetStock(c.Request.Context(), productID, *req.OnHand)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to set stock")
+			return
+		}
+		response.Success(c, http.StatusOK, toResponse(lvl))
+	}
+}
+
+// handleStopTracking handles DELETE /admin/inventory/:productId — the product can no longer run out.
+func handleStopTracking(svc Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, ok := productIDParam(c)
+		if !ok {
+			return
+		}
+		if err := svc.StopTracking(c.Request.Context(), productID); err != nil {
+			if errors.Is(err, ErrNotTracked) {
+				response.Error(c, http.StatusNotFound, "NOT_TRACKED", "product stock is not tracked")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to stop tracking stock")
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"tracked": false})
+	}
+}
+
+// productIDParam parses the :productId path parameter, writing a 400 response if it is invalid.
+func productIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("productId"))
+	if err != nil || id < 1 {
+		response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid product id")
+		return 0, false
+	}
+	return id, true
+}
+
+// toResponse adds the available count to a stock level.
+func toResponse(lvl *StockLevel) stockResponse {
+	return stockResponse{StockLevel: *lvl, Available: lvl.Available()}
+}
+
+// validationMessage returns a short message from a validator error.
+func validationMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	if ve, ok := err.(validator.ValidationErrors); ok && len(ve) > 0 {
+		f := ve[0]
+		return strings.ToLower(f.Field()) + " " + f.Tag()
+	}
+	return err.Error()
+}
+
+// WriteOutOfStock writes the 409 OUT_OF_STOCK response for err, which must match ErrOutOfStock.
+// It is shared by the cart and checkout handlers.
+func WriteOutOfStock(c *gin.Context, err error) {
+	var oos *OutOfStockError
+	if errors.As(err, &oos) {
+		response.Error(c, http.StatusConflict, "OUT_OF_STOCK",
+			"only "+strconv.Itoa(oos.Available)+" left in stock for product "+strconv.Itoa(oos.ProductID))
+		return
+	}
+	response.Error(c, http.StatusConflict, "OUT_OF_STOCK", "not enough stock")
+}
@@ -0,0 +1,74 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+)
+
+// Reservation statuses. A reservation is awaiting payment when it expired or its payment failed
+// but its PaymentIntent could not be cancelled because it is being paid or has just been paid; its
+// stock stays held until the payment webhooks commit or release it.
+const (
+	StatusPending         = "pending"
+	StatusAwaitingPayment = "awaiting_payment"
+	StatusCommitted       = "committed"
+	StatusReleased        = "released"
+)
+
+// StockLevel is the stock of one product. Reserved units are held by pending checkouts and are not
+// available to anyone else. Products without a stock level are not tracked and never run out.
+type StockLevel struct {
+	ProductID int       `gorm:"primaryKey;autoIncrement:false" json:"productId"`
+	OnHand    int       `gorm:"not null;check:on_hand >= 0" json:"onHand"`
+	Reserved  int       `gorm:"not null;default:0;check:reserved >= 0" json:"reserved"`
+	UpdatedAt time.Time `gorm:"not null" json:"updatedAt"`
+}
+
+// TableName overrides the table name for StockLevel.
+func (StockLevel) TableName() string {
+	return "stock_levels"
+}
+
+// Available returns how many units can still be reserved.
+func (s *StockLevel) Available() int {
+	if s.OnHand < s.Reserved {
+		return 0
+	}
+	return s.OnHand - s.Reserved
+}
+
+// Reservation holds stock for one checkout from the moment its PaymentIntent is created until the
+// payment succeeds (committed), fails or times out (released). If releasing an expired reservation
+// fails, ExpiresAt is pushed back so the next attempt waits longer.
+type Reservation struct {
+	ID              uuid.UUID         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID          uuid.UUID         `gorm:"type:uuid;not null;index"`
+	PaymentIntentID string            `gorm:"index"`
+	Status          string            `gorm:"not null;default:pending;index"`
+	ExpiresAt       time.Time         `gorm:"not null;index"`
+	ReleaseAttempts int               `gorm:"not null;default:0"`
+	CreatedAt       time.Time         `gorm:"not null"`
+	UpdatedAt       time.Time         `gorm:"not null"`
+	Items           []ReservationItem `gorm:"foreignKey:ReservationID;constraint:OnDelete:CASCADE"`
+	User            user.User         `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName overrides the table name for Reservation.
+func (Reservation) TableName() string {
+	return "stock_reservations"
+}
+
+// ReservationItem is the quantity of one product held by a reservation.
+type ReservationItem struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ReservationID uuid.UUID `gorm:"type:uuid;not null;index"`
+	ProductID     int       `gorm:"not null"`
+	Quantity      int       `gorm:"not null"`
+}
+
+// TableName overrides the table name for ReservationItem.
+func (ReservationItem) TableName() string {
+	return "stock_reservation_items"
+}
@@ -0,0 +1,233 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository defines the interface for stock level and reservation persistence.
+type Repository interface {
+	GetStock(ctx context.Context, productID int) (*StockLevel, error)
+	GetStocks(ctx context.Context, productIDs []int) ([]StockLevel, error)
+	ListStock(ctx context.Context, page, limit int) ([]StockLevel, int64, error)
+	SetOnHand(ctx context.Context, productID, onHand int) (*StockLevel, error)
+	DeleteStock(ctx context.Context, productID int) (bool, error)
+	Reserve(ctx context.Context, r *Reservation) error
+	SetPaymentIntentID(ctx context.Context, id uuid.UUID, piID string) error
+	GetByPaymentIntentID(ctx context.Context, piID string) (*Reservation, error)
+	ListPendingByUserID(ctx context.Context, userID uuid.UUID) ([]Reservation, error)
+	ListExpired(ctx context.Context, now time.Time) ([]Reservation, error)
+	PostponeRelease(ctx context.Context, id uuid.UUID, until time.Time) error
+	MarkAwaitingPayment(ctx context.Context, id uuid.UUID) error
+	Commit(ctx context.Context, id uuid.UUID) error
+	Release(ctx context.Context, id uuid.UUID) error
+}
+
+// repository implements Repository using GORM.
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository returns a new inventory Repository.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// GetStock returns the product's stock level, or nil if the product is not tracked.
+func (r *repository) GetStock(ctx context.Context, productID int) (*StockLevel, error) {
+	var s StockLevel
+	err := r.db.WithContext(ctx).Where("product_id = ?", productID).First(&s).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetStocks returns the stock levels of the tracked products among productIDs.
+func (r *repository) GetStocks(ctx context.Context, productIDs []int) ([]StockLevel, error) {
+	var levels []StockLevel
+	err := r.db.WithContext(ctx).Where("product_id IN ?", productIDs).Find(&levels).Error
+	return levels, err
+}
+
+// ListStock returns a page of stock levels ordered by product ID and the total count.
+func (r *repository) ListStock(ctx context.Context, page, limit int) ([]StockLevel, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&StockLevel{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var levels []StockLevel
+	err := r.db.WithContext(ctx).
+		Order("product_id").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&levels).Error
+	return levels, total, err
+}
+
+// SetOnHand sets the product's on-hand count, starting to track it if needed. Reserved units are kept.
+func (r *repository) SetOnHand(ctx context.Context, productID, onHand int) (*StockLevel, error) {
+	s := StockLevel{ProductID: productID, OnHand: onHand, UpdatedAt: time.Now()}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"on_hand", "updated_at"}),
+	}).Create(&s).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetStock(ctx, productID)
+}
+
+// DeleteStock stops tracking the product. It returns false if the product was not tracked.
+func (r *repository) DeleteStock(ctx context.Context, productID int) (bool, error) {
+	res := r.db.WithContext(ctx).Where("product_id = ?", productID).Delete(&StockLevel{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// Reserve holds stock for every item of res and inserts it, all in one transaction. Stock rows are
+// locked in product order so concurrent checkouts cannot oversell or deadlock. If a tracked product
+// is short, nothing is reserved and an *OutOfStockError is returned.
+func (r *repository) Reserve(ctx context.Context, res *Reservation) error {
+	items := append([]ReservationItem(nil), res.Items...)
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductID < items[j].ProductID })
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, it := range items {
+			var s StockLevel
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("product_id = ?", it.ProductID).
+				First(&s).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if s.Available() < it.Quantity {
+				return &OutOfStockError{ProductID: it.ProductID, Requested: it.Quantity, Available: s.Available()}
+			}
+			err = tx.Model(&StockLevel{}).Where("product_id = ?", it.ProductID).
+				Updates(map[string]interface{}{
+					"reserved":   gorm.Expr("reserved + ?", it.Quantity),
+					"updated_at": now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(res).Error
+	})
+}
+
+// SetPaymentIntentID links the reservation to the PaymentIntent created for it.
+func (r *repository) SetPaymentIntentID(ctx context.Context, id uuid.UUID, piID string) error {
+	return r.db.WithContext(ctx).Model(&Reservation{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"payment_intent_id": piID, "updated_at": time.Now()}).Error
+}
+
+// GetByPaymentIntentID returns the reservation for the PaymentIntent, or nil if not found.
+func (r *repository) GetByPaymentIntentID(ctx context.Context, piID string) (*Reservation, error) {
+	var res Reservation
+	err := r.db.WithContext(ctx).Preload("Items").Where("payment_intent_id = ?", piID).First(&res).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListPendingByUserID returns the user's pending reservations, without their items.
+func (r *repository) ListPendingByUserID(ctx context.Context, userID uuid.UUID) ([]Reservation, error) {
+	var reservations []Reservation
+	err := r.db.WithContext(ctx).Where("user_id = ? AND status = ?", userID, StatusPending).
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// ListExpired returns the pending reservations that expired before now, without their items.
+func (r *repository) ListExpired(ctx context.Context, now time.Time) ([]Reservation, error) {
+	var reservations []Reservation
+	err := r.db.WithContext(ctx).Where("status = ? AND expires_at < ?", StatusPending, now).
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// PostponeRelease moves a pending reservation's expiry to until and counts a failed release attempt.
+func (r *repository) PostponeRelease(ctx context.Context, id uuid.UUID, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&Reservation{}).Where("id = ? AND status = ?", id, StatusPending).
+		Updates(map[string]interface{}{
+			"expires_at":       until,
+			"release_attempts": gorm.Expr("release_attempts + 1"),
+			"updated_at":       time.Now(),
+		}).Error
+}
+
+// MarkAwaitingPayment takes a pending reservation out of the expiry sweep, keeping its stock held
+// until its payment is committed or released.
+func (r *repository) MarkAwaitingPayment(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&Reservation{}).Where("id = ? AND status = ?", id, StatusPending).
+		Updates(map[string]interface{}{"status": StatusAwaitingPayment, "updated_at": time.Now()}).Error
+}
+
+// Commit turns the reservation into a sale: its units leave on-hand stock along with the hold.
+// A reservation that was already released is still committed since the customer has paid; its
+// units are taken from whatever is on hand. Releases cancel the PaymentIntent first, so this only
+// happens if Stripe let a payment through anyway. Committing twice is a no-op.
+func (r *repository) Commit(ctx context.Context, id uuid.UUID) error {
+	return r.settle(ctx, id, StatusCommitted)
+}
+
+// Release returns a pending or awaiting reservation's units to available stock. Releasing a
+// reservation that was already committed or released is a no-op.
+func (r *repository) Release(ctx context.Context, id uuid.UUID) error {
+	return r.settle(ctx, id, StatusReleased)
+}
+
+// settle moves the reservation to the given final status and adjusts stock levels accordingly.
+func (r *repository) settle(ctx context.Context, id uuid.UUID, status string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var res Reservation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&res).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if res.Status == status || res.Status == StatusCommitted {
+			return nil
+		}
+		var items []ReservationItem
+		if err := tx.Where("reservation_id = ?", id).Order("product_id").Find(&items).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, it := range items {
+			updates := map[string]interface{}{"updated_at": now}
+			if res.Status == StatusPending || res.Status == StatusAwaitingPayment {
+				updates["reserved"] = gorm.Expr("GREATEST(reserved - ?, 0)", it.Quantity)
+			}
+			if status == StatusCommitted {
+				updates["on_hand"] = gorm.Expr("GREATEST(on_hand - ?, 0)", it.Quantity)
+			}
+			if err := tx.Model(&StockLevel{}).Where("product_id = ?", it.ProductID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&Reservation{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": status, "updated_at": now}).Error
+	})
+}
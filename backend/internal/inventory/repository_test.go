@@ -0,0 +1,151 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/internal/user"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testProductID is a product ID far above any real catalog's, so tests do not touch real stock.
+const testProductID = 900001
+
+// newTestRepository connects to the Postgres database in TEST_DATABASE_URL, skipping the test if
+// it is not set. The test user and stock level are removed when the test ends.
+func newTestRepository(t *testing.T, onHand int) (Repository, uuid.UUID) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user.User{}, &StockLevel{}, &Reservation{}, &ReservationItem{}); err != nil {
+		t.Fatal(err)
+	}
+	u := user.User{Email: uuid.NewString() + "@example.com", Role: user.RoleCustomer}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Where("product_id = ?", testProductID).Delete(&StockLevel{})
+		db.Unscoped().Delete(&u)
+	})
+	repo := NewRepository(db)
+	if _, err := repo.SetOnHand(context.Background(), testProductID, onHand); err != nil {
+		t.Fatal(err)
+	}
+	return repo, u.ID
+}
+
+func reserve(repo Repository, userID uuid.UUID, quantity int) (*Reservation, error) {
+	res := &Reservation{
+		UserID:    userID,
+		Status:    StatusPending,
+		ExpiresAt: time.Now().Add(time.Minute),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Items:     []ReservationItem{{ProductID: testProductID, Quantity: quantity}},
+	}
+	return res, repo.Reserve(context.Background(), res)
+}
+
+func assertStock(t *testing.T, repo Repository, onHand, reserved int) {
+	t.Helper()
+	lvl, err := repo.GetStock(context.Background(), testProductID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lvl.OnHand != onHand || lvl.Reserved != reserved {
+		t.Fatalf("stock = %d on hand, %d reserved; want %d, %d", lvl.OnHand, lvl.Reserved, onHand, reserved)
+	}
+}
+
+func TestReserveDoesNotOversell(t *testing.T) {
+	repo, userID := newTestRepository(t, 5)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved, short := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := reserve(repo, userID, 1)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				reserved++
+			case errors.Is(err, ErrOutOfStock):
+				short++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if reserved != 5 || short != 15 {
+		t.Fatalf("%d reserved and %d out of stock, want 5 and 15", reserved, short)
+	}
+	assertStock(t, repo, 5, 5)
+}
+
+func TestSettleIsIdempotent(t *testing.T) {
+	repo, userID := newTestRepository(t, 5)
+	ctx := context.Background()
+
+	committed, err := reserve(repo, userID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released, err := reserve(repo, userID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStock(t, repo, 5, 3)
+
+	for i := 0; i < 2; i++ {
+		if err := repo.Commit(ctx, committed.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Release(ctx, released.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertStock(t, repo, 3, 0)
+
+	// A late failure webhook cannot put sold units back on sale.
+	if err := repo.Release(ctx, committed.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(t, repo, 3, 0)
+}
+
+func TestCommitAwaitingPayment(t *testing.T) {
+	repo, userID := newTestRepository(t, 5)
+	ctx := context.Background()
+
+	res, err := reserve(repo, userID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.MarkAwaitingPayment(ctx, res.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(t, repo, 5, 2)
+	if err := repo.Commit(ctx, res.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(t, repo, 3, 0)
+}
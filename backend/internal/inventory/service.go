@@ -0,0 +1,286 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultReservationTTL is how long a checkout holds stock when no timeout is configured.
+	defaultReservationTTL = 15 * time.Minute
+	// sweepInterval is how often Run releases expired reservations.
+	sweepInterval = time.Minute
+	// Failed releases of an expired reservation are retried after releaseBackoff, doubling with
+	// every attempt up to maxReleaseBackoff.
+	releaseBackoff    = time.Minute
+	maxReleaseBackoff = time.Hour
+)
+
+var (
+	// ErrOutOfStock is returned when a product does not have enough available units.
+	// It is always wrapped in an *OutOfStockError.
+	ErrOutOfStock = errors.New("inventory: out of stock")
+	// ErrNotTracked is returned when a product has no stock level.
+	ErrNotTracked = errors.New("inventory: product stock is not tracked")
+	// ErrNotCancellable is returned by a PaymentCanceller when the payment is being processed or
+	// has succeeded, so it will end in a payment webhook rather than a cancellation.
+	ErrNotCancellable = errors.New("inventory: payment can no longer be cancelled")
+)
+
+// OutOfStockError reports which product is short and how many units are left.
+type OutOfStockError struct {
+	ProductID int
+	Requested int
+	Available int
+}
+
+// Error implements error.
+func (e *OutOfStockError) Error() string {
+	return fmt.Sprintf("inventory: out of stock: product %d has %d available, %d requested", e.ProductID, e.Available, e.Requested)
+}
+
+// Unwrap returns ErrOutOfStock so errors.Is matches the sentinel.
+func (e *OutOfStockError) Unwrap() error {
+	return ErrOutOfStock
+}
+
+// Line is a quantity of one product, e.g. a cart item.
+type Line struct {
+	ProductID int
+	Quantity  int
+}
+
+// Service defines the interface for stock levels and checkout reservations.
+type Service interface {
+	CheckAvailable(ctx context.Context, lines []Line) error
+	Reserve(ctx context.Context, userID uuid.UUID, lines []Line) (*Reservation, error)
+	AttachPaymentIntent(ctx context.Context, reservationID uuid.UUID, piID string) error
+	Cancel(ctx context.Context, reservationID uuid.UUID) error
+	Commit(ctx context.Context, piID string) error
+	Release(ctx context.Context, piID string) error
+	Run(ctx context.Context)
+	GetReservation(ctx context.Context, piID string) (*Reservation, error)
+	GetStock(ctx context.Context, productID int) (*StockLevel, error)
+	ListStock(ctx context.Context, page, limit int) ([]StockLevel, int64, error)
+	SetStock(ctx context.Context, productID, onHand int) (*StockLevel, error)
+	StopTracking(ctx context.Context, productID int) error
+}
+
+// PaymentCanceller cancels the PaymentIntent of a reservation that is about to be released, so the
+// checkout can no longer be paid once its units are back on sale. It returns an error matching
+// ErrNotCancellable if the payment is already being processed or has gone through.
+type PaymentCanceller interface {
+	CancelPaymentIntent(ctx context.Context, piID string) error
+}
+
+// service implements Service.
+type service struct {
+	repo     Repository
+	ttl      time.Duration
+	payments PaymentCanceller
+}
+
+// NewService returns a new inventory Service. Reservations not committed within ttl (default 15
+// minutes) are released by Run after their PaymentIntent is cancelled through payments.
+func NewService(repo Repository, ttl time.Duration, payments PaymentCanceller) Service {
+	if ttl <= 0 {
+		ttl = defaultReservationTTL
+	}
+	return &service{repo: repo, ttl: ttl, payments: payments}
+}
+
+// CheckAvailable returns an *OutOfStockError if any tracked product has fewer available units than
+// its line asks for. It takes no hold; Reserve re-checks under lock.
+func (s *service) CheckAvailable(ctx context.Context, lines []Line) error {
+	ids := make([]int, len(lines))
+	for i, l := range lines {
+		ids[i] = l.ProductID
+	}
+	levels, err := s.repo.GetStocks(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byProduct := make(map[int]*StockLevel, len(levels))
+	for i := range levels {
+		byProduct[levels[i].ProductID] = &levels[i]
+	}
+	for _, l := range lines {
+		if lvl, ok := byProduct[l.ProductID]; ok && lvl.Available() < l.Quantity {
+			return &OutOfStockError{ProductID: l.ProductID, Requested: l.Quantity, Available: lvl.Available()}
+		}
+	}
+	return nil
+}
+
+// Reserve holds stock for a checkout. A user has one checkout at a time, so their earlier pending
+// reservations are released first; one whose payment cannot be cancelled, e.g. because it has just
+// gone through, keeps its stock until its webhook arrives. Returns an *OutOfStockError if a
+// product is short.
+func (s *service) Reserve(ctx context.Context, userID uuid.UUID, lines []Line) (*Reservation, error) {
+	pending, err := s.repo.ListPendingByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pending {
+		if err := s.release(ctx, &pending[i]); err != nil {
+			log.Printf("inventory: release reservation %s: %v", pending[i].ID, err)
+		}
+	}
+	now := time.Now()
+	res := &Reservation{
+		UserID:    userID,
+		Status:    StatusPending,
+		ExpiresAt: now.Add(s.ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, l := range lines {
+		res.Items = append(res.Items, ReservationItem{ProductID: l.ProductID, Quantity: l.Quantity})
+	}
+	if err := s.repo.Reserve(ctx, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AttachPaymentIntent links a reservation to its PaymentIntent so the payment webhooks can settle it.
+func (s *service) AttachPaymentIntent(ctx context.Context, reservationID uuid.UUID, piID string) error {
+	return s.repo.SetPaymentIntentID(ctx, reservationID, piID)
+}
+
+// Cancel releases a reservation whose PaymentIntent could not be created.
+func (s *service) Cancel(ctx context.Context, reservationID uuid.UUID) error {
+	return s.repo.Release(ctx, reservationID)
+}
+
+// Commit turns the PaymentIntent's reservation into a sale. PaymentIntents without a reservation,
+// e.g. from before stock tracking, are ignored.
+func (s *service) Commit(ctx context.Context, piID string) error {
+	res, err := s.repo.GetByPaymentIntentID(ctx, piID)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return nil
+	}
+	return s.repo.Commit(ctx, res.ID)
+}
+
+// Release cancels the PaymentIntent and returns its reserved units to available stock, e.g. after
+// the payment failed. PaymentIntents without a reservation, or whose reservation was already
+// settled, are ignored.
+func (s *service) Release(ctx context.Context, piID string) error {
+	res, err := s.repo.GetByPaymentIntentID(ctx, piID)
+	if err != nil {
+		return err
+	}
+	if res == nil || (res.Status != StatusPending && res.Status != StatusAwaitingPayment) {
+		return nil
+	}
+	return s.release(ctx, res)
+}
+
+// Run releases expired reservations every minute until ctx is done.
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.releaseExpired(ctx, time.Now())
+		}
+	}
+}
+
+// GetReservation returns the PaymentIntent's reservation with its items, or nil if it has none.
+func (s *service) GetReservation(ctx context.Context, piID string) (*Reservation, error) {
+	return s.repo.GetByPaymentIntentID(ctx, piID)
+}
+
+// GetStock returns the product's stock level, or ErrNotTracked.
+func (s *service) GetStock(ctx context.Context, productID int) (*StockLevel, error) {
+	lvl, err := s.repo.GetStock(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if lvl == nil {
+		return nil, ErrNotTracked
+	}
+	return lvl, nil
+}
+
+// ListStock returns a page of stock levels and the number of tracked products.
+func (s *service) ListStock(ctx context.Context, page, limit int) ([]StockLevel, int64, error) {
+	return s.repo.ListStock(ctx, page, limit)
+}
+
+// SetStock sets how many units of the product are on hand, e.g. after a stock count or delivery.
+func (s *service) SetStock(ctx context.Context, productID, onHand int) (*StockLevel, error) {
+	if onHand < 0 {
+		return nil, errors.New("inventory: on hand must not be negative")
+	}
+	return s.repo.SetOnHand(ctx, productID, onHand)
+}
+
+// StopTracking removes the product's stock level so it can no longer run out.
+func (s *service) StopTracking(ctx context.Context, productID int) error {
+	ok, err := s.repo.DeleteStock(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotTracked
+	}
+	return nil
+}
+
+// releaseExpired releases the reservations whose checkout timed out before now. A reservation
+// that fails to release is logged and retried with exponential backoff.
+func (s *service) releaseExpired(ctx context.Context, now time.Time) {
+	expired, err := s.repo.ListExpired(ctx, now)
+	if err != nil {
+		log.Printf("inventory: list expired reservations: %v", err)
+		return
+	}
+	for i := range expired {
+		res := &expired[i]
+		if err := s.release(ctx, res); err != nil {
+			log.Printf("inventory: release reservation %s: %v", res.ID, err)
+			if err := s.repo.PostponeRelease(ctx, res.ID, now.Add(releaseDelay(res.ReleaseAttempts))); err != nil {
+				log.Printf("inventory: postpone reservation %s: %v", res.ID, err)
+			}
+		}
+	}
+}
+
+// releaseDelay returns how long to wait before retrying a release that failed attempts times before.
+func releaseDelay(attempts int) time.Duration {
+	d := releaseBackoff
+	for i := 0; i < attempts && d < maxReleaseBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxReleaseBackoff)
+}
+
+// release cancels the reservation's PaymentIntent and returns its units to available stock. If the
+// PaymentIntent is being paid or has been paid, the reservation awaits the payment webhooks
+// instead, since the customer may be charged.
+func (s *service) release(ctx context.Context, res *Reservation) error {
+	if res.PaymentIntentID != "" && s.payments != nil {
+		err := s.payments.CancelPaymentIntent(ctx, res.PaymentIntentID)
+		if errors.Is(err, ErrNotCancellable) {
+			return s.repo.MarkAwaitingPayment(ctx, res.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return s.repo.Release(ctx, res.ID)
+}
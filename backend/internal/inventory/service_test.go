@@ -0,0 +1,235 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeRepo keeps reservations in memory and records how the service settles them. Stock levels are
+// not modelled; other Repository methods panic through the nil embedded interface.
+type fakeRepo struct {
+	Repository
+
+	mu           sync.Mutex
+	reservations map[uuid.UUID]*Reservation
+	listedExpiry bool
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{reservations: make(map[uuid.UUID]*Reservation)}
+}
+
+func (r *fakeRepo) add(piID string, status string, expiresAt time.Time) *Reservation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := &Reservation{ID: uuid.New(), UserID: uuid.New(), PaymentIntentID: piID, Status: status, ExpiresAt: expiresAt}
+	r.reservations[res.ID] = res
+	return res
+}
+
+func (r *fakeRepo) get(id uuid.UUID) Reservation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.reservations[id]
+}
+
+func (r *fakeRepo) GetStocks(ctx context.Context, productIDs []int) ([]StockLevel, error) {
+	return []StockLevel{{ProductID: 1, OnHand: 3, Reserved: 1}}, nil
+}
+
+func (r *fakeRepo) GetByPaymentIntentID(ctx context.Context, piID string) (*Reservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, res := range r.reservations {
+		if res.PaymentIntentID == piID {
+			c := *res
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) ListExpired(ctx context.Context, now time.Time) ([]Reservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listedExpiry = true
+	var out []Reservation
+	for _, res := range r.reservations {
+		if res.Status == StatusPending && res.ExpiresAt.Before(now) {
+			out = append(out, *res)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) PostponeRelease(ctx context.Context, id uuid.UUID, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if res := r.reservations[id]; res.Status == StatusPending {
+		res.ExpiresAt = until
+		res.ReleaseAttempts++
+	}
+	return nil
+}
+
+func (r *fakeRepo) MarkAwaitingPayment(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if res := r.reservations[id]; res.Status == StatusPending {
+		res.Status = StatusAwaitingPayment
+	}
+	return nil
+}
+
+func (r *fakeRepo) Release(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if res := r.reservations[id]; res.Status == StatusPending || res.Status == StatusAwaitingPayment {
+		res.Status = StatusReleased
+	}
+	return nil
+}
+
+// fakeCanceller cancels PaymentIntents, failing with the error registered for an ID.
+type fakeCanceller struct {
+	mu        sync.Mutex
+	errs      map[string]error
+	cancelled []string
+}
+
+func (c *fakeCanceller) CancelPaymentIntent(ctx context.Context, piID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.errs[piID]; err != nil {
+		return err
+	}
+	c.cancelled = append(c.cancelled, piID)
+	return nil
+}
+
+func TestReleaseExpired(t *testing.T) {
+	repo := newFakeRepo()
+	payments := &fakeCanceller{errs: map[string]error{
+		"pi_paid":  fmt.Errorf("cancel: %w", ErrNotCancellable),
+		"pi_flaky": errors.New("stripe unavailable"),
+	}}
+	svc := NewService(repo, time.Minute, payments).(*service)
+	now := time.Now()
+
+	expired := repo.add("pi_expired", StatusPending, now.Add(-time.Second))
+	live := repo.add("pi_live", StatusPending, now.Add(time.Minute))
+	paid := repo.add("pi_paid", StatusPending, now.Add(-time.Second))
+	flaky := repo.add("pi_flaky", StatusPending, now.Add(-time.Second))
+	svc.releaseExpired(context.Background(), now)
+
+	if got := repo.get(expired.ID).Status; got != StatusReleased {
+		t.Errorf("expired reservation status = %s, want %s", got, StatusReleased)
+	}
+	if got := repo.get(live.ID).Status; got != StatusPending {
+		t.Errorf("live reservation status = %s, want %s", got, StatusPending)
+	}
+	if got := repo.get(paid.ID).Status; got != StatusAwaitingPayment {
+		t.Errorf("uncancellable reservation status = %s, want %s", got, StatusAwaitingPayment)
+	}
+	got := repo.get(flaky.ID)
+	if got.Status != StatusPending || got.ReleaseAttempts != 1 || !got.ExpiresAt.Equal(now.Add(releaseBackoff)) {
+		t.Errorf("failed release: status %s, %d attempts, expires %s; want pending, 1 attempt, expires %s",
+			got.Status, got.ReleaseAttempts, got.ExpiresAt, now.Add(releaseBackoff))
+	}
+	if len(payments.cancelled) != 1 || payments.cancelled[0] != "pi_expired" {
+		t.Errorf("cancelled %v, want [pi_expired]", payments.cancelled)
+	}
+
+	// The uncancellable reservation is no longer swept; the failed one waits for its backoff.
+	payments.cancelled = nil
+	svc.releaseExpired(context.Background(), now.Add(releaseBackoff/2))
+	if len(payments.cancelled) != 0 {
+		t.Errorf("second sweep cancelled %v, want nothing", payments.cancelled)
+	}
+	delete(payments.errs, "pi_flaky")
+	svc.releaseExpired(context.Background(), now.Add(releaseBackoff+time.Second))
+	if got := repo.get(flaky.ID).Status; got != StatusReleased {
+		t.Errorf("retried reservation status = %s, want %s", got, StatusReleased)
+	}
+}
+
+func TestReleaseDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{3, 8 * time.Minute},
+		{6, time.Hour},
+		{1000, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := releaseDelay(tt.attempts); got != tt.want {
+			t.Errorf("releaseDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestReleaseByPaymentIntent(t *testing.T) {
+	repo := newFakeRepo()
+	payments := &fakeCanceller{}
+	svc := NewService(repo, time.Minute, payments)
+	ctx := context.Background()
+	later := time.Now().Add(time.Hour)
+
+	pending := repo.add("pi_pending", StatusPending, later)
+	awaiting := repo.add("pi_awaiting", StatusAwaitingPayment, later)
+	committed := repo.add("pi_committed", StatusCommitted, later)
+	for _, piID := range []string{"pi_pending", "pi_awaiting", "pi_committed", "pi_unknown"} {
+		if err := svc.Release(ctx, piID); err != nil {
+			t.Fatalf("Release(%s): %v", piID, err)
+		}
+	}
+	if got := repo.get(pending.ID).Status; got != StatusReleased {
+		t.Errorf("pending reservation status = %s, want %s", got, StatusReleased)
+	}
+	if got := repo.get(awaiting.ID).Status; got != StatusReleased {
+		t.Errorf("awaiting reservation status = %s, want %s", got, StatusReleased)
+	}
+	if got := repo.get(committed.ID).Status; got != StatusCommitted {
+		t.Errorf("committed reservation status = %s, want %s", got, StatusCommitted)
+	}
+	if len(payments.cancelled) != 2 {
+		t.Errorf("cancelled %v, want the pending and awaiting PaymentIntents", payments.cancelled)
+	}
+
+	// Releasing again, e.g. for a retried webhook, does not call Stripe.
+	if err := svc.Release(ctx, "pi_pending"); err != nil {
+		t.Fatal(err)
+	}
+	if len(payments.cancelled) != 2 {
+		t.Errorf("second release cancelled again: %v", payments.cancelled)
+	}
+}
+
+func TestCheckAvailableDoesNotSweep(t *testing.T) {
+	repo := newFakeRepo()
+	svc := NewService(repo, time.Minute, &fakeCanceller{})
+
+	err := svc.CheckAvailable(context.Background(), []Line{{ProductID: 1, Quantity: 3}, {ProductID: 2, Quantity: 100}})
+	var short *OutOfStockError
+	if !errors.As(err, &short) || !errors.Is(err, ErrOutOfStock) {
+		t.Fatalf("err = %v, want *OutOfStockError", err)
+	}
+	if short.ProductID != 1 || short.Available != 2 || short.Requested != 3 {
+		t.Errorf("unexpected shortage: %+v", short)
+	}
+	if err := svc.CheckAvailable(context.Background(), []Line{{ProductID: 1, Quantity: 2}}); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+	if repo.listedExpiry {
+		t.Error("CheckAvailable swept expired reservations on the request path")
+	}
+}
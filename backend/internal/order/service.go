@@ -3,10 +3,12 @@ package order
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"time"
 
 	"github.com/Rakesh2908/shopgo/internal/cart"
+	"github.com/Rakesh2908/shopgo/internal/inventory"
 	"github.com/Rakesh2908/shopgo/internal/product"
 	"github.com/google/uuid"
 )
@@ -21,18 +23,21 @@ type OrderService interface {
 
 // service implements OrderService.
 type service struct {
-	repo    Repository
-	cart    cart.Service
-	product product.ProductService
+	repo      Repository
+	cart      cart.Service
+	product   product.ProductService
+	inventory inventory.Service
 }
 
 // NewService returns a new OrderService.
-func NewService(repo Repository, cartSvc cart.Service, productSvc product.ProductService) OrderService {
-	return &service{repo: repo, cart: cartSvc, product: productSvc}
+func NewService(repo Repository, cartSvc cart.Service, productSvc product.ProductService, inv inventory.Service) OrderService {
+	return &service{repo: repo, cart: cartSvc, product: productSvc, inventory: inv}
 }
 
-// CreateFromPaymentIntent creates an order from the user's current cart.
-// This is intended to be called after Stripe confirms the PaymentIntent succeeded.
+// CreateFromPaymentIntent creates an order from the items reserved for the PaymentIntent, which are
+// what the customer paid for, and commits that stock. PaymentIntents without a reservation, from
+// before stock tracking, fall back to the user's current cart. This is intended to be called after
+// Stripe confirms the PaymentIntent succeeded.
 func (s *service) CreateFromPaymentIntent(ctx context.Context, piID string, userID uuid.UUID) error {
 	if userID == uuid.Nil {
 		return errors.New("order: missing user id")
@@ -50,12 +55,17 @@ func (s *service) CreateFromPaymentIntent(ctx context.Context, piID string, user
 		return nil
 	}
 
-	cartItems, err := s.cart.GetCart(ctx, userID)
+	// Committing is idempotent, so a retried webhook after a failed order insert is safe.
+	if err := s.inventory.Commit(ctx, piID); err != nil {
+		return err
+	}
+
+	lines, err := s.paidLines(ctx, piID, userID)
 	if err != nil {
 		return err
 	}
-	if len(cartItems) == 0 {
-		// If the cart is empty, avoid creating a meaningless order. Treat as idempotent/no-op.
+	if len(lines) == 0 {
+		// Nothing was bought, so avoid creating a meaningless order. Treat as idempotent/no-op.
 		return nil
 	}
 
@@ -68,19 +78,28 @@ func (s *service) CreateFromPaymentIntent(ctx context.Context, piID string, user
 		CreatedAt:  now,
 	}
 
+	ids := make([]int, len(lines))
+	for i, l := range lines {
+		ids[i] = l.ProductID
+	}
+	products, err := s.product.GetByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("order: load products: %w", err)
+	}
+
 	var totalCents int
-	items := make([]OrderItem, 0, len(cartItems))
-	for _, ci := range cartItems {
+	items := make([]OrderItem, 0, len(lines))
+	for _, l := range lines {
 		// Snapshot the product details at purchase time.
-		p, err := s.product.GetByID(ctx, ci.ProductID)
-		if err != nil || p == nil {
-			return errors.New("order: failed to load product for cart item")
+		p, ok := products[l.ProductID]
+		if !ok {
+			return errors.New("order: failed to load product for order item")
 		}
 		priceCents := int(math.Round(p.Price * 100))
 		if priceCents < 0 {
 			priceCents = 0
 		}
-		lineTotal := priceCents * ci.Quantity
+		lineTotal := priceCents * l.Quantity
 		if lineTotal < 0 {
 			lineTotal = 0
 		}
@@ -89,7 +108,7 @@ func (s *service) CreateFromPaymentIntent(ctx context.Context, piID string, user
 			ProductID:  p.ID,
 			Title:      p.Title,
 			PriceCents: priceCents,
-			Quantity:   ci.Quantity,
+			Quantity:   l.Quantity,
 			ImageURL:   p.Image,
 		})
 	}
@@ -124,12 +143,41 @@ func (s *service) CreateFromPaymentIntent(ctx context.Context, piID string, user
 	return nil
 }
 
-// MarkFailed marks an order associated with the given PaymentIntent as failed.
-// If no order exists yet, it returns nil (idempotent for webhook retries/out-of-order events).
+// paidLines returns the products and quantities paid for with the PaymentIntent: its reservation's
+// items, or the user's cart if it has no reservation.
+func (s *service) paidLines(ctx context.Context, piID string, userID uuid.UUID) ([]inventory.Line, error) {
+	reservation, err := s.inventory.GetReservation(ctx, piID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation != nil {
+		lines := make([]inventory.Line, 0, len(reservation.Items))
+		for _, it := range reservation.Items {
+			lines = append(lines, inventory.Line{ProductID: it.ProductID, Quantity: it.Quantity})
+		}
+		return lines, nil
+	}
+	cartItems, err := s.cart.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]inventory.Line, 0, len(cartItems))
+	for _, ci := range cartItems {
+		lines = append(lines, inventory.Line{ProductID: ci.ProductID, Quantity: ci.Quantity})
+	}
+	return lines, nil
+}
+
+// MarkFailed cancels the PaymentIntent, releases the stock reserved for it and marks the associated
+// order as failed. If no order exists yet, it returns nil (idempotent for webhook
+// retries/out-of-order events).
 func (s *service) MarkFailed(ctx context.Context, piID string) error {
 	if piID == "" {
 		return nil
 	}
+	if err := s.inventory.Release(ctx, piID); err != nil {
+		return err
+	}
 	existing, err := s.repo.GetByStripePIID(ctx, piID)
 	if err != nil {
 		return err
@@ -1,6 +1,7 @@
 package payment
 
 import (
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/Rakesh2908/shopgo/internal/auth"
 	"github.com/Rakesh2908/shopgo/internal/cart"
+	"github.com/Rakesh2908/shopgo/internal/inventory"
 	"github.com/Rakesh2908/shopgo/pkg/response"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -15,13 +17,14 @@ import (
 
 // RegisterRoutes registers payment routes on the given router group.
 // Expects the group to be mounted at / (e.g. api group), and registers:
-// - POST /checkout/intent (protected)
+// - POST /checkout/intent (protected; checkoutGuards run after authMiddleware)
 // - POST /webhooks/stripe (public)
-func RegisterRoutes(rg *gin.RouterGroup, svc PaymentService, cartSvc cart.Service, authMiddleware gin.HandlerFunc) {
+func RegisterRoutes(rg *gin.RouterGroup, svc PaymentService, cartSvc cart.Service, authMiddleware gin.HandlerFunc, checkoutGuards ...gin.HandlerFunc) {
 	rg.POST("/webhooks/stripe", handleStripeWebhook(svc))
 
 	protected := rg.Group("")
 	protected.Use(authMiddleware)
+	protected.Use(checkoutGuards...)
 	protected.POST("/checkout/intent", handleCreateIntent(svc, cartSvc))
 }
 
@@ -39,17 +42,23 @@ func handleCreateIntent(svc PaymentService, cartSvc cart.Service) gin.HandlerFun
 			return
 		}
 		var amountCents int64
+		lines := make([]inventory.Line, 0, len(items))
 		for _, it := range items {
 			// Price is float64 dollars; subtotal already includes quantity.
 			amountCents += int64(it.Subtotal * 100)
+			lines = append(lines, inventory.Line{ProductID: it.ProductID, Quantity: it.Quantity})
 		}
 		if amountCents <= 0 {
 			response.Error(c, http.StatusBadRequest, "EMPTY_CART", "cart is empty")
 			return
 		}
 		currency := "usd"
-		clientSecret, _, err := svc.CreateIntent(c.Request.Context(), userID, amountCents, currency)
+		clientSecret, _, err := svc.CreateIntent(c.Request.Context(), userID, amountCents, currency, lines)
 		if err != nil {
+			if errors.Is(err, inventory.ErrOutOfStock) {
+				inventory.WriteOutOfStock(c, err)
+				return
+			}
 			if strings.Contains(err.Error(), "amount") {
 				response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid amount")
 				return
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 
+	"github.com/Rakesh2908/shopgo/internal/inventory"
 	"github.com/Rakesh2908/shopgo/internal/order"
 	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v80"
@@ -15,7 +17,7 @@ import (
 
 // PaymentService defines payment operations such as Stripe PaymentIntent creation and webhooks.
 type PaymentService interface {
-	CreateIntent(ctx context.Context, userID uuid.UUID, amountCents int64, currency string) (clientSecret string, piID string, err error)
+	CreateIntent(ctx context.Context, userID uuid.UUID, amountCents int64, currency string, lines []inventory.Line) (clientSecret string, piID string, err error)
 	HandleWebhook(payload []byte, sigHeader string) error
 }
 
@@ -23,19 +25,26 @@ type PaymentService interface {
 type paymentService struct {
 	webhookSecret string
 	orderSvc      order.OrderService
+	inventory     inventory.Service
+	payments      inventory.PaymentCanceller
 }
 
-// NewPaymentService returns a new PaymentService.
-func NewPaymentService(stripeSecretKey string, webhookSecret string, orderSvc order.OrderService) PaymentService {
+// NewPaymentService returns a new PaymentService. payments cancels PaymentIntents whose checkout
+// cannot go ahead, normally IntentCanceller.
+func NewPaymentService(stripeSecretKey string, webhookSecret string, orderSvc order.OrderService, inv inventory.Service, payments inventory.PaymentCanceller) PaymentService {
 	stripe.Key = stripeSecretKey
 	return &paymentService{
 		webhookSecret: webhookSecret,
 		orderSvc:      orderSvc,
+		inventory:     inv,
+		payments:      payments,
 	}
 }
 
-// CreateIntent creates a Stripe PaymentIntent for the given amount and returns the client secret and intent ID.
-func (s *paymentService) CreateIntent(ctx context.Context, userID uuid.UUID, amountCents int64, currency string) (string, string, error) {
+// CreateIntent reserves stock for lines, creates a Stripe PaymentIntent for the given amount and
+// returns the client secret and intent ID. Returns an error matching inventory.ErrOutOfStock if a
+// product is short; the reservation is dropped if the PaymentIntent cannot be created.
+func (s *paymentService) CreateIntent(ctx context.Context, userID uuid.UUID, amountCents int64, currency string, lines []inventory.Line) (string, string, error) {
 	if userID == uuid.Nil {
 		return "", "", errors.New("payment: missing user id")
 	}
@@ -45,23 +54,72 @@ func (s *paymentService) CreateIntent(ctx context.Context, userID uuid.UUID, amo
 	if currency == "" {
 		currency = "usd"
 	}
+	reservation, err := s.inventory.Reserve(ctx, userID, lines)
+	if err != nil {
+		return "", "", err
+	}
 	params := &stripe.PaymentIntentParams{
 		Amount:   stripe.Int64(amountCents),
 		Currency: stripe.String(currency),
 		Metadata: map[string]string{
-			"userID": userID.String(),
+			"userID":        userID.String(),
+			"reservationID": reservation.ID.String(),
 		},
 	}
 	pi, err := paymentintent.New(params)
 	if err != nil {
+		s.cancelReservation(ctx, reservation.ID)
 		return "", "", fmt.Errorf("payment: create payment intent: %w", err)
 	}
 	if pi == nil || pi.ClientSecret == "" {
+		s.cancelReservation(ctx, reservation.ID)
 		return "", "", errors.New("payment: missing client secret")
 	}
+	if err := s.inventory.AttachPaymentIntent(ctx, reservation.ID, pi.ID); err != nil {
+		// Without the link the payment could never be settled against the reservation.
+		if cerr := s.payments.CancelPaymentIntent(ctx, pi.ID); cerr != nil {
+			log.Printf("payment: %v", cerr)
+		}
+		s.cancelReservation(ctx, reservation.ID)
+		return "", "", err
+	}
 	return pi.ClientSecret, pi.ID, nil
 }
 
+// cancelReservation releases a reservation whose checkout could not be started. Failures are only
+// logged; the reservation then times out on its own.
+func (s *paymentService) cancelReservation(ctx context.Context, id uuid.UUID) {
+	if err := s.inventory.Cancel(ctx, id); err != nil {
+		log.Printf("payment: release reservation %s: %v", id, err)
+	}
+}
+
+// IntentCanceller cancels Stripe PaymentIntents. It implements inventory.PaymentCanceller.
+type IntentCanceller struct{}
+
+// CancelPaymentIntent cancels the PaymentIntent so it can no longer be paid. A PaymentIntent that is
+// already cancelled counts as cancelled; one that succeeded or is being paid cannot be, and an
+// error matching inventory.ErrNotCancellable is returned.
+func (IntentCanceller) CancelPaymentIntent(ctx context.Context, piID string) error {
+	params := &stripe.PaymentIntentCancelParams{}
+	params.Context = ctx
+	_, err := paymentintent.Cancel(piID, params)
+	if err == nil {
+		return nil
+	}
+	getParams := &stripe.PaymentIntentParams{}
+	getParams.Context = ctx
+	if pi, gerr := paymentintent.Get(piID, getParams); gerr == nil {
+		switch pi.Status {
+		case stripe.PaymentIntentStatusCanceled:
+			return nil
+		case stripe.PaymentIntentStatusSucceeded, stripe.PaymentIntentStatusProcessing:
+			return fmt.Errorf("payment: cancel payment intent %s: %w", piID, inventory.ErrNotCancellable)
+		}
+	}
+	return fmt.Errorf("payment: cancel payment intent %s: %w", piID, err)
+}
+
 // HandleWebhook verifies and handles Stripe webhook events.
 func (s *paymentService) HandleWebhook(payload []byte, sigHeader string) error {
 	if s.webhookSecret == "" {
@@ -91,6 +149,7 @@ func (s *paymentService) HandleWebhook(payload []byte, sigHeader string) error {
 		return s.orderSvc.CreateFromPaymentIntent(context.Background(), pi.ID, userID)
 
 	case "payment_intent.payment_failed":
+		// Cancels the PaymentIntent and puts its reserved stock back on sale.
 		var pi stripe.PaymentIntent
 		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
 			return fmt.Errorf("payment: unmarshal payment intent: %w", err)
@@ -0,0 +1,319 @@
+package product
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const defaultAdminLimit = 20
+
+// CreateProductRequest is the request body for POST /admin/products.
+type CreateProductRequest struct {
+	Title       string  `json:"title" binding:"required,max=200"`
+	Description string  `json:"description" binding:"max=5000"`
+	Category    string  `json:"category" binding:"required"`
+	Image       string  `json:"image" binding:"omitempty,url"`
+	Price       float64 `json:"price" binding:"required,gt=0"`
+}
+
+// UpdateProductRequest is the request body for PATCH /admin/products/:id. Omitted fields are left unchanged.
+type UpdateProductRequest struct {
+	Title       *string `json:"title" binding:"omitempty,min=1,max=200"`
+	Description *string `json:"description" binding:"omitempty,max=5000"`
+	Category    *string `json:"category" binding:"omitempty,min=1"`
+	Image       *string `json:"image" binding:"omitempty,url"`
+}
+
+// SetPriceRequest is the request body for PUT /admin/products/:id/price.
+type SetPriceRequest struct {
+	Price float64 `json:"price" binding:"required,gt=0"`
+}
+
+// CategoryRequest is the request body for POST /admin/categories and PATCH /admin/categories/:id.
+type CategoryRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// RegisterAdminRoutes registers back-office catalog routes on the given router group, which must
+// already require a staff or admin access token. Group path should be "/admin" so routes are
+// GET/POST /admin/products, GET/PATCH /admin/products/:id, PUT /admin/products/:id/price,
+// POST/DELETE /admin/products/:id/archive, GET/POST /admin/categories and PATCH/DELETE /admin/categories/:id.
+func RegisterAdminRoutes(rg *gin.RouterGroup, svc AdminService) {
+	products := rg.Group("/products")
+	products.GET("", handleAdminListProducts(svc))
+	products.POST("", handleCreateProduct(svc))
+	products.GET("/:id", handleAdminGetProduct(svc))
+	products.PATCH("/:id", handleUpdateProduct(svc))
+	products.PUT("/:id/price", handleSetPrice(svc))
+	products.POST("/:id/archive", handleArchiveProduct(svc))
+	products.DELETE("/:id/archive", handleRestoreProduct(svc))
+
+	categories := rg.Group("/categories")
+	categories.GET("", handleAdminListCategories(svc))
+	categories.POST("", handleCreateCategory(svc))
+	categories.PATCH("/:id", handleRenameCategory(svc))
+	categories.DELETE("/:id", handleDeleteCategory(svc))
+}
+
+// handleAdminListProducts handles GET /admin/products?page=&limit=&archived=true
+func handleAdminListProducts(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit := adminPageParams(c)
+		includeArchived := c.Query("archived") == "true"
+		products, total, err := svc.ListProducts(c.Request.Context(), page, limit, includeArchived)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list products")
+			return
+		}
+		response.SuccessWithMeta(c, http.StatusOK, products, gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// handleAdminGetProduct handles GET /admin/products/:id, including archived products.
+func handleAdminGetProduct(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := productIDParam(c)
+		if !ok {
+			return
+		}
+		p, err := svc.GetProduct(c.Request.Context(), id)
+		if err != nil {
+			writeAdminError(c, err, "failed to get product")
+			return
+		}
+		response.Success(c, http.StatusOK, p)
+	}
+}
+
+// handleCreateProduct handles POST /admin/products.
+func handleCreateProduct(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateProductRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		p, err := svc.CreateProduct(c.Request.Context(), ProductInput{
+			Title:       req.Title,
+			Description: req.Description,
+			Category:    req.Category,
+			Image:       req.Image,
+			Price:       req.Price,
+		})
+		if err != nil {
+			writeAdminError(c, err, "failed to create product")
+			return
+		}
+		response.Success(c, http.StatusCreated, p)
+	}
+}
+
+// handleUpdateProduct handles PATCH /admin/products/:id.
+func handleUpdateProduct(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := productIDParam(c)
+		if !ok {
+			return
+		}
+		var req UpdateProductRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		p, err := svc.UpdateProduct(c.Request.Context(), id, ProductUpdate{
+			Title:       req.Title,
+			Description: req.Description,
+			Category:    req.Category,
+			Image:       req.Image,
+		})
+		if err != nil {
+			writeAdminError(c, err, "failed to update product")
+			return
+		}
+		response.Success(c, http.StatusOK, p)
+	}
+}
+
+// handleSetPrice handles PUT /admin/products/:id/price.
+func handleSetPrice(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := productIDParam(c)
+		if !ok {
+			return
+		}
+		var req SetPriceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		p, err := svc.SetPrice(c.Request.Context(), id, req.Price)
+		if err != nil {
+			writeAdminError(c, err, "failed to update price")
+			return
+		}
+		response.Success(c, http.StatusOK, p)
+	}
+}
+
+// handleArchiveProduct handles POST /admin/products/:id/archive — takes the product off sale.
+func handleArchiveProduct(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := productIDParam(c)
+		if !ok {
+			return
+		}
+		p, err := svc.ArchiveProduct(c.Request.Context(), id)
+		if err != nil {
+			writeAdminError(c, err, "failed to archive product")
+			return
+		}
+		response.Success(c, http.StatusOK, p)
+	}
+}
+
+// handleRestoreProduct handles DELETE /admin/products/:id/archive — puts the product back on sale.
+func handleRestoreProduct(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := productIDParam(c)
+		if !ok {
+			return
+		}
+		p, err := svc.RestoreProduct(c.Request.Context(), id)
+		if err != nil {
+			writeAdminError(c, err, "failed to restore product")
+			return
+		}
+		response.Success(c, http.StatusOK, p)
+	}
+}
+
+// handleAdminListCategories handles GET /admin/categories, including categories without products.
+func handleAdminListCategories(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categories, err := svc.ListCategories(c.Request.Context())
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list categories")
+			return
+		}
+		response.Success(c, http.StatusOK, categories)
+	}
+}
+
+// handleCreateCategory handles POST /admin/categories.
+func handleCreateCategory(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CategoryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		category, err := svc.CreateCategory(c.Request.Context(), req.Name)
+		if err != nil {
+			writeAdminError(c, err, "failed to create category")
+			return
+		}
+		response.Success(c, http.StatusCreated, category)
+	}
+}
+
+// handleRenameCategory handles PATCH /admin/categories/:id.
+func handleRenameCategory(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil || id < 1 {
+			response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid category id")
+			return
+		}
+		var req CategoryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", validationMessage(err))
+			return
+		}
+		category, err := svc.RenameCategory(c.Request.Context(), id, req.Name)
+		if err != nil {
+			writeAdminError(c, err, "failed to rename category")
+			return
+		}
+		response.Success(c, http.StatusOK, category)
+	}
+}
+
+// handleDeleteCategory handles DELETE /admin/categories/:id. Only empty categories can be deleted.
+func handleDeleteCategory(svc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil || id < 1 {
+			response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid category id")
+			return
+		}
+		if err := svc.DeleteCategory(c.Request.Context(), id); err != nil {
+			writeAdminError(c, err, "failed to delete category")
+			return
+		}
+		response.Success(c, http.StatusOK, gin.H{"deleted": true})
+	}
+}
+
+// adminPageParams reads the page and limit query parameters of a back-office listing, falling
+// back to the defaults when they are missing or out of range.
+func adminPageParams(c *gin.Context) (page, limit int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", strconv.Itoa(defaultPage)))
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAdminLimit)))
+	if page < 1 {
+		page = defaultPage
+	}
+	if limit < 1 || limit > 100 {
+		limit = defaultAdminLimit
+	}
+	return page, limit
+}
+
+// productIDParam parses the :id path parameter, writing a 400 response if it is invalid.
+func productIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid product id")
+		return 0, false
+	}
+	return id, true
+}
+
+// writeAdminError maps catalog service errors to responses, using msg for unexpected errors.
+func writeAdminError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		response.Error(c, http.StatusNotFound, "NOT_FOUND", "product not found")
+	case errors.Is(err, ErrBlankTitle):
+		response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", "title required")
+	case errors.Is(err, ErrCategoryNotFound):
+		response.Error(c, http.StatusBadRequest, "CATEGORY_NOT_FOUND", "category not found")
+	case errors.Is(err, ErrCategoryExists):
+		response.Error(c, http.StatusConflict, "CATEGORY_EXISTS", "category already exists")
+	case errors.Is(err, ErrCategoryNotEmpty):
+		response.Error(c, http.StatusConflict, "CATEGORY_NOT_EMPTY", "category still has products")
+	default:
+		response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", msg)
+	}
+}
+
+// validationMessage returns a short message from a validator error.
+func validationMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	if ve, ok := err.(validator.ValidationErrors); ok && len(ve) > 0 {
+		f := ve[0]
+		return strings.ToLower(f.Field()) + " " + f.Tag()
+	}
+	return err.Error()
+}
@@ -0,0 +1,338 @@
+package product
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/pkg/cache"
+)
+
+// AdminProduct is a stored product as shown to the back office: the public Product plus its
+// archived state and timestamps.
+type AdminProduct struct {
+	Product
+	Archived   bool       `json:"archived"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// ProductInput holds the fields of a new product. Price is in dollars, like Product.Price.
+type ProductInput struct {
+	Title       string
+	Description string
+	Category    string
+	Image       string
+	Price       float64
+}
+
+// ProductUpdate holds the descriptive fields to change on a product; nil fields are left alone.
+// Prices are changed with SetPrice.
+type ProductUpdate struct {
+	Title       *string
+	Description *string
+	Category    *string
+	Image       *string
+}
+
+// AdminService defines the back-office operations on the stored catalog. Changes are visible on
+// the public routes straight away when the catalog source is "database".
+type AdminService interface {
+	ListProducts(ctx context.Context, page, limit int, includeArchived bool) ([]AdminProduct, int64, error)
+	GetProduct(ctx context.Context, id int) (*AdminProduct, error)
+	CreateProduct(ctx context.Context, in ProductInput) (*AdminProduct, error)
+	UpdateProduct(ctx context.Context, id int, update ProductUpdate) (*AdminProduct, error)
+	SetPrice(ctx context.Context, id int, price float64) (*AdminProduct, error)
+	ArchiveProduct(ctx context.Context, id int) (*AdminProduct, error)
+	RestoreProduct(ctx context.Context, id int) (*AdminProduct, error)
+	ListCategories(ctx context.Context) ([]Category, error)
+	CreateCategory(ctx context.Context, name string) (*Category, error)
+	RenameCategory(ctx context.Context, id int, name string) (*Category, error)
+	DeleteCategory(ctx context.Context, id int) error
+}
+
+// adminService implements AdminService with a repository. It clears the cached catalog entries a
+// change affects so the public routes do not serve stale data.
+type adminService struct {
+	repo  Repository
+	cache *cache.MemoryCache
+}
+
+// NewAdminService returns a new AdminService. c must be the cache used by the ProductService.
+func NewAdminService(repo Repository, c *cache.MemoryCache) AdminService {
+	return &adminService{repo: repo, cache: c}
+}
+
+// ListProducts returns a page of stored products ordered by ID and the total count.
+func (s *adminService) ListProducts(ctx context.Context, page, limit int, includeArchived bool) ([]AdminProduct, int64, error) {
+	records, total, err := s.repo.List(ctx, page, limit, includeArchived)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]AdminProduct, len(records))
+	for i := range records {
+		out[i] = toAdminProduct(&records[i])
+	}
+	return out, total, nil
+}
+
+// GetProduct returns a stored product, archived or not, or ErrNotFound.
+func (s *adminService) GetProduct(ctx context.Context, id int) (*AdminProduct, error) {
+	r, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrNotFound
+	}
+	p := toAdminProduct(r)
+	return &p, nil
+}
+
+// CreateProduct adds a product to the catalog. The title must not be blank and the category must
+// exist.
+func (s *adminService) CreateProduct(ctx context.Context, in ProductInput) (*AdminProduct, error) {
+	title := strings.TrimSpace(in.Title)
+	if title == "" {
+		return nil, ErrBlankTitle
+	}
+	if err := s.requireCategory(ctx, in.Category); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	r := &ProductRecord{
+		Title:       title,
+		Description: in.Description,
+		Category:    in.Category,
+		Image:       in.Image,
+		PriceCents:  priceToCents(in.Price),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.repo.Create(ctx, r); err != nil {
+		return nil, err
+	}
+	s.invalidate(r.ID, r.Category)
+	p := toAdminProduct(r)
+	return &p, nil
+}
+
+// UpdateProduct changes a product's descriptive fields. A new title must not be blank and a new
+// category must exist.
+func (s *adminService) UpdateProduct(ctx context.Context, id int, update ProductUpdate) (*AdminProduct, error) {
+	var title string
+	if update.Title != nil {
+		if title = strings.TrimSpace(*update.Title); title == "" {
+			return nil, ErrBlankTitle
+		}
+	}
+	r, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrNotFound
+	}
+	oldCategory := r.Category
+	if update.Category != nil && *update.Category != r.Category {
+		if err := s.requireCategory(ctx, *update.Category); err != nil {
+			return nil, err
+		}
+		r.Category = *update.Category
+	}
+	if update.Title != nil {
+		r.Title = title
+	}
+	if update.Description != nil {
+		r.Description = *update.Description
+	}
+	if update.Image != nil {
+		r.Image = *update.Image
+	}
+	r.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, r); err != nil {
+		return nil, err
+	}
+	s.invalidate(r.ID, oldCategory, r.Category)
+	p := toAdminProduct(r)
+	return &p, nil
+}
+
+// SetPrice re-prices a product. Carts pick up the new price; placed orders keep theirs.
+func (s *adminService) SetPrice(ctx context.Context, id int, price float64) (*AdminProduct, error) {
+	ok, err := s.repo.UpdatePrice(ctx, id, priceToCents(price))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.reload(ctx, id)
+}
+
+// ArchiveProduct takes a product off sale without deleting it, so past orders still make sense.
+func (s *adminService) ArchiveProduct(ctx context.Context, id int) (*AdminProduct, error) {
+	now := time.Now()
+	ok, err := s.repo.SetArchivedAt(ctx, id, &now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.reload(ctx, id)
+}
+
+// RestoreProduct puts an archived product back on sale.
+func (s *adminService) RestoreProduct(ctx context.Context, id int) (*AdminProduct, error) {
+	ok, err := s.repo.SetArchivedAt(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.reload(ctx, id)
+}
+
+// ListCategories returns every category, including ones without products.
+func (s *adminService) ListCategories(ctx context.Context) ([]Category, error) {
+	return s.repo.ListCategories(ctx)
+}
+
+// CreateCategory adds a category. Names are unique.
+func (s *adminService) CreateCategory(ctx context.Context, name string) (*Category, error) {
+	name = strings.TrimSpace(name)
+	existing, err := s.repo.GetCategoryByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCategoryExists
+	}
+	c := &Category{Name: name, CreatedAt: time.Now()}
+	if err := s.repo.CreateCategory(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RenameCategory renames a category; its products move along with it.
+func (s *adminService) RenameCategory(ctx context.Context, id int, name string) (*Category, error) {
+	name = strings.TrimSpace(name)
+	c, err := s.repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrCategoryNotFound
+	}
+	if c.Name == name {
+		return c, nil
+	}
+	existing, err := s.repo.GetCategoryByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCategoryExists
+	}
+	ok, err := s.repo.RenameCategory(ctx, id, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	// Every product in the category changed, so drop the whole catalog cache.
+	invalidateCache(s.cache, cacheKeyAllProducts, cacheKeyCategories, cacheKeySearchIndex,
+		categoryCacheKey(c.Name), categoryCacheKey(name))
+	s.clearProductEntries(ctx, name)
+	c.Name = name
+	return c, nil
+}
+
+// DeleteCategory removes a category that no product, archived or not, belongs to.
+func (s *adminService) DeleteCategory(ctx context.Context, id int) error {
+	c, err := s.repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return ErrCategoryNotFound
+	}
+	n, err := s.repo.CountByCategory(ctx, c.Name)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrCategoryNotEmpty
+	}
+	ok, err := s.repo.DeleteCategory(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCategoryNotFound
+	}
+	invalidateCache(s.cache, cacheKeyCategories)
+	return nil
+}
+
+// requireCategory returns ErrCategoryNotFound unless a category with the given name exists.
+func (s *adminService) requireCategory(ctx context.Context, name string) error {
+	c, err := s.repo.GetCategoryByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// reload fetches a product after a change and clears its cache entries.
+func (s *adminService) reload(ctx context.Context, id int) (*AdminProduct, error) {
+	r, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrNotFound
+	}
+	s.invalidate(r.ID, r.Category)
+	p := toAdminProduct(r)
+	return &p, nil
+}
+
+// invalidate clears the cached entries that include the product or the given categories.
+func (s *adminService) invalidate(id int, categories ...string) {
+	invalidateCache(s.cache, cacheKeyAllProducts, cacheKeyCategories, cacheKeySearchIndex, productCacheKey(id))
+	for _, c := range categories {
+		invalidateCache(s.cache, categoryCacheKey(c))
+	}
+}
+
+// clearProductEntries clears the single-product cache entries of every product in category.
+func (s *adminService) clearProductEntries(ctx context.Context, category string) {
+	records, err := s.repo.ListActiveByCategory(ctx, category)
+	if err != nil {
+		// The entries expire on their own within cacheTTLProducts.
+		return
+	}
+	for _, r := range records {
+		invalidateCache(s.cache, productCacheKey(r.ID))
+	}
+}
+
+// toAdminProduct converts a stored record to its back-office view.
+func toAdminProduct(r *ProductRecord) AdminProduct {
+	return AdminProduct{
+		Product:    r.Product(),
+		Archived:   r.ArchivedAt != nil,
+		ArchivedAt: r.ArchivedAt,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
@@ -0,0 +1,364 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/pkg/cache"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeCatalogRepository adds the categories and the admin product writes to fakeRepository.
+type fakeCatalogRepository struct {
+	*fakeRepository
+
+	categories map[int]Category
+	nextID     int
+}
+
+func newFakeCatalogRepository(categories []string, records ...ProductRecord) *fakeCatalogRepository {
+	r := &fakeCatalogRepository{fakeRepository: newFakeRepository(records...), categories: make(map[int]Category), nextID: 100}
+	for i, name := range categories {
+		r.categories[i+1] = Category{ID: i + 1, Name: name}
+	}
+	return r
+}
+
+func (r *fakeCatalogRepository) GetByID(ctx context.Context, id int) (*ProductRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.records[id]
+	if !ok {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+func (r *fakeCatalogRepository) Create(ctx context.Context, p *ProductRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	p.ID = r.nextID
+	r.records[p.ID] = *p
+	return nil
+}
+
+func (r *fakeCatalogRepository) Update(ctx context.Context, p *ProductRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[p.ID] = *p
+	return nil
+}
+
+func (r *fakeCatalogRepository) UpdatePrice(ctx context.Context, id, priceCents int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.records[id]
+	if !ok {
+		return false, nil
+	}
+	rec.PriceCents = priceCents
+	r.records[id] = rec
+	return true, nil
+}
+
+func (r *fakeCatalogRepository) SetArchivedAt(ctx context.Context, id int, archivedAt *time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.records[id]
+	if !ok {
+		return false, nil
+	}
+	rec.ArchivedAt = archivedAt
+	r.records[id] = rec
+	return true, nil
+}
+
+func (r *fakeCatalogRepository) ListActiveByCategory(ctx context.Context, category string) ([]ProductRecord, error) {
+	all, _ := r.ListAll(ctx)
+	var out []ProductRecord
+	for _, rec := range all {
+		if rec.Category == category && rec.ArchivedAt == nil {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeCatalogRepository) GetCategoryByID(ctx context.Context, id int) (*Category, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.categories[id]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *fakeCatalogRepository) GetCategoryByName(ctx context.Context, name string) (*Category, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.categories {
+		if c.Name == name {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeCatalogRepository) CreateCategory(ctx context.Context, c *Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	c.ID = r.nextID
+	r.categories[c.ID] = *c
+	return nil
+}
+
+// RenameCategory moves the category's products along with it, like the foreign key's ON UPDATE CASCADE.
+func (r *fakeCatalogRepository) RenameCategory(ctx context.Context, id int, name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.categories[id]
+	if !ok {
+		return false, nil
+	}
+	for recID, rec := range r.records {
+		if rec.Category == c.Name {
+			rec.Category = name
+			r.records[recID] = rec
+		}
+	}
+	c.Name = name
+	r.categories[id] = c
+	return true, nil
+}
+
+func (r *fakeCatalogRepository) CountByCategory(ctx context.Context, category string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, rec := range r.records {
+		if rec.Category == category {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *fakeCatalogRepository) DeleteCategory(ctx context.Context, id int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.categories[id]; !ok {
+		return false, nil
+	}
+	delete(r.categories, id)
+	return true, nil
+}
+
+func newTestAdminService(t *testing.T, repo Repository) (*adminService, *cache.MemoryCache) {
+	t.Helper()
+	c := cache.NewMemoryCache(time.Minute)
+	t.Cleanup(c.Stop)
+	return NewAdminService(repo, c).(*adminService), c
+}
+
+// fillCache stores a value and its stale copy under each key.
+func fillCache(c *cache.MemoryCache, keys ...string) {
+	for _, key := range keys {
+		c.Set(key, "cached", time.Minute)
+		c.Set(staleCacheKey(key), "cached", time.Hour)
+	}
+}
+
+// wantEvicted fails the test if any key, or its stale copy, is still cached.
+func wantEvicted(t *testing.T, c *cache.MemoryCache, what string, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		for _, k := range []string{key, staleCacheKey(key)} {
+			if _, ok := c.Get(k); ok {
+				t.Errorf("%s: %s is still cached", what, k)
+			}
+		}
+	}
+}
+
+// catalogKeys are the cache entries that list every product or category.
+var catalogKeys = []string{cacheKeyAllProducts, cacheKeyCategories, cacheKeySearchIndex}
+
+func TestAdminCreateProduct(t *testing.T) {
+	repo := newFakeCatalogRepository([]string{"books"})
+	svc, c := newTestAdminService(t, repo)
+	ctx := context.Background()
+
+	if _, err := svc.CreateProduct(ctx, ProductInput{Title: "Go", Category: "games", Price: 10}); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("unknown category: err = %v, want ErrCategoryNotFound", err)
+	}
+	if _, err := svc.CreateProduct(ctx, ProductInput{Title: " \t ", Category: "books", Price: 10}); !errors.Is(err, ErrBlankTitle) {
+		t.Errorf("blank title: err = %v, want ErrBlankTitle", err)
+	}
+	if all, _ := repo.ListAll(ctx); len(all) != 0 {
+		t.Fatalf("rejected products were stored: %+v", all)
+	}
+
+	fillCache(c, append(catalogKeys, categoryCacheKey("books"))...)
+	p, err := svc.CreateProduct(ctx, ProductInput{Title: "  The Go Programming Language ", Category: "books", Price: 39.99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "The Go Programming Language" || p.Price != 39.99 || p.Archived || p.CreatedAt.IsZero() {
+		t.Errorf("created %+v", p)
+	}
+	if stored, _ := repo.GetByID(ctx, p.ID); stored == nil || stored.Title != p.Title || stored.PriceCents != 3999 {
+		t.Errorf("stored %+v", stored)
+	}
+	wantEvicted(t, c, "create", append(catalogKeys, categoryCacheKey("books"))...)
+}
+
+func TestAdminUpdateProduct(t *testing.T) {
+	repo := newFakeCatalogRepository([]string{"books", "games"}, mirrored(1, "Go", 10))
+	svc, c := newTestAdminService(t, repo)
+	ctx := context.Background()
+	str := func(s string) *string { return &s }
+
+	if _, err := svc.UpdateProduct(ctx, 2, ProductUpdate{Title: str("Missing")}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown product: err = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.UpdateProduct(ctx, 1, ProductUpdate{Title: str("   ")}); !errors.Is(err, ErrBlankTitle) {
+		t.Errorf("blank title: err = %v, want ErrBlankTitle", err)
+	}
+	if _, err := svc.UpdateProduct(ctx, 1, ProductUpdate{Category: str("toys")}); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("unknown category: err = %v, want ErrCategoryNotFound", err)
+	}
+	if stored, _ := repo.GetByID(ctx, 1); stored.Title != "Go" || stored.Category != "books" {
+		t.Fatalf("a rejected update changed the product: %+v", stored)
+	}
+
+	// The product leaves one category's list and joins another's, so both are cleared.
+	keys := append(catalogKeys, productCacheKey(1), categoryCacheKey("books"), categoryCacheKey("games"))
+	fillCache(c, keys...)
+	p, err := svc.UpdateProduct(ctx, 1, ProductUpdate{Title: str(" Go, Second Edition "), Category: str("games")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Go, Second Edition" || p.Category != "games" || p.Price != 10 {
+		t.Errorf("updated %+v", p)
+	}
+	wantEvicted(t, c, "update", keys...)
+}
+
+func TestAdminPriceArchiveAndRestore(t *testing.T) {
+	repo := newFakeCatalogRepository([]string{"books"}, mirrored(1, "Go", 10))
+	svc, c := newTestAdminService(t, repo)
+	ctx := context.Background()
+	keys := append(catalogKeys, productCacheKey(1), categoryCacheKey("books"))
+
+	tests := []struct {
+		name   string
+		change func(id int) (*AdminProduct, error)
+		check  func(p *AdminProduct) bool
+	}{
+		{"price", func(id int) (*AdminProduct, error) { return svc.SetPrice(ctx, id, 12.5) },
+			func(p *AdminProduct) bool { return p.Price == 12.5 }},
+		{"archive", func(id int) (*AdminProduct, error) { return svc.ArchiveProduct(ctx, id) },
+			func(p *AdminProduct) bool { return p.Archived && p.ArchivedAt != nil }},
+		{"restore", func(id int) (*AdminProduct, error) { return svc.RestoreProduct(ctx, id) },
+			func(p *AdminProduct) bool { return !p.Archived && p.ArchivedAt == nil }},
+	}
+	for _, tt := range tests {
+		if _, err := tt.change(2); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s unknown product: err = %v, want ErrNotFound", tt.name, err)
+		}
+		fillCache(c, keys...)
+		p, err := tt.change(1)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !tt.check(p) {
+			t.Errorf("%s: got %+v", tt.name, p)
+		}
+		wantEvicted(t, c, tt.name, keys...)
+	}
+}
+
+func TestAdminRenameCategory(t *testing.T) {
+	repo := newFakeCatalogRepository([]string{"books", "games"}, mirrored(1, "Go", 10), mirrored(2, "Rust", 20))
+	svc, c := newTestAdminService(t, repo)
+	ctx := context.Background()
+
+	if _, err := svc.RenameCategory(ctx, 99, "toys"); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("unknown category: err = %v, want ErrCategoryNotFound", err)
+	}
+	if _, err := svc.RenameCategory(ctx, 1, "games"); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("taken name: err = %v, want ErrCategoryExists", err)
+	}
+
+	// Renaming to the current name is a no-op and leaves the cache alone.
+	fillCache(c, cacheKeyCategories)
+	if cat, err := svc.RenameCategory(ctx, 1, " books "); err != nil || cat.Name != "books" {
+		t.Errorf("same name: %+v, %v", cat, err)
+	}
+	if _, ok := c.Get(cacheKeyCategories); !ok {
+		t.Error("renaming to the same name cleared the cache")
+	}
+
+	keys := append(catalogKeys, productCacheKey(1), productCacheKey(2), categoryCacheKey("books"), categoryCacheKey("textbooks"))
+	fillCache(c, keys...)
+	cat, err := svc.RenameCategory(ctx, 1, "textbooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cat.ID != 1 || cat.Name != "textbooks" {
+		t.Errorf("renamed %+v", cat)
+	}
+	if n, _ := repo.CountByCategory(ctx, "textbooks"); n != 2 {
+		t.Errorf("%d products moved to the new name, want 2", n)
+	}
+	wantEvicted(t, c, "rename", keys...)
+}
+
+func TestAdminDeleteCategory(t *testing.T) {
+	archived := mirrored(1, "Go", 10)
+	archived.ArchivedAt = new(time.Time)
+	repo := newFakeCatalogRepository([]string{"books", "games"}, archived)
+	svc, c := newTestAdminService(t, repo)
+	ctx := context.Background()
+
+	if err := svc.DeleteCategory(ctx, 99); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("unknown category: err = %v, want ErrCategoryNotFound", err)
+	}
+	// Archived products still count: past orders refer to their category.
+	if err := svc.DeleteCategory(ctx, 1); !errors.Is(err, ErrCategoryNotEmpty) {
+		t.Errorf("category with an archived product: err = %v, want ErrCategoryNotEmpty", err)
+	}
+
+	fillCache(c, cacheKeyCategories)
+	if err := svc.DeleteCategory(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if cat, _ := repo.GetCategoryByID(ctx, 2); cat != nil {
+		t.Error("category still stored")
+	}
+	wantEvicted(t, c, "delete", cacheKeyCategories)
+}
+
+func TestCreateProductRejectsBlankTitle(t *testing.T) {
+	repo := newFakeCatalogRepository([]string{"books"})
+	svc, _ := newTestAdminService(t, repo)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterAdminRoutes(r.Group("/admin"), svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/products", strings.NewReader(`{"title":"   ","category":"books","price":10}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "VALIDATION_ERROR") {
+		t.Errorf("status %d, body %s; want 400 VALIDATION_ERROR", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,70 @@
+package product
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := newCircuitBreaker(3, time.Hour)
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("call %d rejected before the threshold", i+1)
+		}
+		b.failure()
+	}
+	if !b.allow() {
+		t.Fatal("third call rejected")
+	}
+	b.success()
+	for i := 0; i < 2; i++ {
+		b.allow()
+		b.failure()
+	}
+	if !b.allow() {
+		t.Fatal("success did not reset the failure count")
+	}
+	b.failure()
+	if b.allow() {
+		t.Fatal("call allowed after threshold consecutive failures")
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := newCircuitBreaker(1, cooldown)
+	b.allow()
+	b.failure()
+	if b.allow() {
+		t.Fatal("call allowed during cooldown")
+	}
+
+	time.Sleep(cooldown)
+	if !b.allow() {
+		t.Fatal("probe rejected after cooldown")
+	}
+	if b.allow() {
+		t.Fatal("second call allowed while probing")
+	}
+	b.failure()
+	if b.allow() {
+		t.Fatal("failed probe did not reopen the circuit")
+	}
+
+	time.Sleep(cooldown)
+	if !b.allow() {
+		t.Fatal("probe rejected after cooldown")
+	}
+	// An abandoned probe says nothing about the upstream, so the next call probes instead.
+	b.abandon()
+	if !b.allow() {
+		t.Fatal("no new probe after an abandoned one")
+	}
+	b.success()
+	for i := 0; i < 3; i++ {
+		if !b.allow() {
+			t.Fatal("successful probe did not close the circuit")
+		}
+		b.success()
+	}
+}
@@ -3,15 +3,32 @@ package product
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"strconv"
 	"time"
 )
 
-const defaultTimeout = 10 * time.Second
+const (
+	defaultTimeout = 10 * time.Second
+
+	// Failed requests are tried up to maxAttempts times in all, waiting a random time of up to
+	// retryBaseDelay, doubling with each retry and capped at retryMaxDelay.
+	maxAttempts    = 3
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+
+	// After breakerThreshold calls in a row fail, calls fail fast for breakerCooldown.
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without calling the FakeStore API while it is considered down.
+var ErrCircuitOpen = errors.New("fakestore: circuit open")
 
 // Product represents a product from the FakeStoreAPI.
 type Product struct {
@@ -27,42 +44,30 @@ type Product struct {
 	} `json:"rating"`
 }
 
-// FakeStoreClient is an HTTP client for the FakeStoreAPI.
+// FakeStoreClient is an HTTP client for the FakeStoreAPI. Requests are retried with jittered
+// backoff on network errors, 429 and 5xx responses, and a circuit breaker stops calling the API
+// while it keeps failing.
 type FakeStoreClient struct {
 	baseURL string
 	client  *http.Client
+	breaker *circuitBreaker
 }
 
-// NewClient returns a new FakeStoreClient with a 10s timeout.
+// NewClient returns a new FakeStoreClient with a 10s timeout per attempt.
 func NewClient(baseURL string) *FakeStoreClient {
 	return &FakeStoreClient{
 		baseURL: baseURL,
 		client: &http.Client{
 			Timeout: defaultTimeout,
 		},
+		breaker: newCircuitBreaker(breakerThreshold, breakerCooldown),
 	}
 }
 
 // GetProducts fetches all products. Returns error on non-200.
 func (c *FakeStoreClient) GetProducts(ctx context.Context) ([]Product, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/products", nil)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("fakestore: unexpected status %d", resp.StatusCode)
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
 	var products []Product
-	if err := json.Unmarshal(body, &products); err != nil {
+	if err := c.getJSON(ctx, "/products", &products); err != nil {
 		return nil, err
 	}
 	return products, nil
@@ -70,25 +75,8 @@ func (c *FakeStoreClient) GetProducts(ctx context.Context) ([]Product, error) {
 
 // GetProduct fetches a single product by ID. Returns error on non-200.
 func (c *FakeStoreClient) GetProduct(ctx context.Context, id int) (*Product, error) {
-	url := c.baseURL + "/products/" + strconv.Itoa(id)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("fakestore: unexpected status %d", resp.StatusCode)
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
 	var p Product
-	if err := json.Unmarshal(body, &p); err != nil {
+	if err := c.getJSON(ctx, "/products/"+strconv.Itoa(id), &p); err != nil {
 		return nil, err
 	}
 	return &p, nil
@@ -96,24 +84,8 @@ func (c *FakeStoreClient) GetProduct(ctx context.Context, id int) (*Product, err
 
 // GetCategories fetches all category names. Returns error on non-200.
 func (c *FakeStoreClient) GetCategories(ctx context.Context) ([]string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/products/categories", nil)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("fakestore: unexpected status %d", resp.StatusCode)
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
 	var categories []string
-	if err := json.Unmarshal(body, &categories); err != nil {
+	if err := c.getJSON(ctx, "/products/categories", &categories); err != nil {
 		return nil, err
 	}
 	return categories, nil
@@ -121,26 +93,101 @@ func (c *FakeStoreClient) GetCategories(ctx context.Context) ([]string, error) {
 
 // GetProductsByCategory fetches products in the given category. Returns error on non-200.
 func (c *FakeStoreClient) GetProductsByCategory(ctx context.Context, category string) ([]Product, error) {
-	path := c.baseURL + "/products/category/" + url.PathEscape(category)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
-	if err != nil {
+	var products []Product
+	if err := c.getJSON(ctx, "/products/category/"+url.PathEscape(category), &products); err != nil {
 		return nil, err
 	}
+	return products, nil
+}
+
+// statusError is a non-200 response from the API.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("fakestore: unexpected status %d", e.code)
+}
+
+// retryable reports whether err is worth retrying: a failure of the upstream rather than of the
+// request itself.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr)
+}
+
+// getJSON GETs path and decodes the JSON response into dst, retrying upstream failures and
+// reporting the outcome to the circuit breaker.
+func (c *FakeStoreClient) getJSON(ctx context.Context, path string, dst interface{}) error {
+	if !c.breaker.allow() {
+		return ErrCircuitOpen
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = c.get(ctx, path, dst)
+		if err == nil || !retryable(err) || attempt == maxAttempts || ctx.Err() != nil {
+			break
+		}
+		if sleepErr := sleepCtx(ctx, backoff(attempt)); sleepErr != nil {
+			break
+		}
+	}
+	switch {
+	case err == nil || !retryable(err):
+		// The API answered, even if not with what we wanted.
+		c.breaker.success()
+	case ctx.Err() != nil:
+		c.breaker.abandon()
+	default:
+		c.breaker.failure()
+	}
+	return err
+}
+
+// get makes a single GET request for path and decodes the JSON response into dst.
+func (c *FakeStoreClient) get(ctx context.Context, path string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("fakestore: unexpected status %d", resp.StatusCode)
+		return &statusError{code: resp.StatusCode}
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var products []Product
-	if err := json.Unmarshal(body, &products); err != nil {
-		return nil, err
+	return json.Unmarshal(body, dst)
+}
+
+// backoff returns how long to wait before the retry following the given attempt: a random
+// duration up to retryBaseDelay doubled for each earlier attempt, capped at retryMaxDelay.
+func backoff(attempt int) time.Duration {
+	ceiling := retryBaseDelay << (attempt - 1)
+	if ceiling > retryMaxDelay || ceiling <= 0 {
+		ceiling = retryMaxDelay
+	}
+	return rand.N(ceiling) + time.Millisecond
+}
+
+// sleepCtx waits for d or until ctx is done, returning ctx.Err() in the latter case.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
-	return products, nil
 }
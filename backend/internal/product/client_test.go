@@ -0,0 +1,110 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a FakeStore stand-in answering each request with the status returned by
+// status, given the 1-based request number, and an empty product list on 200.
+func newTestServer(t *testing.T, status func(n int64) int) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := status(calls.Add(1))
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			_, _ = w.Write([]byte("[]"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestClientRetriesUpstreamFailures(t *testing.T) {
+	srv, calls := newTestServer(t, func(n int64) int {
+		if n < maxAttempts {
+			return http.StatusBadGateway
+		}
+		return http.StatusOK
+	})
+	if _, err := NewClient(srv.URL).GetProducts(context.Background()); err != nil {
+		t.Fatalf("GetProducts: %v", err)
+	}
+	if got := calls.Load(); got != maxAttempts {
+		t.Fatalf("%d requests, want %d", got, maxAttempts)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	srv, calls := newTestServer(t, func(int64) int { return http.StatusNotFound })
+	c := NewClient(srv.URL)
+	for i := 0; i < breakerThreshold+1; i++ {
+		var se *statusError
+		if _, err := c.GetProducts(context.Background()); !errors.As(err, &se) || se.code != http.StatusNotFound {
+			t.Fatalf("err = %v, want status 404", err)
+		}
+	}
+	// Each call was tried once, and answers from the API never open the circuit.
+	if got := calls.Load(); got != breakerThreshold+1 {
+		t.Fatalf("%d requests, want %d", got, breakerThreshold+1)
+	}
+}
+
+func TestClientOpensCircuit(t *testing.T) {
+	srv, calls := newTestServer(t, func(int64) int { return http.StatusServiceUnavailable })
+	c := NewClient(srv.URL)
+	for i := 0; i < breakerThreshold; i++ {
+		if _, err := c.GetProducts(context.Background()); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: err = %v, want the upstream failure", i+1, err)
+		}
+	}
+	made := calls.Load()
+	if made != breakerThreshold*maxAttempts {
+		t.Fatalf("%d requests, want %d", made, breakerThreshold*maxAttempts)
+	}
+	if _, err := c.GetProducts(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if calls.Load() != made {
+		t.Fatal("open circuit still called the API")
+	}
+}
+
+func TestClientStopsRetryingWhenCancelled(t *testing.T) {
+	srv, calls := newTestServer(t, func(int64) int { return http.StatusInternalServerError })
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := NewClient(srv.URL).GetProducts(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := calls.Load(); got != 0 {
+		t.Fatalf("%d requests with a cancelled context, want 0", got)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	for attempt := 1; attempt <= 6; attempt++ {
+		ceiling := retryBaseDelay << (attempt - 1)
+		if ceiling > retryMaxDelay {
+			ceiling = retryMaxDelay
+		}
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 50; i++ {
+			d := backoff(attempt)
+			if d <= 0 || d > ceiling+time.Millisecond {
+				t.Fatalf("backoff(%d) = %s, want within (0, %s]", attempt, d, ceiling+time.Millisecond)
+			}
+			seen[d] = true
+		}
+		if len(seen) < 2 {
+			t.Fatalf("backoff(%d) returned the same delay 50 times", attempt)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package product
+
+import (
+	"context"
+	"sync"
+)
+
+// flight is an in-progress fetch whose result is shared by every caller asking for its key.
+type flight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// flightGroup coalesces fetches by key, so only one fetch per key runs at a time.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// start runs fn in the background unless a fetch for key is already running, and returns the
+// running fetch.
+func (g *flightGroup) start(key string, fn func() (interface{}, error)) *flight {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.flights[key]; ok {
+		return f
+	}
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	go func() {
+		f.value, f.err = fn()
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+// do returns the result of fn, or of the fetch for key already running. A caller whose ctx ends
+// stops waiting, but the fetch carries on for the others.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	f := g.start(key, fn)
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
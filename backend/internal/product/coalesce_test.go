@@ -0,0 +1,142 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupCoalesces(t *testing.T) {
+	var g flightGroup
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.do(context.Background(), "key", fn)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = v
+		}()
+	}
+	// Let every caller join the flight before it finishes.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("fn ran %d times, want 1", n)
+	}
+	for i, v := range results {
+		if v != "value" {
+			t.Errorf("caller %d got %v", i, v)
+		}
+	}
+
+	// A finished flight is forgotten, so the next call fetches again.
+	if _, err := g.do(context.Background(), "key", func() (interface{}, error) { return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.do(context.Background(), "other", fn); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("fn ran %d times after the first flight ended, want 2", n)
+	}
+}
+
+func TestFlightGroupCallerCancel(t *testing.T) {
+	var g flightGroup
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return "value", nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := g.start("key", fn)
+	cancel()
+	if _, err := g.do(ctx, "key", fn); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	close(release)
+	<-f.done
+	if f.value != "value" || f.err != nil {
+		t.Fatalf("flight ended with %v, %v after a caller gave up", f.value, f.err)
+	}
+}
+
+func TestConcurrentMissesShareOneFetch(t *testing.T) {
+	source := newFakeSource(Product{ID: 1, Title: "Backpack"})
+	source.delay = 20 * time.Millisecond
+	svc, _ := newTestService(t, source)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p, err := svc.GetByID(context.Background(), 1); err != nil || p.Title != "Backpack" {
+				t.Errorf("GetByID = %v, %v", p, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := source.count("GetProduct"); n != 1 {
+		t.Fatalf("%d upstream lookups, want 1", n)
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	source := newFakeSource(Product{ID: 1, Title: "New title"})
+	svc, c := newTestService(t, source)
+	ctx := context.Background()
+
+	old := []Product{{ID: 1, Title: "Old title"}}
+	c.Set(cacheKeyAllProducts, &cacheEntry{value: old, refreshAt: time.Now().Add(time.Minute)}, time.Minute)
+	if _, err := svc.GetAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := source.count("GetProducts"); n != 0 {
+		t.Fatalf("fresh entry was refreshed %d times", n)
+	}
+
+	// Past its refresh time the cached list is still served while a new one is fetched.
+	c.Set(cacheKeyAllProducts, &cacheEntry{value: old, refreshAt: time.Now().Add(-time.Second)}, time.Minute)
+	products, err := svc.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if products[0].Title != "Old title" {
+		t.Fatalf("got %q, want the cached list served immediately", products[0].Title)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		products, err = svc.GetAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if products[0].Title == "New title" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the background refresh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := source.count("GetProducts"); n != 1 {
+		t.Fatalf("%d upstream fetches, want one background refresh", n)
+	}
+}
@@ -0,0 +1,79 @@
+package product
+
+import (
+	"context"
+	"math"
+)
+
+// DBSource serves the catalog from the products table. Archived products are left out of every
+// listing and are not found by ID, so they can no longer be added to carts or ordered.
+type DBSource struct {
+	repo Repository
+}
+
+// NewDBSource returns a DBSource reading from repo.
+func NewDBSource(repo Repository) *DBSource {
+	return &DBSource{repo: repo}
+}
+
+// GetProducts returns every product on sale.
+func (s *DBSource) GetProducts(ctx context.Context) ([]Product, error) {
+	records, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toProducts(records), nil
+}
+
+// GetProduct returns the product with the given ID, or ErrNotFound if it does not exist or is archived.
+func (s *DBSource) GetProduct(ctx context.Context, id int) (*Product, error) {
+	r, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil || r.ArchivedAt != nil {
+		return nil, ErrNotFound
+	}
+	p := r.Product()
+	return &p, nil
+}
+
+// GetCategories returns the categories that have products on sale.
+func (s *DBSource) GetCategories(ctx context.Context) ([]string, error) {
+	names, err := s.repo.ListActiveCategoryNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if names == nil {
+		names = []string{}
+	}
+	return names, nil
+}
+
+// GetProductsByCategory returns the products on sale in the given category.
+func (s *DBSource) GetProductsByCategory(ctx context.Context, category string) ([]Product, error) {
+	records, err := s.repo.ListActiveByCategory(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	return toProducts(records), nil
+}
+
+// toProducts converts stored records to the public Product shape.
+func toProducts(records []ProductRecord) []Product {
+	out := make([]Product, len(records))
+	for i := range records {
+		out[i] = records[i].Product()
+	}
+	return out
+}
+
+// centsToPrice converts a price in cents to the decimal price used by Product.
+func centsToPrice(cents int) float64 {
+	return float64(cents) / 100
+}
+
+// priceToCents converts a decimal price to cents, rounding to the nearest cent.
+func priceToCents(price float64) int {
+	return int(math.Round(price * 100))
+}
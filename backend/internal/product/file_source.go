@@ -0,0 +1,84 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FileSource serves the catalog from a JSON file holding an array of products in the FakeStore
+// format, for offline development. The file is read on every call so edits show up once the
+// service cache expires.
+type FileSource struct {
+	path string
+}
+
+// NewFileSource returns a FileSource reading from path.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+// GetProducts returns every product in the file.
+func (s *FileSource) GetProducts(ctx context.Context) ([]Product, error) {
+	return s.load()
+}
+
+// GetProduct returns the product with the given ID, or ErrNotFound.
+func (s *FileSource) GetProduct(ctx context.Context, id int) (*Product, error) {
+	products, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		if products[i].ID == id {
+			return &products[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// GetCategories returns the distinct category names in the order they first appear.
+func (s *FileSource) GetCategories(ctx context.Context) ([]string, error) {
+	products, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	categories := []string{}
+	for _, p := range products {
+		if !seen[p.Category] {
+			seen[p.Category] = true
+			categories = append(categories, p.Category)
+		}
+	}
+	return categories, nil
+}
+
+// GetProductsByCategory returns the products in the given category.
+func (s *FileSource) GetProductsByCategory(ctx context.Context, category string) ([]Product, error) {
+	products, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	out := []Product{}
+	for _, p := range products {
+		if p.Category == category {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// load reads and decodes the catalog file.
+func (s *FileSource) load() ([]Product, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("product: read catalog file: %w", err)
+	}
+	var products []Product
+	if err := json.Unmarshal(data, &products); err != nil {
+		return nil, fmt.Errorf("product: decode catalog file: %w", err)
+	}
+	return products, nil
+}
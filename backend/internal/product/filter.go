@@ -0,0 +1,175 @@
+package product
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sort orders accepted by GET /products. The default keeps the catalog's own order.
+const (
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortRating    = "rating"
+	SortNewest    = "newest"
+	SortTitle     = "title"
+)
+
+// priceBuckets are the upper bounds, in dollars, of the price facet buckets. The last bucket has
+// no upper bound.
+var priceBuckets = []float64{25, 50, 100, 200}
+
+// Filter narrows a product listing. Zero fields match everything.
+type Filter struct {
+	Categories []string // any of these
+	MinPrice   *float64
+	MaxPrice   *float64
+	MinRating  *float64
+}
+
+// CategoryFacet is the number of products in a category that match the other filters.
+type CategoryFacet struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// PriceFacet is the number of products priced in [Min, Max) that match the other filters. Max is
+// nil for the most expensive bucket.
+type PriceFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// Facets holds the counts shown next to each filter option.
+type Facets struct {
+	Categories []CategoryFacet `json:"categories"`
+	Prices     []PriceFacet    `json:"prices"`
+}
+
+// matchesCategory reports whether p is in one of the filter's categories.
+func (f *Filter) matchesCategory(p *Product) bool {
+	if len(f.Categories) == 0 {
+		return true
+	}
+	for _, c := range f.Categories {
+		if strings.EqualFold(c, p.Category) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPrice reports whether p is within the filter's price range.
+func (f *Filter) matchesPrice(p *Product) bool {
+	return (f.MinPrice == nil || p.Price >= *f.MinPrice) && (f.MaxPrice == nil || p.Price <= *f.MaxPrice)
+}
+
+// matchesRating reports whether p is rated at least the filter's minimum.
+func (f *Filter) matchesRating(p *Product) bool {
+	return f.MinRating == nil || p.Rating.Rate >= *f.MinRating
+}
+
+// FilterProducts returns the products matching f, in their original order.
+func FilterProducts(products []Product, f Filter) []Product {
+	out := make([]Product, 0, len(products))
+	for i := range products {
+		p := &products[i]
+		if f.matchesCategory(p) && f.matchesPrice(p) && f.matchesRating(p) {
+			out = append(out, *p)
+		}
+	}
+	return out
+}
+
+// ValidSort reports whether order is empty or one of the Sort constants.
+func ValidSort(order string) bool {
+	switch order {
+	case "", SortPriceAsc, SortPriceDesc, SortRating, SortNewest, SortTitle:
+		return true
+	}
+	return false
+}
+
+// SortProducts sorts products in place by order, one of the Sort constants, and leaves them as
+// they are for any other order. Newest is by descending ID, since IDs are assigned in order.
+// Ties keep their original order.
+func SortProducts(products []Product, order string) {
+	var less func(a, b *Product) bool
+	switch order {
+	case SortPriceAsc:
+		less = func(a, b *Product) bool { return a.Price < b.Price }
+	case SortPriceDesc:
+		less = func(a, b *Product) bool { return a.Price > b.Price }
+	case SortRating:
+		less = func(a, b *Product) bool {
+			if a.Rating.Rate != b.Rating.Rate {
+				return a.Rating.Rate > b.Rating.Rate
+			}
+			return a.Rating.Count > b.Rating.Count
+		}
+	case SortNewest:
+		less = func(a, b *Product) bool { return a.ID > b.ID }
+	case SortTitle:
+		less = func(a, b *Product) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	default:
+		return
+	}
+	sort.SliceStable(products, func(i, j int) bool { return less(&products[i], &products[j]) })
+}
+
+// ComputeFacets counts products per category and per price bucket. Each facet ignores its own
+// filter, so the counts show what choosing another option would return: category counts apply
+// the price and rating filters, and price counts apply the category and rating filters. Every
+// category in products is listed, even when its count is zero.
+func ComputeFacets(products []Product, f Filter) Facets {
+	categoryCounts := make(map[string]int)
+	priceCounts := make([]int, len(priceBuckets)+1)
+	for i := range products {
+		p := &products[i]
+		if _, ok := categoryCounts[p.Category]; !ok {
+			categoryCounts[p.Category] = 0
+		}
+		if !f.matchesRating(p) {
+			continue
+		}
+		if f.matchesPrice(p) {
+			categoryCounts[p.Category]++
+		}
+		if f.matchesCategory(p) {
+			priceCounts[priceBucket(p.Price)]++
+		}
+	}
+
+	facets := Facets{
+		Categories: make([]CategoryFacet, 0, len(categoryCounts)),
+		Prices:     make([]PriceFacet, len(priceCounts)),
+	}
+	for c, n := range categoryCounts {
+		facets.Categories = append(facets.Categories, CategoryFacet{Category: c, Count: n})
+	}
+	sort.Slice(facets.Categories, func(i, j int) bool {
+		return facets.Categories[i].Category < facets.Categories[j].Category
+	})
+	for i := range priceCounts {
+		b := PriceFacet{Count: priceCounts[i]}
+		if i > 0 {
+			b.Min = priceBuckets[i-1]
+		}
+		if i < len(priceBuckets) {
+			max := priceBuckets[i]
+			b.Max = &max
+		}
+		facets.Prices[i] = b
+	}
+	return facets
+}
+
+// priceBucket returns the index of the price bucket containing price.
+func priceBucket(price float64) int {
+	for i, max := range priceBuckets {
+		if price < max {
+			return i
+		}
+	}
+	return len(priceBuckets)
+}
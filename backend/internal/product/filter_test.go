@@ -0,0 +1,155 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func listed(id int, title, category string, price, rate float64, count int) Product {
+	p := Product{ID: id, Title: title, Category: category, Price: price}
+	p.Rating.Rate, p.Rating.Count = rate, count
+	return p
+}
+
+var filterCatalog = []Product{
+	listed(1, "backpack", "bags", 109.95, 3.9, 120),
+	listed(2, "T-shirt", "men's clothing", 22.3, 4.1, 259),
+	listed(3, "Jacket", "men's clothing", 55.99, 4.7, 500),
+	listed(4, "bracelet", "jewelery", 695, 4.6, 400),
+	listed(5, "Ring", "jewelery", 9.99, 3.0, 400),
+	listed(6, "Dress", "women's clothing", 22.3, 4.7, 146),
+}
+
+func productIDs(products []Product) []int {
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func float(v float64) *float64 { return &v }
+
+func TestFilterProducts(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int
+	}{
+		{"no filter", Filter{}, []int{1, 2, 3, 4, 5, 6}},
+		{"one category, any case", Filter{Categories: []string{"Jewelery"}}, []int{4, 5}},
+		{"several categories", Filter{Categories: []string{"bags", "women's clothing"}}, []int{1, 6}},
+		{"price range is inclusive", Filter{MinPrice: float(22.3), MaxPrice: float(55.99)}, []int{2, 3, 6}},
+		{"minimum rating", Filter{MinRating: float(4.6)}, []int{3, 4, 6}},
+		{"all filters", Filter{Categories: []string{"men's clothing"}, MaxPrice: float(50), MinRating: float(4)}, []int{2}},
+		{"nothing matches", Filter{Categories: []string{"toys"}}, []int{}},
+	}
+	for _, tt := range tests {
+		if got := productIDs(FilterProducts(filterCatalog, tt.filter)); !equalIDs(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSortProducts(t *testing.T) {
+	tests := []struct {
+		order string
+		want  []int
+	}{
+		{"", []int{1, 2, 3, 4, 5, 6}},
+		{"unknown", []int{1, 2, 3, 4, 5, 6}},
+		// Equal prices keep the catalog order.
+		{SortPriceAsc, []int{5, 2, 6, 3, 1, 4}},
+		{SortPriceDesc, []int{4, 1, 3, 2, 6, 5}},
+		// Equal ratings are broken by the number of ratings.
+		{SortRating, []int{3, 6, 4, 2, 1, 5}},
+		{SortNewest, []int{6, 5, 4, 3, 2, 1}},
+		{SortTitle, []int{1, 4, 6, 3, 5, 2}},
+	}
+	for _, tt := range tests {
+		products := append([]Product(nil), filterCatalog...)
+		SortProducts(products, tt.order)
+		if got := productIDs(products); !equalIDs(got, tt.want) {
+			t.Errorf("sort %q: got %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestComputeFacets(t *testing.T) {
+	facets := ComputeFacets(filterCatalog, Filter{Categories: []string{"men's clothing"}, MaxPrice: float(100)})
+
+	wantCategories := []CategoryFacet{
+		{"bags", 0}, {"jewelery", 1}, {"men's clothing", 2}, {"women's clothing", 1},
+	}
+	if len(facets.Categories) != len(wantCategories) {
+		t.Fatalf("categories = %+v, want %+v", facets.Categories, wantCategories)
+	}
+	for i, want := range wantCategories {
+		if facets.Categories[i] != want {
+			t.Errorf("category facet %d = %+v, want %+v", i, facets.Categories[i], want)
+		}
+	}
+
+	// Price counts ignore the price filter but apply the category filter.
+	wantPrices := []int{1, 0, 1, 0, 0}
+	if len(facets.Prices) != len(wantPrices) {
+		t.Fatalf("%d price buckets, want %d", len(facets.Prices), len(wantPrices))
+	}
+	for i, want := range wantPrices {
+		if facets.Prices[i].Count != want {
+			t.Errorf("price bucket %d count = %d, want %d", i, facets.Prices[i].Count, want)
+		}
+	}
+	if facets.Prices[0].Min != 0 || *facets.Prices[0].Max != 25 || facets.Prices[4].Min != 200 || facets.Prices[4].Max != nil {
+		t.Errorf("unexpected bucket bounds: %+v ... %+v", facets.Prices[0], facets.Prices[4])
+	}
+}
+
+func TestListProductsQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc, _ := newTestService(t, newFakeSource(filterCatalog...))
+	r := gin.New()
+	r.GET("/products", handleListProducts(svc))
+
+	tests := []struct {
+		query  string
+		status int
+		want   []int
+		total  int
+	}{
+		{"?category=jewelery&category=bags&sort=price_asc", http.StatusOK, []int{5, 1, 4}, 3},
+		{"?minRating=4&sort=newest&limit=2&page=2", http.StatusOK, []int{3, 2}, 4},
+		{"?minPrice=1000", http.StatusOK, []int{}, 0},
+		{"?sort=cheapest", http.StatusBadRequest, nil, 0},
+		{"?minPrice=-1", http.StatusBadRequest, nil, 0},
+		{"?minRating=abc", http.StatusBadRequest, nil, 0},
+		{"?minPrice=50&maxPrice=10", http.StatusBadRequest, nil, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.query, w.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Data []Product `json:"data"`
+			Meta struct {
+				Total int `json:"total"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if got := productIDs(body.Data); !equalIDs(got, tt.want) || body.Meta.Total != tt.total {
+			t.Errorf("%s: got %v of %d, want %v of %d", tt.query, got, body.Meta.Total, tt.want, tt.total)
+		}
+	}
+}
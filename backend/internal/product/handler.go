@@ -1,8 +1,11 @@
 package product
 
 import (
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/Rakesh2908/shopgo/pkg/response"
 	"github.com/gin-gonic/gin"
@@ -22,12 +25,13 @@ func RegisterRoutes(rg *gin.RouterGroup, svc ProductService) {
 	rg.GET("", handleListProducts(svc))
 }
 
-// handleListProducts handles GET /products?page=1&limit=12&category=
+// handleListProducts handles GET /products?page=1&limit=12&category=&minPrice=&maxPrice=&minRating=&sort=
+// category may be repeated to match any of several categories. sort is one of price_asc,
+// price_desc, rating, newest or title. meta.facets holds per-category and per-price-bucket counts.
 func handleListProducts(svc ProductService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		page, _ := strconv.Atoi(c.DefaultQuery("page", strconv.Itoa(defaultPage)))
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
-		category := c.Query("category")
 
 		if page < 1 {
 			page = defaultPage
@@ -36,18 +40,28 @@ func handleListProducts(svc ProductService) gin.HandlerFunc {
 			limit = defaultLimit
 		}
 
-		var products []Product
-		var err error
-		if category != "" {
-			products, err = svc.GetByCategory(c.Request.Context(), category)
-		} else {
-			products, err = svc.GetAll(c.Request.Context())
+		filter, ok := filterParams(c)
+		if !ok {
+			return
 		}
+		order := c.Query("sort")
+		if !ValidSort(order) {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR",
+				"sort must be one of price_asc, price_desc, rating, newest, title")
+			return
+		}
+
+		ctx, stale := WithStaleFlag(c.Request.Context())
+		products, err := svc.GetAll(ctx)
 		if err != nil {
 			response.Error(c, http.StatusBadGateway, "UPSTREAM_ERROR", "failed to fetch products")
 			return
 		}
 
+		facets := ComputeFacets(products, filter)
+		products = FilterProducts(products, filter)
+		SortProducts(products, order)
+
 		total := len(products)
 		start := (page - 1) * limit
 		if start >= total {
@@ -61,36 +75,86 @@ func handleListProducts(svc ProductService) gin.HandlerFunc {
 		}
 
 		meta := gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
+			"page":   page,
+			"limit":  limit,
+			"total":  total,
+			"facets": facets,
+			"stale":  stale.Load(),
 		}
 		response.SuccessWithMeta(c, http.StatusOK, products, meta)
 	}
 }
 
+// filterParams reads the listing filters from the query string, writing a 400 response if one is invalid.
+func filterParams(c *gin.Context) (Filter, bool) {
+	var filter Filter
+	for _, category := range c.QueryArray("category") {
+		if category = strings.TrimSpace(category); category != "" {
+			filter.Categories = append(filter.Categories, category)
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"minPrice", &filter.MinPrice}, {"maxPrice", &filter.MaxPrice}, {"minRating", &filter.MinRating}} {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", p.name+" must be a non-negative number")
+			return Filter{}, false
+		}
+		*p.dst = &f
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", "minPrice must not exceed maxPrice")
+		return Filter{}, false
+	}
+	return filter, true
+}
+
 // handleCategories handles GET /products/categories
 func handleCategories(svc ProductService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		categories, err := svc.GetCategories(c.Request.Context())
+		ctx, stale := WithStaleFlag(c.Request.Context())
+		categories, err := svc.GetCategories(ctx)
 		if err != nil {
 			response.Error(c, http.StatusBadGateway, "UPSTREAM_ERROR", "failed to fetch categories")
 			return
 		}
-		response.Success(c, http.StatusOK, categories)
+		writeCatalog(c, categories, stale)
 	}
 }
 
-// handleSearch handles GET /products/search?q=
+// handleSearch handles GET /products/search?q=&page=1&limit=12 — hits in relevance order.
 func handleSearch(svc ProductService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		q := c.Query("q")
-		products, err := svc.Search(c.Request.Context(), q)
+		page, _ := strconv.Atoi(c.DefaultQuery("page", strconv.Itoa(defaultPage)))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+		if page < 1 {
+			page = defaultPage
+		}
+		if limit < 1 || limit > 100 {
+			limit = defaultLimit
+		}
+
+		ctx, stale := WithStaleFlag(c.Request.Context())
+		hits, total, err := svc.Search(ctx, q, page, limit)
 		if err != nil {
 			response.Error(c, http.StatusBadGateway, "UPSTREAM_ERROR", "failed to search products")
 			return
 		}
-		response.Success(c, http.StatusOK, products)
+
+		meta := gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"stale": stale.Load(),
+		}
+		response.SuccessWithMeta(c, http.StatusOK, hits, meta)
 	}
 }
 
@@ -103,11 +167,22 @@ func handleGetProduct(svc ProductService) gin.HandlerFunc {
 			response.Error(c, http.StatusBadRequest, "INVALID_ID", "invalid product id")
 			return
 		}
-		p, err := svc.GetByID(c.Request.Context(), id)
+		ctx, stale := WithStaleFlag(c.Request.Context())
+		p, err := svc.GetByID(ctx, id)
 		if err != nil {
 			response.Error(c, http.StatusNotFound, "NOT_FOUND", "product not found")
 			return
 		}
-		response.Success(c, http.StatusOK, p)
+		writeCatalog(c, p, stale)
+	}
+}
+
+// writeCatalog writes a catalog read, adding meta.stale when it was served from the last known good
+// copy because the catalog source is failing.
+func writeCatalog(c *gin.Context, data interface{}, stale *atomic.Bool) {
+	if stale.Load() {
+		response.SuccessWithMeta(c, http.StatusOK, data, gin.H{"stale": true})
+		return
 	}
+	response.Success(c, http.StatusOK, data)
 }
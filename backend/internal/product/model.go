@@ -0,0 +1,99 @@
+package product
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductRecord is a product stored in the products table. Prices are kept in cents like order
+// totals; Product converts a record to the shape served by the public API.
+type ProductRecord struct {
+	ID          int    `gorm:"primaryKey"`
+	Title       string `gorm:"not null"`
+	Description string `gorm:"type:text"`
+	Category    string `gorm:"not null;index"`
+	Image       string
+	PriceCents  int        `gorm:"not null;check:price_cents > 0"`
+	RatingRate  float64    `gorm:"not null;default:0"`
+	RatingCount int        `gorm:"not null;default:0"`
+	ArchivedAt  *time.Time `gorm:"index"`
+	CreatedAt   time.Time  `gorm:"not null"`
+	UpdatedAt   time.Time  `gorm:"not null"`
+	CategoryRef Category   `gorm:"foreignKey:Category;references:Name;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+// TableName overrides the table name for ProductRecord.
+func (ProductRecord) TableName() string {
+	return "products"
+}
+
+// Product returns the record in the public Product shape.
+func (r *ProductRecord) Product() Product {
+	p := Product{
+		ID:          r.ID,
+		Title:       r.Title,
+		Price:       centsToPrice(r.PriceCents),
+		Description: r.Description,
+		Category:    r.Category,
+		Image:       r.Image,
+	}
+	p.Rating.Rate = r.RatingRate
+	p.Rating.Count = r.RatingCount
+	return p
+}
+
+// Category is a product category managed in the back office. Products reference it by name,
+// so renaming a category carries its products along.
+type Category struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `gorm:"not null" json:"createdAt"`
+}
+
+// TableName overrides the table name for Category.
+func (Category) TableName() string {
+	return "categories"
+}
+
+// Catalog sync statuses.
+const (
+	SyncRunning   = "running"
+	SyncSucceeded = "succeeded"
+	SyncFailed    = "failed"
+)
+
+// CatalogSync is one run of the catalog sync that mirrors the FakeStore catalog into the products
+// table, with what it changed.
+type CatalogSync struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Status       string     `gorm:"not null;index" json:"status"`
+	Added        int        `gorm:"not null;default:0" json:"added"`
+	Updated      int        `gorm:"not null;default:0" json:"updated"`
+	Removed      int        `gorm:"not null;default:0" json:"removed"`
+	Unchanged    int        `gorm:"not null;default:0" json:"unchanged"`
+	PriceChanges int        `gorm:"not null;default:0" json:"priceChanges"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    time.Time  `gorm:"not null;index" json:"startedAt"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+}
+
+// TableName overrides the table name for CatalogSync.
+func (CatalogSync) TableName() string {
+	return "catalog_syncs"
+}
+
+// PriceChange is a change to a product's price found by a catalog sync. Prices are in cents.
+type PriceChange struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ProductID     int       `gorm:"not null;index" json:"productId"`
+	SyncID        uuid.UUID `gorm:"type:uuid;not null;index" json:"syncId"`
+	OldPriceCents int       `gorm:"not null" json:"oldPriceCents"`
+	NewPriceCents int       `gorm:"not null" json:"newPriceCents"`
+	ChangedAt     time.Time `gorm:"not null;index" json:"changedAt"`
+}
+
+// TableName overrides the table name for PriceChange.
+func (PriceChange) TableName() string {
+	return "product_price_changes"
+}
@@ -0,0 +1,308 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository defines the interface for stored product and category persistence.
+type Repository interface {
+	ListActive(ctx context.Context) ([]ProductRecord, error)
+	ListActiveByCategory(ctx context.Context, category string) ([]ProductRecord, error)
+	ListActiveCategoryNames(ctx context.Context) ([]string, error)
+	List(ctx context.Context, page, limit int, includeArchived bool) ([]ProductRecord, int64, error)
+	GetByID(ctx context.Context, id int) (*ProductRecord, error)
+	Create(ctx context.Context, p *ProductRecord) error
+	Update(ctx context.Context, p *ProductRecord) error
+	UpdatePrice(ctx context.Context, id, priceCents int) (bool, error)
+	SetArchivedAt(ctx context.Context, id int, archivedAt *time.Time) (bool, error)
+	ListCategories(ctx context.Context) ([]Category, error)
+	GetCategoryByID(ctx context.Context, id int) (*Category, error)
+	GetCategoryByName(ctx context.Context, name string) (*Category, error)
+	CreateCategory(ctx context.Context, c *Category) error
+	RenameCategory(ctx context.Context, id int, name string) (bool, error)
+	DeleteCategory(ctx context.Context, id int) (bool, error)
+	CountByCategory(ctx context.Context, category string) (int64, error)
+	ListAll(ctx context.Context) ([]ProductRecord, error)
+	ApplySync(ctx context.Context, changes SyncChanges) error
+	CreateSync(ctx context.Context, s *CatalogSync) error
+	UpdateSync(ctx context.Context, s *CatalogSync) error
+	ListSyncs(ctx context.Context, page, limit int) ([]CatalogSync, int64, error)
+	ListPriceChanges(ctx context.Context, productID, page, limit int) ([]PriceChange, int64, error)
+}
+
+// SyncChanges are the writes a catalog sync makes, applied together by ApplySync.
+type SyncChanges struct {
+	Categories   []string        // created if missing
+	Added        []ProductRecord // inserted with their upstream IDs
+	Updated      []ProductRecord // fields overwritten from upstream; restored if archived
+	Removed      []int           // archived
+	PriceChanges []PriceChange
+	At           time.Time
+}
+
+// repository implements Repository using GORM.
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository returns a new product Repository.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// ListActive returns every product that is not archived, ordered by ID.
+func (r *repository) ListActive(ctx context.Context) ([]ProductRecord, error) {
+	var products []ProductRecord
+	err := r.db.WithContext(ctx).Where("archived_at IS NULL").Order("id").Find(&products).Error
+	return products, err
+}
+
+// ListActiveByCategory returns the products in category that are not archived, ordered by ID.
+func (r *repository) ListActiveByCategory(ctx context.Context, category string) ([]ProductRecord, error) {
+	var products []ProductRecord
+	err := r.db.WithContext(ctx).
+		Where("category = ? AND archived_at IS NULL", category).
+		Order("id").
+		Find(&products).Error
+	return products, err
+}
+
+// ListActiveCategoryNames returns the names of categories that have at least one product on sale.
+func (r *repository) ListActiveCategoryNames(ctx context.Context) ([]string, error) {
+	var names []string
+	err := r.db.WithContext(ctx).Model(&ProductRecord{}).
+		Where("archived_at IS NULL").
+		Distinct("category").
+		Order("category").
+		Pluck("category", &names).Error
+	return names, err
+}
+
+// List returns a page of products ordered by ID and the total count, optionally including archived ones.
+func (r *repository) List(ctx context.Context, page, limit int, includeArchived bool) ([]ProductRecord, int64, error) {
+	q := r.db.WithContext(ctx).Model(&ProductRecord{})
+	if !includeArchived {
+		q = q.Where("archived_at IS NULL")
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var products []ProductRecord
+	err := q.Order("id").Offset((page - 1) * limit).Limit(limit).Find(&products).Error
+	return products, total, err
+}
+
+// GetByID returns the product by ID, archived or not, or nil if not found.
+func (r *repository) GetByID(ctx context.Context, id int) (*ProductRecord, error) {
+	var p ProductRecord
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&p).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create inserts a new product.
+func (r *repository) Create(ctx context.Context, p *ProductRecord) error {
+	return r.db.WithContext(ctx).Omit("CategoryRef").Create(p).Error
+}
+
+// Update saves the product's descriptive fields. Price and archived state have their own methods.
+func (r *repository) Update(ctx context.Context, p *ProductRecord) error {
+	return r.db.WithContext(ctx).Model(&ProductRecord{}).Where("id = ?", p.ID).
+		Updates(map[string]interface{}{
+			"title":       p.Title,
+			"description": p.Description,
+			"category":    p.Category,
+			"image":       p.Image,
+			"updated_at":  p.UpdatedAt,
+		}).Error
+}
+
+// UpdatePrice sets the product's price. It returns false if the product does not exist.
+func (r *repository) UpdatePrice(ctx context.Context, id, priceCents int) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&ProductRecord{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"price_cents": priceCents, "updated_at": time.Now()})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// SetArchivedAt archives or, with a nil archivedAt, restores the product. It returns false if the
+// product does not exist.
+func (r *repository) SetArchivedAt(ctx context.Context, id int, archivedAt *time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&ProductRecord{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"archived_at": archivedAt, "updated_at": time.Now()})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// ListCategories returns every category ordered by name.
+func (r *repository) ListCategories(ctx context.Context) ([]Category, error) {
+	var categories []Category
+	err := r.db.WithContext(ctx).Order("name").Find(&categories).Error
+	return categories, err
+}
+
+// GetCategoryByID returns the category by ID, or nil if not found.
+func (r *repository) GetCategoryByID(ctx context.Context, id int) (*Category, error) {
+	var c Category
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&c).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCategoryByName returns the category with the given name, or nil if not found.
+func (r *repository) GetCategoryByName(ctx context.Context, name string) (*Category, error) {
+	var c Category
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&c).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCategory inserts a new category.
+func (r *repository) CreateCategory(ctx context.Context, c *Category) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+// RenameCategory changes the category's name; its products follow through the foreign key.
+// It returns false if the category does not exist.
+func (r *repository) RenameCategory(ctx context.Context, id int, name string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&Category{}).Where("id = ?", id).Update("name", name)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// DeleteCategory deletes the category. It returns false if the category does not exist.
+func (r *repository) DeleteCategory(ctx context.Context, id int) (bool, error) {
+	res := r.db.WithContext(ctx).Where("id = ?", id).Delete(&Category{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// CountByCategory returns how many products, archived or not, belong to category.
+func (r *repository) CountByCategory(ctx context.Context, category string) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&ProductRecord{}).Where("category = ?", category).Count(&n).Error
+	return n, err
+}
+
+// ListAll returns every product, archived or not, ordered by ID.
+func (r *repository) ListAll(ctx context.Context) ([]ProductRecord, error) {
+	var products []ProductRecord
+	err := r.db.WithContext(ctx).Order("id").Find(&products).Error
+	return products, err
+}
+
+// ApplySync applies a catalog sync's changes in one transaction, so the mirror never holds half
+// a sync. Inserting upstream IDs bypasses the ID sequence, so it is moved past the highest ID.
+func (r *repository) ApplySync(ctx context.Context, changes SyncChanges) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, name := range changes.Categories {
+			c := Category{Name: name, CreatedAt: changes.At}
+			if err := tx.Where(Category{Name: name}).FirstOrCreate(&c).Error; err != nil {
+				return err
+			}
+		}
+		if len(changes.Added) > 0 {
+			if err := tx.Omit("CategoryRef").Create(&changes.Added).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("SELECT setval(pg_get_serial_sequence('products', 'id'), (SELECT MAX(id) FROM products))").Error; err != nil {
+				return err
+			}
+		}
+		for _, p := range changes.Updated {
+			err := tx.Model(&ProductRecord{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+				"title":        p.Title,
+				"description":  p.Description,
+				"category":     p.Category,
+				"image":        p.Image,
+				"price_cents":  p.PriceCents,
+				"rating_rate":  p.RatingRate,
+				"rating_count": p.RatingCount,
+				"archived_at":  nil,
+				"updated_at":   changes.At,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(changes.Removed) > 0 {
+			err := tx.Model(&ProductRecord{}).Where("id IN ?", changes.Removed).
+				Updates(map[string]interface{}{"archived_at": changes.At, "updated_at": changes.At}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(changes.PriceChanges) > 0 {
+			if err := tx.Create(&changes.PriceChanges).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateSync inserts a catalog sync run.
+func (r *repository) CreateSync(ctx context.Context, s *CatalogSync) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+// UpdateSync saves a catalog sync run's status and results.
+func (r *repository) UpdateSync(ctx context.Context, s *CatalogSync) error {
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+// ListSyncs returns a page of catalog sync runs, newest first, and the total count.
+func (r *repository) ListSyncs(ctx context.Context, page, limit int) ([]CatalogSync, int64, error) {
+	q := r.db.WithContext(ctx).Model(&CatalogSync{})
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var syncs []CatalogSync
+	err := q.Order("started_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&syncs).Error
+	return syncs, total, err
+}
+
+// ListPriceChanges returns a page of recorded price changes, newest first, and the total count.
+// A productID of 0 lists changes to every product.
+func (r *repository) ListPriceChanges(ctx context.Context, productID, page, limit int) ([]PriceChange, int64, error) {
+	q := r.db.WithContext(ctx).Model(&PriceChange{})
+	if productID > 0 {
+		q = q.Where("product_id = ?", productID)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var changes []PriceChange
+	err := q.Order("changed_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&changes).Error
+	return changes, total, err
+}
@@ -0,0 +1,363 @@
+package product
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Searchable fields and how much a match in each counts towards a product's score.
+const (
+	fieldTitle = iota
+	fieldCategory
+	fieldDescription
+	numFields
+)
+
+var fieldWeights = [numFields]float64{
+	fieldTitle:       3,
+	fieldCategory:    2,
+	fieldDescription: 1,
+}
+
+// BM25 tuning: bm25K1 controls how quickly repeated terms stop adding to the score and bm25B how
+// strongly long fields are penalised.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// prefixMatchWeight discounts matches of the last query word as a prefix ("shi" -> "shirt"),
+	// so search-as-you-type works but whole-word matches still rank first.
+	prefixMatchWeight = 0.5
+
+	snippetLen = 160
+	markOpen   = "<mark>"
+	markClose  = "</mark>"
+)
+
+// stopwords are common words that carry no meaning for search and are left out of the index.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "from": true, "if": true, "in": true, "into": true,
+	"is": true, "it": true, "its": true, "of": true, "on": true, "or": true, "so": true,
+	"that": true, "the": true, "their": true, "this": true, "to": true, "was": true,
+	"will": true, "with": true, "you": true, "your": true,
+}
+
+// SearchHit is a product matching a search, with its relevance score and, for each field that
+// matched, an HTML-escaped excerpt with the matching words wrapped in <mark> tags.
+type SearchHit struct {
+	Product
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// token is a normalized word and its byte offsets in the original text.
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into lower-cased, stemmed words, skipping stopwords and single letters such
+// as the s of a possessive.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := strings.ToLower(text[start:end])
+		if !stopwords[word] && !isSingleLetter(word) {
+			tokens = append(tokens, token{term: stem(word), start: start, end: end})
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+// isSingleLetter reports whether word is one letter long. Single digits are kept.
+func isSingleLetter(word string) bool {
+	r, n := utf8.DecodeRuneInString(word)
+	return n == len(word) && unicode.IsLetter(r)
+}
+
+// posting records how often a term appears in each field of one product.
+type posting struct {
+	doc int
+	tf  [numFields]int
+}
+
+// searchIndex is an inverted index over the catalog, scored with BM25F.
+type searchIndex struct {
+	products []Product
+	lengths  [][numFields]int
+	avgLen   [numFields]float64
+	postings map[string][]posting
+	terms    []string // sorted, for prefix lookups
+}
+
+// buildIndex indexes the title, category and description of each product.
+func buildIndex(products []Product) *searchIndex {
+	idx := &searchIndex{
+		products: products,
+		lengths:  make([][numFields]int, len(products)),
+		postings: make(map[string][]posting),
+	}
+	var total [numFields]int
+	for doc, p := range products {
+		counts := make(map[string]*posting)
+		for f, text := range productFields(&p) {
+			tokens := tokenize(text)
+			idx.lengths[doc][f] = len(tokens)
+			total[f] += len(tokens)
+			for _, t := range tokens {
+				ps := counts[t.term]
+				if ps == nil {
+					ps = &posting{doc: doc}
+					counts[t.term] = ps
+				}
+				ps.tf[f]++
+			}
+		}
+		for term, ps := range counts {
+			idx.postings[term] = append(idx.postings[term], *ps)
+		}
+	}
+	if n := len(products); n > 0 {
+		for f := range total {
+			idx.avgLen[f] = float64(total[f]) / float64(n)
+		}
+	}
+	idx.terms = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+	return idx
+}
+
+// productFields returns the searchable text of p, indexed by field.
+func productFields(p *Product) [numFields]string {
+	return [numFields]string{
+		fieldTitle:       p.Title,
+		fieldCategory:    p.Category,
+		fieldDescription: p.Description,
+	}
+}
+
+// queryTerm is one word of a query and the index terms it matches, with their weights.
+type queryTerm struct {
+	matches map[string]float64
+}
+
+// parseQuery tokenizes q and resolves each word to index terms. The last word also matches any
+// term it is a prefix of, unless it is already a whole term.
+func (idx *searchIndex) parseQuery(q string) []queryTerm {
+	tokens := tokenize(q)
+	seen := make(map[string]bool)
+	var terms []queryTerm
+	for i, t := range tokens {
+		if seen[t.term] {
+			continue
+		}
+		seen[t.term] = true
+		qt := queryTerm{matches: map[string]float64{t.term: 1}}
+		if i == len(tokens)-1 && idx.postings[t.term] == nil {
+			// Try the word as typed as well as its stem: a partly typed word stems unpredictably.
+			for _, prefix := range []string{t.term, strings.ToLower(q[t.start:t.end])} {
+				for _, term := range idx.termsWithPrefix(prefix) {
+					qt.matches[term] = prefixMatchWeight
+				}
+			}
+		}
+		terms = append(terms, qt)
+	}
+	return terms
+}
+
+// termsWithPrefix returns the index terms beginning with prefix.
+func (idx *searchIndex) termsWithPrefix(prefix string) []string {
+	i := sort.SearchStrings(idx.terms, prefix)
+	var out []string
+	for ; i < len(idx.terms) && strings.HasPrefix(idx.terms[i], prefix); i++ {
+		out = append(out, idx.terms[i])
+	}
+	return out
+}
+
+// search returns the products matching q, most relevant first. Products matching more of the
+// query's words rank above those matching fewer; ties are broken by product ID.
+func (idx *searchIndex) search(q string) []SearchHit {
+	terms := idx.parseQuery(q)
+	if len(terms) == 0 {
+		return nil
+	}
+	scores := make(map[int]float64)
+	matched := make(map[int]int)
+	for _, qt := range terms {
+		termScores := make(map[int]float64)
+		for term, weight := range qt.matches {
+			postings := idx.postings[term]
+			idf := idx.idf(len(postings))
+			for _, ps := range postings {
+				termScores[ps.doc] = math.Max(termScores[ps.doc], weight*idf*idx.saturate(ps))
+			}
+		}
+		for doc, score := range termScores {
+			scores[doc] += score
+			matched[doc]++
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for doc, score := range scores {
+		coord := float64(matched[doc]) / float64(len(terms))
+		p := idx.products[doc]
+		hits = append(hits, SearchHit{
+			Product:    p,
+			Score:      math.Round(score*coord*1000) / 1000,
+			Highlights: highlight(&p, terms),
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// idf returns the inverse document frequency of a term found in df products.
+func (idx *searchIndex) idf(df int) float64 {
+	n := float64(len(idx.products))
+	return math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+// saturate combines a term's per-field frequencies, weighted and normalized by field length, and
+// applies BM25 saturation.
+func (idx *searchIndex) saturate(ps posting) float64 {
+	var tf float64
+	for f := 0; f < numFields; f++ {
+		if ps.tf[f] == 0 {
+			continue
+		}
+		norm := 1.0
+		if idx.avgLen[f] > 0 {
+			norm = 1 - bm25B + bm25B*float64(idx.lengths[ps.doc][f])/idx.avgLen[f]
+		}
+		tf += fieldWeights[f] * float64(ps.tf[f]) / norm
+	}
+	return tf * (bm25K1 + 1) / (tf + bm25K1)
+}
+
+// highlight returns the marked-up excerpts of the fields of p that contain a query word, keyed by
+// JSON field name. Long descriptions are cut to a snippet around the first match.
+func highlight(p *Product, terms []queryTerm) map[string]string {
+	out := make(map[string]string)
+	for f, text := range productFields(p) {
+		var spans []token
+		for _, t := range tokenize(text) {
+			for _, qt := range terms {
+				if _, ok := qt.matches[t.term]; ok {
+					spans = append(spans, t)
+					break
+				}
+			}
+		}
+		if len(spans) == 0 {
+			continue
+		}
+		switch f {
+		case fieldTitle:
+			out["title"] = markSpans(text, spans, 0, len(text))
+		case fieldCategory:
+			out["category"] = markSpans(text, spans, 0, len(text))
+		case fieldDescription:
+			start, end := snippetBounds(text, spans[0])
+			snippet := markSpans(text, spans, start, end)
+			if start > 0 {
+				snippet = "…" + snippet
+			}
+			if end < len(text) {
+				snippet += "…"
+			}
+			out["description"] = snippet
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// markSpans returns text[start:end], HTML-escaped, with the spans inside it wrapped in <mark> tags.
+func markSpans(text string, spans []token, start, end int) string {
+	var b strings.Builder
+	pos := start
+	for _, s := range spans {
+		if s.start < start || s.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:s.start]))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(text[s.start:s.end]))
+		b.WriteString(markClose)
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// snippetBounds returns the byte range of a snippet of about snippetLen bytes around first,
+// starting a little before it and cut at word boundaries.
+func snippetBounds(text string, first token) (start, end int) {
+	if len(text) <= snippetLen {
+		return 0, len(text)
+	}
+	start = first.start - snippetLen/4
+	if start <= 0 {
+		start = 0
+	} else {
+		// Move forward to the start of the next word.
+		for start < first.start && !isSpaceAt(text, start-1) {
+			start++
+		}
+	}
+	end = start + snippetLen
+	if end >= len(text) {
+		return start, len(text)
+	}
+	if end < first.end {
+		end = first.end
+	}
+	// Move back to the end of the previous word.
+	for end > first.end && !isSpaceAt(text, end) {
+		end--
+	}
+	for end > first.end && isSpaceAt(text, end-1) {
+		end--
+	}
+	return start, end
+}
+
+// isSpaceAt reports whether the rune starting at byte i of text is white space. Continuation
+// bytes of multi-byte runes are never space, so cuts only happen at rune boundaries.
+func isSpaceAt(text string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return utf8.RuneStart(text[i]) && unicode.IsSpace(r)
+}
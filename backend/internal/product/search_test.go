@@ -0,0 +1,170 @@
+package product
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var searchCatalog = []Product{
+	{ID: 1, Title: "Leather wallet", Category: "accessories", Description: "Slim wallet that fits a cotton shirt pocket."},
+	{ID: 2, Title: "Cotton shirt", Category: "men's clothing", Description: "A breathable shirt for everyday wear."},
+	{ID: 3, Title: "Rain jacket", Category: "men's clothing", Description: "Light jacket for wet days."},
+	{ID: 4, Title: "Shirt dress", Category: "women's clothing", Description: "Belted dress in the style of a shirt."},
+	{ID: 5, Title: "Gold ring", Category: "jewelery", Description: "Ring of solid gold."},
+}
+
+func hitIDs(hits []SearchHit) []int {
+	ids := make([]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := buildIndex(searchCatalog)
+	tests := []struct {
+		q    string
+		want []int
+	}{
+		// Title matches outrank description-only matches; among titles, the shorter field wins.
+		{"shirt", []int{2, 4, 1}},
+		// Stemming: the plural finds the singular.
+		{"shirts", []int{2, 4, 1}},
+		// Products matching every query word rank above those matching some.
+		{"cotton shirt", []int{2, 1, 4}},
+		// Category matches count, weighted below the title.
+		{"clothing", []int{2, 3, 4}},
+		// The last word matches as a prefix while typing.
+		{"jack", []int{3}},
+		{"the of and", nil},
+		{"submarine", nil},
+	}
+	for _, tt := range tests {
+		if got := hitIDs(idx.search(tt.q)); !equalIDs(got, tt.want) {
+			t.Errorf("search(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestSearchPrefixRanksBelowWholeWord(t *testing.T) {
+	idx := buildIndex([]Product{
+		{ID: 1, Title: "Ring light"},
+		{ID: 2, Title: "Ringer tee"},
+	})
+	hits := idx.search("ring")
+	if got := hitIDs(hits); !equalIDs(got, []int{1}) {
+		t.Fatalf("search(ring) = %v, want only the whole-word match", got)
+	}
+	hits = idx.search("rin")
+	if got := hitIDs(hits); len(got) != 2 {
+		t.Fatalf("search(rin) = %v, want both prefix matches", got)
+	}
+	for _, h := range hits {
+		if h.Score <= 0 {
+			t.Errorf("hit %d has score %v", h.ID, h.Score)
+		}
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	svc, _ := newTestService(t, newFakeSource(searchCatalog...))
+	ctx := context.Background()
+	tests := []struct {
+		q           string
+		page, limit int
+		want        []int
+		total       int
+	}{
+		{"shirt", 1, 2, []int{2, 4}, 3},
+		{"shirt", 2, 2, []int{1}, 3},
+		{"shirt", 3, 2, []int{}, 3},
+		{"shirt", 1, 100, []int{2, 4, 1}, 3},
+		// An empty query lists the catalog in its own order.
+		{"  ", 2, 2, []int{3, 4}, 5},
+		{"submarine", 1, 10, []int{}, 0},
+	}
+	for _, tt := range tests {
+		hits, total, err := svc.Search(ctx, tt.q, tt.page, tt.limit)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.q, err)
+		}
+		if got := hitIDs(hits); !equalIDs(got, tt.want) || total != tt.total {
+			t.Errorf("Search(%q, page %d, limit %d) = %v of %d, want %v of %d",
+				tt.q, tt.page, tt.limit, got, total, tt.want, tt.total)
+		}
+		if hits == nil {
+			t.Errorf("Search(%q, page %d) returned nil hits, want an empty page", tt.q, tt.page)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	idx := buildIndex([]Product{{ID: 1, Title: "<b>Shirts</b> & ties", Category: "Men's clothing"}})
+	hits := idx.search("shirt")
+	if len(hits) != 1 {
+		t.Fatalf("got %d hits, want 1", len(hits))
+	}
+	if got, want := hits[0].Highlights["title"], "&lt;b&gt;<mark>Shirts</mark>&lt;/b&gt; &amp; ties"; got != want {
+		t.Errorf("title highlight = %q, want %q", got, want)
+	}
+	if _, ok := hits[0].Highlights["category"]; ok {
+		t.Error("highlighted a field without a match")
+	}
+}
+
+func TestSnippetMultibyte(t *testing.T) {
+	filler := strings.Repeat("Crème brûlée façade naïve — ", 10)
+	tests := []struct {
+		name        string
+		description string
+		prefix      bool
+		suffix      bool
+	}{
+		{"match at start", "Jacket " + filler, false, true},
+		{"match in middle", filler + "jacket " + filler, true, true},
+		{"match at end", filler + "jacket", true, false},
+		{"short text", "Écru jacket", false, false},
+	}
+	for _, tt := range tests {
+		idx := buildIndex([]Product{{ID: 1, Title: "Coat", Description: tt.description}})
+		hits := idx.search("jacket")
+		if len(hits) != 1 {
+			t.Fatalf("%s: got %d hits, want 1", tt.name, len(hits))
+		}
+		snippet := hits[0].Highlights["description"]
+		if !utf8.ValidString(snippet) {
+			t.Errorf("%s: snippet is not valid UTF-8: %q", tt.name, snippet)
+		}
+		if !strings.Contains(strings.ToLower(snippet), "<mark>jacket</mark>") {
+			t.Errorf("%s: snippet %q does not mark the match", tt.name, snippet)
+		}
+		if got := strings.HasPrefix(snippet, "…"); got != tt.prefix {
+			t.Errorf("%s: leading ellipsis = %v, want %v: %q", tt.name, got, tt.prefix, snippet)
+		}
+		if got := strings.HasSuffix(snippet, "…"); got != tt.suffix {
+			t.Errorf("%s: trailing ellipsis = %v, want %v: %q", tt.name, got, tt.suffix, snippet)
+		}
+		plain := strings.NewReplacer("<mark>", "", "</mark>", "", "…", "").Replace(snippet)
+		if n := len(plain); n > snippetLen+len("jacket") {
+			t.Errorf("%s: snippet is %d bytes, want at most about %d", tt.name, n, snippetLen)
+		}
+		if strings.HasPrefix(plain, " ") || strings.HasSuffix(plain, " ") {
+			t.Errorf("%s: snippet %q is not cut at word boundaries", tt.name, snippet)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package product
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Rakesh2908/shopgo/pkg/cache"
@@ -12,99 +16,293 @@ import (
 const (
 	cacheTTLProducts   = 5 * time.Minute
 	cacheTTLCategories = 10 * time.Minute
+
+	// Cached entries are refreshed in the background once this fraction of their TTL has passed,
+	// so entries in use are renewed before they expire.
+	refreshAheadFraction = 0.8
+
+	// The last value fetched for a key is kept this long to answer for a failing source.
+	staleTTL = 24 * time.Hour
+
+	// maxConcurrentFetches bounds the source calls GetByIDs makes at once.
+	maxConcurrentFetches = 8
+
+	cacheKeyAllProducts = "products:all"
+	cacheKeyCategories  = "categories"
+	cacheKeySearchIndex = "products:search-index"
 )
 
 // ProductService defines the interface for product operations.
 type ProductService interface {
 	GetAll(ctx context.Context) ([]Product, error)
 	GetByID(ctx context.Context, id int) (*Product, error)
+	GetByIDs(ctx context.Context, ids []int) (map[int]*Product, error)
 	GetCategories(ctx context.Context) ([]string, error)
 	GetByCategory(ctx context.Context, category string) ([]Product, error)
-	Search(ctx context.Context, q string) ([]Product, error)
+	Search(ctx context.Context, q string, page, limit int) ([]SearchHit, int, error)
 }
 
-// productService implements ProductService with an API client and in-memory cache.
+// productService implements ProductService with a catalog source and in-memory cache. Concurrent
+// misses on a key share one fetch from the source, and entries still being read are refreshed
+// ahead of expiry. When the source fails, it serves the last value it fetched for the same key and
+// marks the request stale. Those values are kept in the cache too (see staleCacheKey), so the
+// admin and sync invalidations clear them along with the cached ones.
 type productService struct {
-	client *FakeStoreClient
-	cache  *cache.MemoryCache
+	source  CatalogSource
+	cache   *cache.MemoryCache
+	flights flightGroup
 }
 
-// NewProductService returns a new ProductService.
-func NewProductService(client *FakeStoreClient, c *cache.MemoryCache) ProductService {
-	return &productService{client: client, cache: c}
+// cacheEntry is a value cached by load, with the time after which a read triggers a refresh.
+type cacheEntry struct {
+	value     interface{}
+	refreshAt time.Time
+}
+
+// NewProductService returns a new ProductService reading from source.
+func NewProductService(source CatalogSource, c *cache.MemoryCache) ProductService {
+	return &productService{source: source, cache: c}
 }
 
 // GetAll returns all products, using cache (key products:all, TTL 5 min) on miss.
 func (s *productService) GetAll(ctx context.Context) ([]Product, error) {
-	key := "products:all"
-	if v, ok := s.cache.Get(key); ok {
-		return v.([]Product), nil
-	}
-	products, err := s.client.GetProducts(ctx)
+	v, err := s.load(ctx, cacheKeyAllProducts, cacheTTLProducts, func(ctx context.Context) (interface{}, error) {
+		return s.source.GetProducts(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
-	s.cache.Set(key, products, cacheTTLProducts)
-	return products, nil
+	return v.([]Product), nil
 }
 
 // GetByID returns a product by ID, using cache (key product:{id}, TTL 5 min) on miss.
 func (s *productService) GetByID(ctx context.Context, id int) (*Product, error) {
-	key := fmt.Sprintf("product:%d", id)
-	if v, ok := s.cache.Get(key); ok {
-		return v.(*Product), nil
-	}
-	p, err := s.client.GetProduct(ctx, id)
+	v, err := s.load(ctx, productCacheKey(id), cacheTTLProducts, func(ctx context.Context) (interface{}, error) {
+		return s.source.GetProduct(ctx, id)
+	})
 	if err != nil {
 		return nil, err
 	}
-	s.cache.Set(key, p, cacheTTLProducts)
-	return p, nil
+	return v.(*Product), nil
+}
+
+// GetByIDs returns the products with the given IDs, keyed by ID. Cached products are served
+// directly and the rest are fetched concurrently, at most maxConcurrentFetches at a time, and
+// cached like GetByID. Products that do not exist are left out of the map. Products that fail to
+// load for another reason are left out too, and the failures are returned joined in the error; the
+// map is still valid.
+func (s *productService) GetByIDs(ctx context.Context, ids []int) (map[int]*Product, error) {
+	var (
+		mu   sync.Mutex
+		out  = make(map[int]*Product, len(ids))
+		errs []error
+	)
+	fetch := func(id int) {
+		p, err := s.GetByID(ctx, id)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err == nil:
+			out[id] = p
+		case !errors.Is(err, ErrNotFound):
+			errs = append(errs, fmt.Errorf("product %d: %w", id, err))
+		}
+	}
+
+	var misses []int
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, ok := s.cache.Get(productCacheKey(id)); ok {
+			fetch(id)
+		} else {
+			misses = append(misses, id)
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentFetches)
+	for _, id := range misses {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fetch(id)
+		}()
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
 }
 
 // GetCategories returns all category names, using cache (key categories, TTL 10 min) on miss.
 func (s *productService) GetCategories(ctx context.Context) ([]string, error) {
-	key := "categories"
-	if v, ok := s.cache.Get(key); ok {
-		return v.([]string), nil
-	}
-	categories, err := s.client.GetCategories(ctx)
+	v, err := s.load(ctx, cacheKeyCategories, cacheTTLCategories, func(ctx context.Context) (interface{}, error) {
+		return s.source.GetCategories(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
-	s.cache.Set(key, categories, cacheTTLCategories)
-	return categories, nil
+	return v.([]string), nil
 }
 
 // GetByCategory returns products in the given category, using cache (key products:cat:{cat}, TTL 5 min) on miss.
 func (s *productService) GetByCategory(ctx context.Context, category string) ([]Product, error) {
-	key := "products:cat:" + category
+	v, err := s.load(ctx, categoryCacheKey(category), cacheTTLProducts, func(ctx context.Context) (interface{}, error) {
+		return s.source.GetProductsByCategory(ctx, category)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]Product), nil
+}
+
+// load returns the cached value for key, or fetches it and caches it for ttl. Only one fetch per
+// key runs at a time; concurrent callers wait for its result. A read in the last part of the TTL
+// starts a background refresh and returns the cached value straight away.
+//
+// If the fetch fails for any reason other than ErrNotFound and a fetch of key succeeded within
+// staleTTL, that earlier value is returned instead and the request is marked stale (see
+// WithStaleFlag).
+func (s *productService) load(ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (interface{}, error)) (interface{}, error) {
 	if v, ok := s.cache.Get(key); ok {
-		return v.([]Product), nil
+		e := v.(*cacheEntry)
+		if time.Now().After(e.refreshAt) {
+			s.flights.start(key, s.fetcher(ctx, key, ttl, fetch))
+		}
+		return e.value, nil
 	}
-	products, err := s.client.GetProductsByCategory(ctx, category)
-	if err != nil {
+	v, err := s.flights.do(ctx, key, s.fetcher(ctx, key, ttl, fetch))
+	if err == nil {
+		return v, nil
+	}
+	if errors.Is(err, ErrNotFound) || ctx.Err() != nil {
 		return nil, err
 	}
-	s.cache.Set(key, products, cacheTTLProducts)
-	return products, nil
+	if last, ok := s.cache.Get(staleCacheKey(key)); ok {
+		log.Printf("product: serving stale %s: %v", key, err)
+		markStale(ctx)
+		return last, nil
+	}
+	return nil, err
+}
+
+// fetcher returns the shared fetch of key run by load: it calls fetch and caches the result. The
+// fetch is not cancelled with ctx, since other callers may be waiting for it.
+func (s *productService) fetcher(ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (interface{}, error)) func() (interface{}, error) {
+	ctx = context.WithoutCancel(ctx)
+	return func() (interface{}, error) {
+		v, err := fetch(ctx)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				s.cache.Delete(staleCacheKey(key))
+			}
+			return nil, err
+		}
+		refreshAt := time.Now().Add(time.Duration(float64(ttl) * refreshAheadFraction))
+		s.cache.Set(key, &cacheEntry{value: v, refreshAt: refreshAt}, ttl)
+		if products, ok := v.([]Product); ok && len(products) == 0 {
+			// Unknown categories come back empty; keeping their lists would let any client grow
+			// the cache, and an empty list is not worth serving in place of an error.
+			s.cache.Delete(staleCacheKey(key))
+		} else {
+			s.cache.Set(staleCacheKey(key), v, staleTTL)
+		}
+		if key == cacheKeyAllProducts {
+			// The search index was built from the previous list.
+			s.cache.Delete(cacheKeySearchIndex)
+		}
+		return v, nil
+	}
 }
 
-// Search returns products whose title contains q (case-insensitive), using cached all products.
-func (s *productService) Search(ctx context.Context, q string) ([]Product, error) {
-	products, err := s.GetAll(ctx)
+// Search returns a page of the products matching q, most relevant first, and the total number of
+// matches. Title, category and description are searched; see searchIndex. An empty query matches
+// every product, in catalog order.
+func (s *productService) Search(ctx context.Context, q string, page, limit int) ([]SearchHit, int, error) {
+	idx, err := s.searchIndex(ctx)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	q = strings.ToLower(strings.TrimSpace(q))
-	if q == "" {
-		return products, nil
+	var hits []SearchHit
+	if strings.TrimSpace(q) == "" {
+		hits = make([]SearchHit, len(idx.products))
+		for i, p := range idx.products {
+			hits[i] = SearchHit{Product: p}
+		}
+	} else {
+		hits = idx.search(q)
+	}
+	total := len(hits)
+	start := (page - 1) * limit
+	if start >= total {
+		return []SearchHit{}, total, nil
 	}
-	var out []Product
-	for _, p := range products {
-		if strings.Contains(strings.ToLower(p.Title), q) {
-			out = append(out, p)
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	return hits[start:end], total, nil
+}
+
+// searchIndex returns the search index over all products, using cache (key products:search-index,
+// TTL 5 min) on miss. GetAll drops the cached index whenever it refetches the products.
+func (s *productService) searchIndex(ctx context.Context) (*searchIndex, error) {
+	v, err := s.load(ctx, cacheKeySearchIndex, cacheTTLProducts, func(ctx context.Context) (interface{}, error) {
+		products, err := s.GetAll(ctx)
+		if err != nil {
+			return nil, err
 		}
+		return buildIndex(products), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*searchIndex), nil
+}
+
+// staleFlagKey is the context key of the flag set by markStale.
+type staleFlagKey struct{}
+
+// WithStaleFlag returns a context whose catalog reads record whether any of them was answered
+// with the last known good data because the catalog source failed. The returned flag is set once
+// that happens.
+func WithStaleFlag(ctx context.Context) (context.Context, *atomic.Bool) {
+	flag := new(atomic.Bool)
+	return context.WithValue(ctx, staleFlagKey{}, flag), flag
+}
+
+// markStale sets the stale flag of ctx, if it has one.
+func markStale(ctx context.Context) {
+	if flag, ok := ctx.Value(staleFlagKey{}).(*atomic.Bool); ok {
+		flag.Store(true)
+	}
+}
+
+// productCacheKey returns the cache key for a single product.
+func productCacheKey(id int) string {
+	return fmt.Sprintf("product:%d", id)
+}
+
+// categoryCacheKey returns the cache key for the products in a category.
+func categoryCacheKey(category string) string {
+	return "products:cat:" + category
+}
+
+// staleCacheKey returns the cache key of the last value fetched for key, served while the source
+// fails.
+func staleCacheKey(key string) string {
+	return "stale:" + key
+}
+
+// invalidateCache removes keys from c along with their stale values, so a changed entry is not
+// served again when the source next fails.
+func invalidateCache(c *cache.MemoryCache, keys ...string) {
+	for _, key := range keys {
+		c.Delete(key)
+		c.Delete(staleCacheKey(key))
 	}
-	return out, nil
 }
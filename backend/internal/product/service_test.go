@@ -0,0 +1,220 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/pkg/cache"
+)
+
+// fakeSource serves products from memory and counts the calls made to it. While err is set every
+// call fails with it; productErrs fails single product lookups. Single product lookups take delay,
+// and the most that ran at once is kept in maxInflight.
+type fakeSource struct {
+	mu          sync.Mutex
+	products    []Product
+	err         error
+	productErrs map[int]error
+	calls       map[string]int
+	delay       time.Duration
+	inflight    int
+	maxInflight int
+}
+
+func newFakeSource(products ...Product) *fakeSource {
+	return &fakeSource{products: products, calls: make(map[string]int)}
+}
+
+func (s *fakeSource) call(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[name]++
+	return s.err
+}
+
+func (s *fakeSource) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *fakeSource) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[name]
+}
+
+func (s *fakeSource) GetProducts(ctx context.Context) ([]Product, error) {
+	if err := s.call("GetProducts"); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Product(nil), s.products...), nil
+}
+
+func (s *fakeSource) GetProduct(ctx context.Context, id int) (*Product, error) {
+	if err := s.call("GetProduct"); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.inflight++
+	s.maxInflight = max(s.maxInflight, s.inflight)
+	s.mu.Unlock()
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inflight--
+	if err := s.productErrs[id]; err != nil {
+		return nil, err
+	}
+	for _, p := range s.products {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *fakeSource) GetCategories(ctx context.Context) ([]string, error) {
+	if err := s.call("GetCategories"); err != nil {
+		return nil, err
+	}
+	return []string{"books"}, nil
+}
+
+func (s *fakeSource) GetProductsByCategory(ctx context.Context, category string) ([]Product, error) {
+	if err := s.call("GetProductsByCategory"); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []Product{}
+	for _, p := range s.products {
+		if p.Category == category {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func newTestService(t *testing.T, source CatalogSource) (*productService, *cache.MemoryCache) {
+	t.Helper()
+	c := cache.NewMemoryCache(time.Minute)
+	t.Cleanup(c.Stop)
+	return NewProductService(source, c).(*productService), c
+}
+
+func TestServeStaleOnSourceFailure(t *testing.T) {
+	source := newFakeSource(Product{ID: 1, Title: "Go", Category: "books"})
+	svc, c := newTestService(t, source)
+	ctx := context.Background()
+
+	if _, err := svc.GetByCategory(ctx, "books"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if products, err := svc.GetByCategory(ctx, "no-such-category"); err != nil || len(products) != 0 {
+		t.Fatalf("unknown category: %v, %v; want an empty list", products, err)
+	}
+
+	// The cached entries expire while the source is down.
+	c.Delete(categoryCacheKey("books"))
+	c.Delete(categoryCacheKey("no-such-category"))
+	c.Delete(productCacheKey(1))
+	source.setErr(errors.New("upstream down"))
+
+	staleCtx, stale := WithStaleFlag(ctx)
+	products, err := svc.GetByCategory(staleCtx, "books")
+	if err != nil || len(products) != 1 || products[0].ID != 1 {
+		t.Fatalf("GetByCategory = %v, %v; want the last known list", products, err)
+	}
+	if !stale.Load() {
+		t.Fatal("stale answer not flagged")
+	}
+	if _, err := svc.GetByCategory(ctx, "no-such-category"); err == nil {
+		t.Fatal("served a stale empty list for an unknown category")
+	}
+	if _, ok := c.Get(staleCacheKey(categoryCacheKey("no-such-category"))); ok {
+		t.Fatal("kept a stale copy of an unknown category")
+	}
+
+	// Invalidating a product, e.g. after an admin deleted it, drops its stale copy too.
+	invalidateCache(c, productCacheKey(1))
+	if _, err := svc.GetByID(ctx, 1); err == nil {
+		t.Fatal("served an invalidated product as stale")
+	}
+}
+
+func TestNotFoundDropsStaleCopy(t *testing.T) {
+	source := newFakeSource(Product{ID: 1, Category: "books"})
+	svc, c := newTestService(t, source)
+	ctx := context.Background()
+
+	if _, err := svc.GetByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	source.mu.Lock()
+	source.products = nil
+	source.mu.Unlock()
+	c.Delete(productCacheKey(1))
+	if _, err := svc.GetByID(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+
+	source.setErr(errors.New("upstream down"))
+	if _, err := svc.GetByID(ctx, 1); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want the source failure", err)
+	}
+}
+
+func TestGetByIDsFetchesMissesConcurrently(t *testing.T) {
+	var products []Product
+	for id := 1; id <= 3*maxConcurrentFetches; id++ {
+		products = append(products, Product{ID: id})
+	}
+	source := newFakeSource(products...)
+	source.delay = 5 * time.Millisecond
+	source.productErrs = map[int]error{7: errors.New("upstream down")}
+	svc, _ := newTestService(t, source)
+	ctx := context.Background()
+
+	ids := []int{404, 1, 1}
+	for id := 2; id <= len(products); id++ {
+		ids = append(ids, id)
+	}
+	got, err := svc.GetByIDs(ctx, ids)
+	if err == nil || !strings.Contains(err.Error(), "product 7") {
+		t.Fatalf("err = %v, want the failure of product 7", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Fatal("a missing product was reported as an error")
+	}
+	if len(got) != len(products)-1 || got[7] != nil || got[404] != nil {
+		t.Fatalf("got %d products, want all but 7 and the unknown ID", len(got))
+	}
+	if n := source.count("GetProducts"); n != 0 {
+		t.Fatalf("loaded the whole catalog %d times", n)
+	}
+	if n := source.count("GetProduct"); n != len(products)+1 {
+		t.Fatalf("%d product lookups, want one per distinct ID (%d)", n, len(products)+1)
+	}
+	if source.maxInflight > maxConcurrentFetches || source.maxInflight < 2 {
+		t.Fatalf("%d lookups ran at once, want between 2 and %d", source.maxInflight, maxConcurrentFetches)
+	}
+
+	// Everything found is now cached per product.
+	source.productErrs = nil
+	if _, err := svc.GetByIDs(ctx, ids[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if n := source.count("GetProduct"); n != len(products)+2 {
+		t.Fatalf("%d product lookups after the second call, want only product 7 fetched again", n)
+	}
+}
@@ -0,0 +1,51 @@
+package product
+
+import (
+	"context"
+	"errors"
+)
+
+// Catalog source drivers accepted by NewCatalogSource.
+const (
+	SourceFakeStore = "fakestore"
+	SourceFile      = "file"
+	SourceDatabase  = "database"
+	SourceMirror    = "mirror"
+)
+
+// Errors returned by catalog sources and the back-office catalog service.
+var (
+	ErrNotFound         = errors.New("product: not found")
+	ErrCategoryNotFound = errors.New("product: category not found")
+	ErrCategoryExists   = errors.New("product: category already exists")
+	ErrCategoryNotEmpty = errors.New("product: category still has products")
+	ErrBlankTitle       = errors.New("product: title is blank")
+)
+
+// CatalogSource is where the product catalog comes from. productService caches on top of it.
+type CatalogSource interface {
+	GetProducts(ctx context.Context) ([]Product, error)
+	GetProduct(ctx context.Context, id int) (*Product, error)
+	GetCategories(ctx context.Context) ([]string, error)
+	GetProductsByCategory(ctx context.Context, category string) ([]Product, error)
+}
+
+var (
+	_ CatalogSource = (*FakeStoreClient)(nil)
+	_ CatalogSource = (*FileSource)(nil)
+	_ CatalogSource = (*DBSource)(nil)
+)
+
+// NewCatalogSource returns the CatalogSource for the given driver: "database" serves the products
+// table through repo, "mirror" does the same with the table kept in sync with the FakeStore API
+// by a SyncService, "file" reads products from the JSON file at path, and anything else uses the
+// FakeStore API at baseURL.
+func NewCatalogSource(driver, baseURL, path string, repo Repository) CatalogSource {
+	switch driver {
+	case SourceDatabase, SourceMirror:
+		return NewDBSource(repo)
+	case SourceFile:
+		return NewFileSource(path)
+	}
+	return NewClient(baseURL)
+}
@@ -0,0 +1,227 @@
+package product
+
+import "sort"
+
+// stem reduces an English word to its stem with the Porter algorithm, so "shirts", "shirt" and
+// "shirting" all index as "shirt". The word must be lower case; words that are not plain ASCII
+// letters, and words of two letters or fewer, are returned unchanged.
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	w := &stemmer{b: []byte(word)}
+	w.step1a()
+	w.step1b()
+	w.step1c()
+	w.replaceSuffixes(step2Suffixes, 0)
+	w.replaceSuffixes(step3Suffixes, 0)
+	w.step4()
+	w.step5()
+	return string(w.b)
+}
+
+// stemmer holds the word being stemmed.
+type stemmer struct {
+	b []byte
+}
+
+// suffixRule replaces suffix with replacement.
+type suffixRule struct {
+	suffix, replacement string
+}
+
+var (
+	step2Suffixes = longestFirst([]suffixRule{
+		{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+		{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"},
+		{"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"},
+		{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"},
+		{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}, {"logi", "log"},
+	})
+	step3Suffixes = longestFirst([]suffixRule{
+		{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+		{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+	})
+	step4Suffixes = longestFirst([]suffixRule{
+		{"al", ""}, {"ance", ""}, {"ence", ""}, {"er", ""}, {"ic", ""}, {"able", ""},
+		{"ible", ""}, {"ant", ""}, {"ement", ""}, {"ment", ""}, {"ent", ""}, {"ion", ""},
+		{"ou", ""}, {"ism", ""}, {"ate", ""}, {"iti", ""}, {"ous", ""}, {"ive", ""}, {"ize", ""},
+	})
+)
+
+// longestFirst orders rules so the longest matching suffix is always tried first.
+func longestFirst(rules []suffixRule) []suffixRule {
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].suffix) > len(rules[j].suffix) })
+	return rules
+}
+
+// isConsonant reports whether b[i] is a consonant. Y is a consonant unless it follows one.
+func (w *stemmer) isConsonant(i int) bool {
+	switch w.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !w.isConsonant(i-1)
+	}
+	return true
+}
+
+// measure returns the number of vowel-consonant sequences in b[:n].
+func (w *stemmer) measure(n int) int {
+	m := 0
+	i := 0
+	for i < n && w.isConsonant(i) {
+		i++
+	}
+	for i < n {
+		for i < n && !w.isConsonant(i) {
+			i++
+		}
+		if i >= n {
+			break
+		}
+		for i < n && w.isConsonant(i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+// hasVowel reports whether b[:n] contains a vowel.
+func (w *stemmer) hasVowel(n int) bool {
+	for i := 0; i < n; i++ {
+		if !w.isConsonant(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsDoubleConsonant reports whether b[:n] ends in two equal consonants.
+func (w *stemmer) endsDoubleConsonant(n int) bool {
+	return n >= 2 && w.b[n-1] == w.b[n-2] && w.isConsonant(n-1)
+}
+
+// endsCVC reports whether b[:n] ends consonant-vowel-consonant with the last not w, x or y,
+// as in "hop" but not "snow".
+func (w *stemmer) endsCVC(n int) bool {
+	if n < 3 || !w.isConsonant(n-1) || w.isConsonant(n-2) || !w.isConsonant(n-3) {
+		return false
+	}
+	switch w.b[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// hasSuffix reports whether the word ends with s.
+func (w *stemmer) hasSuffix(s string) bool {
+	return len(w.b) >= len(s) && string(w.b[len(w.b)-len(s):]) == s
+}
+
+// setSuffix replaces the last n bytes with s.
+func (w *stemmer) setSuffix(n int, s string) {
+	w.b = append(w.b[:len(w.b)-n], s...)
+}
+
+// step1a removes plurals: caresses -> caress, ponies -> poni, cats -> cat.
+func (w *stemmer) step1a() {
+	switch {
+	case w.hasSuffix("sses"):
+		w.setSuffix(4, "ss")
+	case w.hasSuffix("ies"):
+		w.setSuffix(3, "i")
+	case w.hasSuffix("ss"):
+	case w.hasSuffix("s"):
+		w.setSuffix(1, "")
+	}
+}
+
+// step1b removes -ed and -ing: agreed -> agree, hopping -> hop, filing -> file.
+func (w *stemmer) step1b() {
+	if w.hasSuffix("eed") {
+		if w.measure(len(w.b)-3) > 0 {
+			w.setSuffix(1, "")
+		}
+		return
+	}
+	var n int
+	switch {
+	case w.hasSuffix("ed") && w.hasVowel(len(w.b)-2):
+		n = 2
+	case w.hasSuffix("ing") && w.hasVowel(len(w.b)-3):
+		n = 3
+	default:
+		return
+	}
+	w.setSuffix(n, "")
+	switch {
+	case w.hasSuffix("at"), w.hasSuffix("bl"), w.hasSuffix("iz"):
+		w.b = append(w.b, 'e')
+	case w.endsDoubleConsonant(len(w.b)):
+		switch w.b[len(w.b)-1] {
+		case 'l', 's', 'z':
+		default:
+			w.setSuffix(1, "")
+		}
+	case w.measure(len(w.b)) == 1 && w.endsCVC(len(w.b)):
+		w.b = append(w.b, 'e')
+	}
+}
+
+// step1c turns a final y into i when the stem has a vowel: happy -> happi.
+func (w *stemmer) step1c() {
+	if w.hasSuffix("y") && w.hasVowel(len(w.b)-1) {
+		w.b[len(w.b)-1] = 'i'
+	}
+}
+
+// replaceSuffixes applies the first rule whose suffix matches, if the remaining stem has a
+// measure greater than minMeasure.
+func (w *stemmer) replaceSuffixes(rules []suffixRule, minMeasure int) {
+	for _, r := range rules {
+		if w.hasSuffix(r.suffix) {
+			if w.measure(len(w.b)-len(r.suffix)) > minMeasure {
+				w.setSuffix(len(r.suffix), r.replacement)
+			}
+			return
+		}
+	}
+}
+
+// step4 removes remaining derivational suffixes from long stems: adjustment -> adjust.
+func (w *stemmer) step4() {
+	for _, r := range step4Suffixes {
+		if !w.hasSuffix(r.suffix) {
+			continue
+		}
+		n := len(w.b) - len(r.suffix)
+		if r.suffix == "ion" && (n == 0 || (w.b[n-1] != 's' && w.b[n-1] != 't')) {
+			return
+		}
+		if w.measure(n) > 1 {
+			w.setSuffix(len(r.suffix), "")
+		}
+		return
+	}
+}
+
+// step5 removes a final e and reduces a final ll on long stems: probate -> probat, controll -> control.
+func (w *stemmer) step5() {
+	if w.hasSuffix("e") {
+		n := len(w.b) - 1
+		if m := w.measure(n); m > 1 || (m == 1 && !w.endsCVC(n)) {
+			w.b = w.b[:n]
+		}
+	}
+	if w.hasSuffix("ll") && w.measure(len(w.b)) > 1 {
+		w.setSuffix(1, "")
+	}
+}
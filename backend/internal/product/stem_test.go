@@ -0,0 +1,48 @@
+package product
+
+import "testing"
+
+// Expected stems are from the reference vocabulary of Martin Porter's original implementation.
+func TestStem(t *testing.T) {
+	tests := []struct{ word, want string }{
+		// Step 1a: plurals.
+		{"caresses", "caress"}, {"ponies", "poni"}, {"ties", "ti"}, {"caress", "caress"}, {"cats", "cat"},
+		// Step 1b: -ed and -ing, with the clean-ups that follow them.
+		{"feed", "feed"}, {"agreed", "agre"}, {"plastered", "plaster"}, {"bled", "bled"},
+		{"motoring", "motor"}, {"sing", "sing"}, {"conflated", "conflat"}, {"troubled", "troubl"},
+		{"sized", "size"}, {"hopping", "hop"}, {"tanned", "tan"}, {"falling", "fall"},
+		{"hissing", "hiss"}, {"fizzed", "fizz"}, {"failing", "fail"}, {"filing", "file"},
+		// Step 1c: y to i.
+		{"happy", "happi"}, {"sky", "sky"},
+		// Step 2: double suffixes.
+		{"relational", "relat"}, {"conditional", "condit"}, {"rational", "ration"},
+		{"valenci", "valenc"}, {"hesitanci", "hesit"}, {"digitizer", "digit"},
+		{"conformabli", "conform"}, {"radicalli", "radic"}, {"differentli", "differ"},
+		{"vileli", "vile"}, {"analogousli", "analog"}, {"vietnamization", "vietnam"},
+		{"predication", "predic"}, {"operator", "oper"}, {"feudalism", "feudal"},
+		{"decisiveness", "decis"}, {"hopefulness", "hope"}, {"callousness", "callous"},
+		{"formaliti", "formal"}, {"sensitiviti", "sensit"}, {"sensibiliti", "sensibl"},
+		// Step 3.
+		{"triplicate", "triplic"}, {"formative", "form"}, {"formalize", "formal"},
+		{"electriciti", "electr"}, {"electrical", "electr"}, {"hopeful", "hope"}, {"goodness", "good"},
+		// Step 4.
+		{"revival", "reviv"}, {"allowance", "allow"}, {"inference", "infer"}, {"airliner", "airlin"},
+		{"gyroscopic", "gyroscop"}, {"adjustable", "adjust"}, {"defensible", "defens"},
+		{"irritant", "irrit"}, {"replacement", "replac"}, {"adjustment", "adjust"},
+		{"dependent", "depend"}, {"adoption", "adopt"}, {"homologou", "homolog"},
+		{"communism", "commun"}, {"activate", "activ"}, {"angulariti", "angular"},
+		{"homologous", "homolog"}, {"effective", "effect"}, {"bowdlerize", "bowdler"},
+		// Step 5.
+		{"probate", "probat"}, {"rate", "rate"}, {"cease", "ceas"}, {"controll", "control"}, {"roll", "roll"},
+		// Whole words through several steps.
+		{"generalizations", "gener"}, {"oscillators", "oscil"}, {"connections", "connect"},
+		{"shirts", "shirt"}, {"shirting", "shirt"},
+		// Left alone: short words and anything that is not plain ASCII letters.
+		{"is", "is"}, {"café", "café"}, {"4k", "4k"},
+	}
+	for _, tt := range tests {
+		if got := stem(tt.word); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Rakesh2908/shopgo/pkg/cache"
+)
+
+const defaultSyncInterval = 15 * time.Minute
+
+// Errors returned by the catalog sync.
+var (
+	// ErrSyncRunning is returned when a sync is started while another is still running.
+	ErrSyncRunning = errors.New("product: catalog sync already running")
+	// ErrEmptyUpstream is returned instead of archiving the whole mirror when the upstream
+	// returns no products.
+	ErrEmptyUpstream = errors.New("product: upstream returned an empty catalog")
+)
+
+// SyncService mirrors an upstream catalog, normally the FakeStore API, into the products and
+// categories tables, which the "mirror" catalog source serves. Each run is logged, and price
+// changes are recorded so other features can react to them.
+type SyncService interface {
+	Sync(ctx context.Context) (*CatalogSync, error)
+	Run(ctx context.Context)
+	ListSyncs(ctx context.Context, page, limit int) ([]CatalogSync, int64, error)
+	ListPriceChanges(ctx context.Context, productID, page, limit int) ([]PriceChange, int64, error)
+}
+
+// syncService implements SyncService.
+type syncService struct {
+	upstream CatalogSource
+	repo     Repository
+	cache    *cache.MemoryCache
+	interval time.Duration
+	running  sync.Mutex
+}
+
+// NewSyncService returns a new SyncService copying upstream into repo every interval (default
+// 15m). c must be the cache used by the ProductService, so synced changes show up straight away.
+func NewSyncService(upstream CatalogSource, repo Repository, c *cache.MemoryCache, interval time.Duration) SyncService {
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+	return &syncService{upstream: upstream, repo: repo, cache: c, interval: interval}
+}
+
+// Run syncs straight away and then every interval until ctx is done. Failed runs are logged and
+// retried on the next tick.
+func (s *syncService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if run, err := s.Sync(ctx); err != nil {
+			log.Printf("product: catalog sync: %v", err)
+		} else {
+			log.Printf("product: catalog sync: %d added, %d updated, %d removed, %d price changes",
+				run.Added, run.Updated, run.Removed, run.PriceChanges)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync fetches the upstream catalog and brings the mirror in line with it: new products are
+// added, changed ones updated, and ones no longer upstream archived so past orders still make
+// sense. Products archived earlier come back if they reappear upstream. The run is recorded
+// whether it succeeds or not.
+func (s *syncService) Sync(ctx context.Context) (*CatalogSync, error) {
+	if !s.running.TryLock() {
+		return nil, ErrSyncRunning
+	}
+	defer s.running.Unlock()
+
+	run := &CatalogSync{Status: SyncRunning, StartedAt: time.Now()}
+	if err := s.repo.CreateSync(ctx, run); err != nil {
+		return nil, err
+	}
+	changes, err := s.diff(ctx, run)
+	if err == nil {
+		err = s.repo.ApplySync(ctx, changes)
+	}
+	finished := time.Now()
+	run.FinishedAt = &finished
+	if err != nil {
+		// Nothing was applied.
+		run.Status, run.Error = SyncFailed, err.Error()
+		run.Added, run.Updated, run.Removed, run.Unchanged, run.PriceChanges = 0, 0, 0, 0, 0
+	} else {
+		run.Status = SyncSucceeded
+		s.invalidate(changes)
+	}
+	// Record the outcome even if ctx was cancelled part way through.
+	if uerr := s.repo.UpdateSync(context.WithoutCancel(ctx), run); uerr != nil {
+		log.Printf("product: record catalog sync %s: %v", run.ID, uerr)
+	}
+	return run, err
+}
+
+// diff compares the upstream catalog with the mirror, filling in run's counts.
+func (s *syncService) diff(ctx context.Context, run *CatalogSync) (SyncChanges, error) {
+	upstream, err := s.upstream.GetProducts(ctx)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+	categories, err := s.upstream.GetCategories(ctx)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+	existing, err := s.repo.ListAll(ctx)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+	if len(upstream) == 0 && len(existing) > 0 {
+		return SyncChanges{}, ErrEmptyUpstream
+	}
+
+	changes := SyncChanges{At: run.StartedAt}
+	stored := make(map[int]*ProductRecord, len(existing))
+	for i := range existing {
+		stored[existing[i].ID] = &existing[i]
+	}
+	seenCategories := make(map[string]bool)
+	for _, c := range categories {
+		seenCategories[c] = true
+	}
+	seen := make(map[int]bool, len(upstream))
+	for i := range upstream {
+		p := &upstream[i]
+		if seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		seenCategories[p.Category] = true
+		r := recordFromProduct(p, run.StartedAt)
+		old, ok := stored[p.ID]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, r)
+		case old.ArchivedAt != nil || !sameContent(old, &r):
+			changes.Updated = append(changes.Updated, r)
+			if old.PriceCents != r.PriceCents {
+				changes.PriceChanges = append(changes.PriceChanges, PriceChange{
+					ProductID:     p.ID,
+					SyncID:        run.ID,
+					OldPriceCents: old.PriceCents,
+					NewPriceCents: r.PriceCents,
+					ChangedAt:     run.StartedAt,
+				})
+			}
+		default:
+			run.Unchanged++
+		}
+	}
+	for _, r := range existing {
+		if !seen[r.ID] && r.ArchivedAt == nil {
+			changes.Removed = append(changes.Removed, r.ID)
+		}
+	}
+	for c := range seenCategories {
+		changes.Categories = append(changes.Categories, c)
+	}
+	sort.Strings(changes.Categories)
+
+	run.Added = len(changes.Added)
+	run.Updated = len(changes.Updated)
+	run.Removed = len(changes.Removed)
+	run.PriceChanges = len(changes.PriceChanges)
+	return changes, nil
+}
+
+// invalidate clears the cached catalog entries a sync changed.
+func (s *syncService) invalidate(changes SyncChanges) {
+	if len(changes.Added)+len(changes.Updated)+len(changes.Removed) == 0 {
+		return
+	}
+	invalidateCache(s.cache, cacheKeyAllProducts, cacheKeyCategories, cacheKeySearchIndex)
+	for _, c := range changes.Categories {
+		invalidateCache(s.cache, categoryCacheKey(c))
+	}
+	for _, r := range changes.Added {
+		invalidateCache(s.cache, productCacheKey(r.ID))
+	}
+	for _, r := range changes.Updated {
+		invalidateCache(s.cache, productCacheKey(r.ID))
+	}
+	for _, id := range changes.Removed {
+		invalidateCache(s.cache, productCacheKey(id))
+	}
+}
+
+// ListSyncs returns a page of sync runs, newest first, and the total count.
+func (s *syncService) ListSyncs(ctx context.Context, page, limit int) ([]CatalogSync, int64, error) {
+	return s.repo.ListSyncs(ctx, page, limit)
+}
+
+// ListPriceChanges returns a page of recorded price changes, newest first, and the total count.
+// A productID of 0 lists changes to every product.
+func (s *syncService) ListPriceChanges(ctx context.Context, productID, page, limit int) ([]PriceChange, int64, error) {
+	return s.repo.ListPriceChanges(ctx, productID, page, limit)
+}
+
+// recordFromProduct converts an upstream product to a stored record.
+func recordFromProduct(p *Product, now time.Time) ProductRecord {
+	return ProductRecord{
+		ID:          p.ID,
+		Title:       p.Title,
+		Description: p.Description,
+		Category:    p.Category,
+		Image:       p.Image,
+		PriceCents:  priceToCents(p.Price),
+		RatingRate:  p.Rating.Rate,
+		RatingCount: p.Rating.Count,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// sameContent reports whether two records have the same upstream fields.
+func sameContent(a, b *ProductRecord) bool {
+	return a.Title == b.Title &&
+		a.Description == b.Description &&
+		a.Category == b.Category &&
+		a.Image == b.Image &&
+		a.PriceCents == b.PriceCents &&
+		a.RatingRate == b.RatingRate &&
+		a.RatingCount == b.RatingCount
+}
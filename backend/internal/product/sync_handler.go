@@ -0,0 +1,80 @@
+package product
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Rakesh2908/shopgo/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSyncRoutes registers the catalog sync routes on the given router group, which must
+// already require a staff or admin access token. Group path should be "/admin/catalog" so routes
+// are GET/POST /admin/catalog/syncs and GET /admin/catalog/price-changes.
+func RegisterSyncRoutes(rg *gin.RouterGroup, svc SyncService) {
+	rg.GET("/syncs", handleListSyncs(svc))
+	rg.POST("/syncs", handleRunSync(svc))
+	rg.GET("/price-changes", handleListPriceChanges(svc))
+}
+
+// handleListSyncs handles GET /admin/catalog/syncs?page=&limit= — the sync log, newest first.
+func handleListSyncs(svc SyncService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit := adminPageParams(c)
+		syncs, total, err := svc.ListSyncs(c.Request.Context(), page, limit)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list catalog syncs")
+			return
+		}
+		response.SuccessWithMeta(c, http.StatusOK, syncs, gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// handleRunSync handles POST /admin/catalog/syncs — runs a sync now instead of waiting for the
+// next scheduled one. A failed sync is still returned, with its error, as 502.
+func handleRunSync(svc SyncService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		run, err := svc.Sync(c.Request.Context())
+		switch {
+		case errors.Is(err, ErrSyncRunning):
+			response.Error(c, http.StatusConflict, "SYNC_RUNNING", "a catalog sync is already running")
+		case run == nil:
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start catalog sync")
+		case err != nil:
+			response.Error(c, http.StatusBadGateway, "SYNC_FAILED", run.Error)
+		default:
+			response.Success(c, http.StatusOK, run)
+		}
+	}
+}
+
+// handleListPriceChanges handles GET /admin/catalog/price-changes?productId=&page=&limit=
+func handleListPriceChanges(svc SyncService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var productID int
+		if v := c.Query("productId"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil || id < 1 {
+				response.Error(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid productId")
+				return
+			}
+			productID = id
+		}
+		page, limit := adminPageParams(c)
+		changes, total, err := svc.ListPriceChanges(c.Request.Context(), productID, page, limit)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list price changes")
+			return
+		}
+		response.SuccessWithMeta(c, http.StatusOK, changes, gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeRepository mirrors products in memory for the catalog sync and records the sync runs.
+// Other Repository methods panic through the nil embedded interface.
+type fakeRepository struct {
+	Repository
+
+	mu      sync.Mutex
+	records map[int]ProductRecord
+	applied []SyncChanges
+	syncs   map[uuid.UUID]CatalogSync
+}
+
+func newFakeRepository(records ...ProductRecord) *fakeRepository {
+	r := &fakeRepository{records: make(map[int]ProductRecord), syncs: make(map[uuid.UUID]CatalogSync)}
+	for _, rec := range records {
+		r.records[rec.ID] = rec
+	}
+	return r
+}
+
+func (r *fakeRepository) ListAll(ctx context.Context) ([]ProductRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]ProductRecord, 0, len(r.records))
+	for _, rec := range r.records {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (r *fakeRepository) ApplySync(ctx context.Context, changes SyncChanges) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applied = append(r.applied, changes)
+	for _, rec := range changes.Added {
+		r.records[rec.ID] = rec
+	}
+	for _, rec := range changes.Updated {
+		r.records[rec.ID] = rec
+	}
+	for _, id := range changes.Removed {
+		rec := r.records[id]
+		at := changes.At
+		rec.ArchivedAt = &at
+		r.records[id] = rec
+	}
+	return nil
+}
+
+func (r *fakeRepository) CreateSync(ctx context.Context, s *CatalogSync) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.ID = uuid.New()
+	r.syncs[s.ID] = *s
+	return nil
+}
+
+func (r *fakeRepository) UpdateSync(ctx context.Context, s *CatalogSync) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.syncs[s.ID] = *s
+	return nil
+}
+
+func mirrored(id int, title string, price float64) ProductRecord {
+	return ProductRecord{ID: id, Title: title, Category: "books", PriceCents: priceToCents(price)}
+}
+
+func upstreamProduct(id int, title string, price float64) Product {
+	return Product{ID: id, Title: title, Category: "books", Price: price}
+}
+
+func TestSyncDiff(t *testing.T) {
+	archived := time.Now().Add(-time.Hour)
+	returning := mirrored(4, "Returning", 5)
+	returning.ArchivedAt = &archived
+	repo := newFakeRepository(
+		mirrored(1, "Unchanged", 10),
+		mirrored(2, "Repriced", 20),
+		mirrored(3, "Dropped", 30),
+		returning,
+	)
+	source := newFakeSource(
+		upstreamProduct(1, "Unchanged", 10),
+		upstreamProduct(2, "Repriced", 18.5),
+		upstreamProduct(4, "Returning", 5),
+		upstreamProduct(5, "New", 12),
+		upstreamProduct(5, "New again", 13),
+	)
+	svc, _ := newTestService(t, source)
+	syncer := NewSyncService(source, repo, svc.cache, time.Minute)
+
+	run, err := syncer.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != SyncSucceeded || run.Added != 1 || run.Updated != 2 || run.Removed != 1 || run.Unchanged != 1 || run.PriceChanges != 1 {
+		t.Fatalf("run = %+v", run)
+	}
+	if stored := repo.syncs[run.ID]; stored.Status != SyncSucceeded || stored.FinishedAt == nil {
+		t.Errorf("recorded run = %+v, want it finished", stored)
+	}
+	changes := repo.applied[0]
+	pc := changes.PriceChanges[0]
+	if pc.ProductID != 2 || pc.OldPriceCents != 2000 || pc.NewPriceCents != 1850 || pc.SyncID != run.ID {
+		t.Errorf("price change = %+v", pc)
+	}
+	if changes.Added[0].Title != "New" {
+		t.Errorf("added %q, want the first upstream copy of a duplicated ID", changes.Added[0].Title)
+	}
+	if repo.records[3].ArchivedAt == nil || repo.records[4].ArchivedAt != nil {
+		t.Error("the dropped product should be archived and the returning one restored")
+	}
+
+	// Nothing changed upstream since, so the next run writes nothing.
+	run, err = syncer.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Added+run.Updated+run.Removed != 0 || run.Unchanged != 4 {
+		t.Errorf("second run = %+v, want 4 unchanged", run)
+	}
+}
+
+func TestSyncInvalidatesChangedEntries(t *testing.T) {
+	repo := newFakeRepository(mirrored(1, "Unchanged", 10), mirrored(2, "Repriced", 20))
+	source := newFakeSource(upstreamProduct(1, "Unchanged", 10), upstreamProduct(2, "Repriced", 25))
+	svc, c := newTestService(t, source)
+	ctx := context.Background()
+	for _, id := range []int{1, 2} {
+		if _, err := svc.GetByID(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.GetAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetByCategory(ctx, "books"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{productCacheKey(1), productCacheKey(2), cacheKeyAllProducts, categoryCacheKey("books")} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%s was not cached", key)
+		}
+	}
+
+	if _, err := NewSyncService(source, repo, c, time.Minute).Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{productCacheKey(2), staleCacheKey(productCacheKey(2)), cacheKeyAllProducts, categoryCacheKey("books")} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("%s is still cached after the sync changed it", key)
+		}
+	}
+	if _, ok := c.Get(productCacheKey(1)); !ok {
+		t.Error("the unchanged product was evicted")
+	}
+}
+
+func TestSyncRefusesEmptyUpstream(t *testing.T) {
+	repo := newFakeRepository(mirrored(1, "Kept", 10))
+	source := newFakeSource()
+	svc, _ := newTestService(t, source)
+
+	run, err := NewSyncService(source, repo, svc.cache, time.Minute).Sync(context.Background())
+	if !errors.Is(err, ErrEmptyUpstream) {
+		t.Fatalf("err = %v, want ErrEmptyUpstream", err)
+	}
+	if run.Status != SyncFailed || run.Error == "" || repo.syncs[run.ID].Status != SyncFailed {
+		t.Errorf("run = %+v, want it recorded as failed", run)
+	}
+	if len(repo.applied) != 0 || repo.records[1].ArchivedAt != nil {
+		t.Error("the mirror was changed by a failed sync")
+	}
+}
+
+func TestSyncRejectsConcurrentRun(t *testing.T) {
+	source := newFakeSource(upstreamProduct(1, "Book", 10))
+	svc, _ := newTestService(t, source)
+	s := NewSyncService(source, newFakeRepository(), svc.cache, time.Minute).(*syncService)
+
+	s.running.Lock()
+	defer s.running.Unlock()
+	if _, err := s.Sync(context.Background()); !errors.Is(err, ErrSyncRunning) {
+		t.Fatalf("err = %v, want ErrSyncRunning", err)
+	}
+}
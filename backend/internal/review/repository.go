@@ -14,7 +14,9 @@ type Repository interface {
 	GetByProductID(ctx context.Context, productID int, page, limit int) ([]Review, int64, error)
 	GetAverageRating(ctx context.Context, productID int) (float64, int64, error)
 	GetUserReview(ctx context.Context, userID uuid.UUID, productID int) (*Review, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]Review, error)
 	Delete(ctx context.Context, reviewID uuid.UUID, userID uuid.UUID) error
+	AnonymizeByUserID(ctx context.Context, userID uuid.UUID) error
 }
 
 // repository implements Repository using GORM.
@@ -74,6 +76,13 @@ func (r *repository) GetUserReview(ctx context.Context, userID uuid.UUID, produc
 	return &rev, nil
 }
 
+// GetByUserID returns all reviews written by the user, newest first.
+func (r *repository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]Review, error) {
+	var reviews []Review
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&reviews).Error
+	return reviews, err
+}
+
 // Delete removes a review by ID only if it belongs to the given user.
 func (r *repository) Delete(ctx context.Context, reviewID uuid.UUID, userID uuid.UUID) error {
 	res := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", reviewID, userID).Delete(&Review{})
@@ -85,3 +94,15 @@ func (r *repository) Delete(ctx context.Context, reviewID uuid.UUID, userID uuid
 	}
 	return nil
 }
+
+// AnonymizeByUserID clears the comments of all reviews written by the user. Ratings are kept so
+// product averages do not change.
+func (r *repository) AnonymizeByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&Review{}).Where("user_id = ?", userID).Update("comment", "").Error
+}
+
+// AnonymizeUserData clears the user's review comments, which may identify the author, when their
+// account is deleted. tx is the account deletion transaction; it matches auth.AccountCleanup.
+func AnonymizeUserData(ctx context.Context, tx *gorm.DB, userID uuid.UUID) error {
+	return NewRepository(tx).AnonymizeByUserID(ctx, userID)
+}
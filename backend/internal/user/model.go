@@ -4,16 +4,44 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// Roles a user can hold. Customers are the default; staff and admins can use the back-office API.
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+)
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleCustomer, RoleStaff, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 // User is the user domain model for GORM.
+// PendingEmail holds a requested new address until it is verified. Deleted accounts are scrubbed
+// of personal data and soft-deleted, because their orders and reviews still reference them.
 type User struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Email     string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	FullName  string    `gorm:"not null" json:"fullName"`
-	CreatedAt time.Time `gorm:"not null" json:"createdAt"`
-	UpdatedAt time.Time `gorm:"not null" json:"updatedAt"`
+	ID                 uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Email              string         `gorm:"uniqueIndex;not null" json:"email"`
+	Password           string         `gorm:"not null" json:"-"`
+	FullName           string         `gorm:"not null" json:"fullName"`
+	Role               string         `gorm:"not null;default:customer;index" json:"role"`
+	EmailVerified      bool           `gorm:"not null;default:false" json:"emailVerified"`
+	PendingEmail       string         `json:"pendingEmail,omitempty"`
+	VerificationSentAt *time.Time     `json:"-"`
+	TwoFactorEnabled   bool           `gorm:"not null;default:false" json:"twoFactorEnabled"`
+	TOTPSecret         string         `gorm:"column:totp_secret" json:"-"`
+	TOTPLastCounter    int64          `gorm:"column:totp_last_counter;not null;default:0" json:"-"`
+	BannedAt           *time.Time     `gorm:"index" json:"bannedAt,omitempty"`
+	CreatedAt          time.Time      `gorm:"not null" json:"createdAt"`
+	UpdatedAt          time.Time      `gorm:"not null" json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName overrides the table name for User.
@@ -22,16 +50,200 @@ func (User) TableName() string {
 }
 
 // RefreshToken stores hashed refresh tokens for user sessions.
+// Every refresh retires the presented token and issues a new one in the same family;
+// a retired token that is presented again after a short grace period marks the family as compromised.
+// Within the grace period a retired token is exchanged at most once more; ReissuedAt records that.
+// UserAgent, IPAddress and LastUsedAt describe the device that last used the token family.
 type RefreshToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index"`
+	FamilyID   uuid.UUID  `gorm:"type:uuid;not null;default:gen_random_uuid();index"`
+	TokenHash  string     `gorm:"not null"`
+	UserAgent  string     `gorm:"type:text"`
+	IPAddress  string     `gorm:"column:ip_address"`
+	ExpiresAt  time.Time  `gorm:"not null"`
+	RevokedAt  *time.Time `gorm:"index"`
+	ReissuedAt *time.Time
+	LastUsedAt time.Time `gorm:"not null;default:now()"`
+	CreatedAt  time.Time `gorm:"not null"`
+	User       User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName overrides the table name for RefreshToken.
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// PasswordResetToken stores a hashed, single-use password reset token.
+type PasswordResetToken struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
 	TokenHash string    `gorm:"not null"`
 	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
 	CreatedAt time.Time `gorm:"not null"`
 	User      User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
 }
 
-// TableName overrides the table name for RefreshToken.
-func (RefreshToken) TableName() string {
-	return "refresh_tokens"
+// TableName overrides the table name for PasswordResetToken.
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// MagicLinkToken stores a hashed, single-use passwordless login token. NonceHash binds it to the
+// browser that asked for it; Email is the address the link was sent to.
+type MagicLinkToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"not null"`
+	NonceHash string    `gorm:"not null"`
+	Email     string    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"not null"`
+	User      User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName overrides the table name for MagicLinkToken.
+func (MagicLinkToken) TableName() string {
+	return "magic_link_tokens"
+}
+
+// RecoveryCode stores a hashed one-time 2FA recovery code.
+type RecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	CodeHash  string    `gorm:"not null;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"not null"`
+	User      User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName overrides the table name for RecoveryCode.
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// LoginThrottle tracks recent failed logins for one key, e.g. "email:a@b.c" or "ip:203.0.113.7".
+type LoginThrottle struct {
+	Key           string    `gorm:"primaryKey"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"not null"`
+	LockedUntil   *time.Time
+}
+
+// TableName overrides the table name for LoginThrottle.
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
+
+// RequestCounter counts recent requests for one rate-limited key, e.g. "reset:email:a@b.c". It is
+// kept apart from LoginThrottle so request limits never count as failed logins or lock accounts out.
+type RequestCounter struct {
+	Key           string    `gorm:"primaryKey"`
+	Requests      int       `gorm:"not null;default:0"`
+	LastRequestAt time.Time `gorm:"not null"`
+}
+
+// TableName overrides the table name for RequestCounter.
+func (RequestCounter) TableName() string {
+	return "request_counters"
+}
+
+// LoginLockout is an append-only audit record written whenever a key is locked out.
+type LoginLockout struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Key         string    `gorm:"not null;index"`
+	Email       string    `gorm:"index"`
+	IPAddress   string    `gorm:"column:ip_address"`
+	Failures    int       `gorm:"not null"`
+	LockedUntil time.Time `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"not null;index"`
+}
+
+// TableName overrides the table name for LoginLockout.
+func (LoginLockout) TableName() string {
+	return "login_lockouts"
+}
+
+// AuthEvent is an append-only audit record of an authentication event such as a login, refresh or
+// logout. UserID is nil when the event could not be tied to an account, e.g. a login attempt for an
+// unknown email. Outcome is "success", "failure" or "challenged" (a second factor was requested).
+// The one change ever made to an event is redaction when its account is deleted: Email, IPAddress
+// and UserAgent are blanked so the record no longer identifies the person.
+type AuthEvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Type      string     `gorm:"not null;index" json:"type"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index" json:"userId,omitempty"`
+	Email     string     `gorm:"index" json:"email,omitempty"`
+	IPAddress string     `gorm:"column:ip_address;index" json:"ipAddress"`
+	UserAgent string     `gorm:"type:text" json:"userAgent"`
+	Outcome   string     `gorm:"not null;index" json:"outcome"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `gorm:"not null;index" json:"createdAt"`
+}
+
+// TableName overrides the table name for AuthEvent.
+func (AuthEvent) TableName() string {
+	return "auth_events"
+}
+
+// ExternalIdentity links a user to an account at an OpenID Connect provider.
+type ExternalIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Email     string
+	CreatedAt time.Time `gorm:"not null"`
+	User      User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName overrides the table name for ExternalIdentity.
+func (ExternalIdentity) TableName() string {
+	return "external_identities"
+}
+
+// APIKey is a named personal access token for scripts and integrations. Only a SHA-256 hash of
+// the key is stored; Prefix holds its first characters so users can tell keys apart.
+type APIKey struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Name       string    `gorm:"not null"`
+	Prefix     string    `gorm:"not null"`
+	KeyHash    string    `gorm:"not null;uniqueIndex"`
+	Scopes     string    `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	LastUsedAt *time.Time
+	CreatedAt  time.Time `gorm:"not null"`
+	User       User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName overrides the table name for APIKey.
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// RevokedAccessToken rejects one access token, identified by its jti, until it would have expired.
+type RevokedAccessToken struct {
+	JTI       string    `gorm:"column:jti;primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// TableName overrides the table name for RevokedAccessToken.
+func (RevokedAccessToken) TableName() string {
+	return "revoked_access_tokens"
+}
+
+// AccessTokenCutoff rejects every access token of a user issued before IssuedBefore, e.g. after a
+// ban or password change. It is kept until ExpiresAt, when all such tokens have expired anyway.
+type AccessTokenCutoff struct {
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	IssuedBefore time.Time `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
+
+// TableName overrides the table name for AccessTokenCutoff.
+func (AccessTokenCutoff) TableName() string {
+	return "access_token_cutoffs"
 }
@@ -31,6 +31,7 @@ type Repository interface {
 	GetByUserIDAndProductID(ctx context.Context, userID uuid.UUID, productID int) (*WishlistItem, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	DeleteByUserIDAndProductID(ctx context.Context, userID uuid.UUID, productID int) error
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
 }
 
 // repository implements Repository using GORM.
@@ -101,3 +102,14 @@ func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
 func (r *repository) DeleteByUserIDAndProductID(ctx context.Context, userID uuid.UUID, productID int) error {
 	return r.db.WithContext(ctx).Where("user_id = ? AND product_id = ?", userID, productID).Delete(&WishlistItem{}).Error
 }
+
+// DeleteByUserID removes every wishlist item of a user.
+func (r *repository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&WishlistItem{}).Error
+}
+
+// DeleteUserData removes the user's wishlist when their account is deleted. tx is the account
+// deletion transaction; it matches auth.AccountCleanup.
+func DeleteUserData(ctx context.Context, tx *gorm.DB, userID uuid.UUID) error {
+	return NewRepository(tx).DeleteByUserID(ctx, userID)
+}
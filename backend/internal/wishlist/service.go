@@ -49,10 +49,16 @@ func (s *service) GetByUserID(ctx context.Context, userID uuid.UUID) ([]Wishlist
 	if err != nil {
 		return nil, err
 	}
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	// Items whose product could not be loaded are skipped, as with the cart.
+	products, _ := s.product.GetByIDs(ctx, ids)
 	out := make([]WishlistItemResponse, 0, len(items))
 	for _, item := range items {
-		p, err := s.product.GetByID(ctx, item.ProductID)
-		if err != nil {
+		p, ok := products[item.ProductID]
+		if !ok {
 			continue
 		}
 		out = append(out, WishlistItemResponse{
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -31,11 +32,72 @@ type Config struct {
 	DatabasePoolerURL   string   `envconfig:"DATABASE_POOLER_URL"` // optional; use if direct DB fails with "no route to host" (e.g. Supabase Session pooler)
 	JWTSecret           string   `envconfig:"JWT_SECRET"`
 	JWTAccessTTL        Duration `envconfig:"JWT_ACCESS_TTL"`
-	JWTRefreshTTL        Duration `envconfig:"JWT_REFRESH_TTL"`
+	JWTRefreshTTL       Duration `envconfig:"JWT_REFRESH_TTL"`
 	StripeSecretKey     string   `envconfig:"STRIPE_SECRET_KEY"`
 	StripeWebhookSecret string   `envconfig:"STRIPE_WEBHOOK_SECRET"`
 	FakestoreBaseURL    string   `envconfig:"FAKESTORE_BASE_URL"`
 	CORSAllowedOrigins  string   `envconfig:"CORS_ALLOWED_ORIGINS"`
+	AppBaseURL          string   `envconfig:"APP_BASE_URL"` // frontend origin used in emailed links, e.g. http://localhost:5173
+	MailDriver          string   `envconfig:"MAIL_DRIVER"`  // "log" (default) or "file"
+	MailFrom            string   `envconfig:"MAIL_FROM"`
+	MailFileDir         string   `envconfig:"MAIL_FILE_DIR"` // directory for MAIL_DRIVER=file
+	// RequireVerifiedEmailForCheckout blocks POST /checkout/intent for users who have not verified their email.
+	RequireVerifiedEmailForCheckout bool `envconfig:"REQUIRE_VERIFIED_EMAIL_FOR_CHECKOUT"`
+
+	// AdminEmails lists accounts given the admin role at startup, e.g. to create the first admin.
+	// Each must already exist with a verified email.
+	AdminEmails string `envconfig:"ADMIN_EMAILS"` // comma-separated
+
+	// Login brute-force protection. Zero values fall back to the defaults in the auth package.
+	LoginMaxFailuresPerEmail int      `envconfig:"LOGIN_MAX_FAILURES_PER_EMAIL"` // default 10
+	LoginMaxFailuresPerIP    int      `envconfig:"LOGIN_MAX_FAILURES_PER_IP"`    // default 50
+	LoginLockoutDuration     Duration `envconfig:"LOGIN_LOCKOUT_DURATION"`       // default 15m
+
+	// Asymmetric access token signing. When JWTSigningKeyFile is empty, tokens are signed with JWTSecret (HS256).
+	// JWTSecret is still required with a signing key file, as it signs the single-purpose tokens.
+	JWTSigningKeyFile       string `envconfig:"JWT_SIGNING_KEY_FILE"`       // PEM private key, RSA (RS256) or Ed25519 (EdDSA)
+	JWTVerificationKeyFiles string `envconfig:"JWT_VERIFICATION_KEY_FILES"` // comma-separated PEM keys from earlier rotations, still accepted
+
+	// OIDCProviderNames lists the enabled social login providers, e.g. "google,gitlab".
+	// Each provider NAME is configured through OIDC_<NAME>_* variables, see OIDCProvider.
+	OIDCProviderNames string         `envconfig:"OIDC_PROVIDERS"`
+	OIDCProviders     []OIDCProvider `ignored:"true"`
+
+	// Password hashing. New hashes use PasswordHashAlgorithm; existing hashes made with another algorithm
+	// or weaker parameters are upgraded on the next successful login. Zero values use the defaults.
+	PasswordHashAlgorithm string `envconfig:"PASSWORD_HASH_ALGORITHM"` // "argon2id" (default) or "bcrypt"
+	Argon2MemoryKiB       int    `envconfig:"ARGON2_MEMORY_KIB"`       // default 65536
+	Argon2Iterations      int    `envconfig:"ARGON2_ITERATIONS"`       // default 3
+	Argon2Parallelism     int    `envconfig:"ARGON2_PARALLELISM"`      // default 2
+	BcryptCost            int    `envconfig:"BCRYPT_COST"`             // default 12
+	// PasswordHashConcurrency caps how many hashes are computed at once; each argon2id hash holds
+	// Argon2MemoryKiB of memory. Default GOMAXPROCS.
+	PasswordHashConcurrency int `envconfig:"PASSWORD_HASH_CONCURRENCY"`
+
+	// ExportDir is where personal data export archives are written. Defaults to a directory under os.TempDir().
+	ExportDir string `envconfig:"EXPORT_DIR"`
+
+	// Product catalog source. The FakeStore source uses FakestoreBaseURL; "database" serves the products
+	// table managed through the /admin/products API; "mirror" serves the products table kept in sync
+	// with FakeStore every CatalogSyncInterval.
+	CatalogSource string `envconfig:"CATALOG_SOURCE"` // "fakestore" (default), "file", "database" or "mirror"
+	CatalogFile   string `envconfig:"CATALOG_FILE"`   // JSON product file for CATALOG_SOURCE=file, default data/products.json
+
+	// InventoryReservationTTL is how long a checkout holds stock before it is released. Default 15m.
+	InventoryReservationTTL Duration `envconfig:"INVENTORY_RESERVATION_TTL"`
+
+	// CatalogSyncInterval is how often CATALOG_SOURCE=mirror pulls the FakeStore catalog. Default 15m.
+	CatalogSyncInterval Duration `envconfig:"CATALOG_SYNC_INTERVAL"`
+}
+
+// OIDCProvider configures one OpenID Connect issuer used for social login.
+type OIDCProvider struct {
+	Name         string `ignored:"true"`
+	Issuer       string `envconfig:"ISSUER" required:"true"` // e.g. https://accounts.google.com
+	ClientID     string `envconfig:"CLIENT_ID" required:"true"`
+	ClientSecret string `envconfig:"CLIENT_SECRET"`
+	RedirectURL  string `envconfig:"REDIRECT_URL" required:"true"` // frontend page that receives ?code=&state=
+	Scopes       string `envconfig:"SCOPES" default:"openid email profile"`
 }
 
 // Load reads configuration from environment variables and returns Config.
@@ -57,6 +119,17 @@ func Load() *Config {
 	if err := envconfig.Process("", &c); err != nil {
 		log.Fatalf("config: load env: %v", err)
 	}
+	for _, name := range strings.Split(c.OIDCProviderNames, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		p := OIDCProvider{Name: name}
+		if err := envconfig.Process("OIDC_"+strings.ToUpper(name), &p); err != nil {
+			log.Fatalf("config: load oidc provider %s: %v", name, err)
+		}
+		c.OIDCProviders = append(c.OIDCProviders, p)
+	}
 	return &c
 }
 
@@ -6,7 +6,10 @@ import (
 	"net/url"
 
 	"github.com/Rakesh2908/shopgo/internal/cart"
+	"github.com/Rakesh2908/shopgo/internal/export"
+	"github.com/Rakesh2908/shopgo/internal/inventory"
 	"github.com/Rakesh2908/shopgo/internal/order"
+	"github.com/Rakesh2908/shopgo/internal/product"
 	"github.com/Rakesh2908/shopgo/internal/review"
 	"github.com/Rakesh2908/shopgo/internal/user"
 	"github.com/Rakesh2908/shopgo/internal/wishlist"
@@ -62,17 +65,45 @@ func Connect(dsn string) *gorm.DB {
 	return db
 }
 
-// Migrate runs GORM AutoMigrate for all models from user, cart, order, wishlist, and review packages.
+// Migrate runs GORM AutoMigrate for all models from user, product, inventory, cart, order, wishlist, review and export packages.
+//
+// Users who existed before email verification was introduced are marked verified when the column
+// is added, so enabling REQUIRE_VERIFIED_EMAIL_FOR_CHECKOUT does not lock them out of checkout.
 func Migrate(db *gorm.DB) {
+	backfillVerified := db.Migrator().HasTable(&user.User{}) && !db.Migrator().HasColumn(&user.User{}, "EmailVerified")
 	if err := db.AutoMigrate(
 		&user.User{},
 		&user.RefreshToken{},
+		&user.PasswordResetToken{},
+		&user.MagicLinkToken{},
+		&user.RecoveryCode{},
+		&user.LoginThrottle{},
+		&user.RequestCounter{},
+		&user.LoginLockout{},
+		&user.AuthEvent{},
+		&user.ExternalIdentity{},
+		&user.APIKey{},
+		&user.RevokedAccessToken{},
+		&user.AccessTokenCutoff{},
+		&product.Category{},
+		&product.ProductRecord{},
+		&product.CatalogSync{},
+		&product.PriceChange{},
+		&inventory.StockLevel{},
+		&inventory.Reservation{},
+		&inventory.ReservationItem{},
 		&cart.CartItem{},
 		&order.Order{},
 		&order.OrderItem{},
 		&wishlist.WishlistItem{},
 		&review.Review{},
+		&export.DataExport{},
 	); err != nil {
 		log.Fatalf("database: migrate: %v", err)
 	}
+	if backfillVerified {
+		if err := db.Exec("UPDATE users SET email_verified = true").Error; err != nil {
+			log.Fatalf("database: backfill email_verified: %v", err)
+		}
+	}
 }
@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email messages. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+const defaultFrom = "ShopGo <no-reply@shopgo.local>"
+
+// NewSender returns the Sender for the given driver: "file" writes messages to dir, anything else logs them.
+func NewSender(driver, from, dir string) Sender {
+	if from == "" {
+		from = defaultFrom
+	}
+	switch strings.ToLower(driver) {
+	case "file":
+		return NewFileSender(from, dir)
+	default:
+		return NewLogSender(from)
+	}
+}
+
+// LogSender writes messages to the standard logger. Intended for local development.
+type LogSender struct {
+	from string
+}
+
+// NewLogSender returns a Sender that logs every message.
+func NewLogSender(from string) *LogSender {
+	return &LogSender{from: from}
+}
+
+// Send logs the message.
+func (s *LogSender) Send(_ context.Context, msg Message) error {
+	log.Printf("mail: from=%s to=%s subject=%q\n%s", s.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileSender writes each message as an .eml file into a directory. Intended for local development.
+type FileSender struct {
+	from string
+	dir  string
+	mu   sync.Mutex
+}
+
+// NewFileSender returns a Sender that writes messages into dir (default "mail").
+func NewFileSender(from, dir string) *FileSender {
+	if dir == "" {
+		dir = "mail"
+	}
+	return &FileSender{from: from, dir: dir}
+}
+
+// Send writes the message to <dir>/<timestamp>-<id>.eml.
+func (s *FileSender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("mail: create dir: %w", err)
+	}
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405"), uuid.NewString())
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n",
+		s.from, msg.To, msg.Subject, now.Format(time.RFC1123Z), msg.Body)
+	if err := os.WriteFile(filepath.Join(s.dir, name), []byte(content), 0o600); err != nil {
+		return fmt.Errorf("mail: write message: %w", err)
+	}
+	return nil
+}
@@ -1,5 +1,5 @@
 import api, { apiGet } from './client'
-import type { ApiResponse, Product } from '@/types'
+import type { ApiResponse, Product, SearchHit } from '@/types'
 
 export interface GetProductsParams {
   page?: number
@@ -12,6 +12,16 @@ export interface ProductsListResponse {
   meta: Record<string, unknown>
 }
 
+export interface SearchProductsParams {
+  page?: number
+  limit?: number
+}
+
+export interface SearchResponse {
+  data: SearchHit[]
+  meta: Record<string, unknown>
+}
+
 /**
  * Fetch products with optional pagination and category filter.
  */
@@ -43,8 +53,21 @@ export async function getCategories(): Promise<string[]> {
 }
 
 /**
- * Search products by query string.
+ * Search products by query string. Hits come most relevant first, one page at a time;
+ * meta.total is the number of matches.
  */
-export async function searchProducts(q: string): Promise<Product[]> {
-  return apiGet<Product[]>('/products/search', { params: { q } })
+export async function searchProducts(
+  q: string,
+  params?: SearchProductsParams,
+): Promise<SearchResponse> {
+  const res = await api.get<ApiResponse<SearchHit[]>>('/products/search', {
+    params: { q, ...params },
+  })
+  if (!res.data.success) {
+    throw new Error(res.data.error?.message ?? 'Request failed')
+  }
+  return {
+    data: res.data.data,
+    meta: (res.data.meta as Record<string, unknown>) ?? {},
+  }
 }
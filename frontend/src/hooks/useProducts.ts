@@ -7,8 +7,8 @@ import {
   searchProducts,
 } from '@/api/products'
 import { getReviews } from '@/api/reviews'
-import type { GetProductsParams } from '@/api/products'
-import type { Product, ReviewsResponse } from '@/types'
+import type { GetProductsParams, SearchProductsParams, SearchResponse } from '@/api/products'
+import type { ReviewsResponse } from '@/types'
 
 import { useDebounce } from './useDebounce'
 
@@ -48,13 +48,13 @@ export function useCategories() {
 }
 
 /**
- * Search products by query string. Debounced 300ms, runs only when q.length > 1.
+ * Search products by query string, one page of hits at a time. Debounced 300ms, runs only when q.length > 1.
  */
-export function useSearchProducts(q: string) {
+export function useSearchProducts(q: string, params?: SearchProductsParams) {
   const debouncedQ = useDebounce(q, SEARCH_DEBOUNCE_MS)
   return useQuery({
-    queryKey: ['products', 'search', debouncedQ],
-    queryFn: (): Promise<Product[]> => searchProducts(debouncedQ),
+    queryKey: ['products', 'search', debouncedQ, params],
+    queryFn: (): Promise<SearchResponse> => searchProducts(debouncedQ, params),
     enabled: debouncedQ.length > 1,
   })
 }
@@ -12,11 +12,11 @@ const MAX_SUGGESTIONS = 6
 export function useSearchProducts(query: string) {
   const debouncedQuery = useDebounce(query.trim(), 300)
 
-  const { data: products = [] } = useQuery({
-    queryKey: ['products', 'search', debouncedQuery],
-    queryFn: () => searchProducts(debouncedQuery),
+  const { data } = useQuery({
+    queryKey: ['products', 'search', debouncedQuery, { limit: MAX_SUGGESTIONS }],
+    queryFn: () => searchProducts(debouncedQuery, { limit: MAX_SUGGESTIONS }),
     enabled: debouncedQuery.length >= 3,
   })
 
-  return products.slice(0, MAX_SUGGESTIONS)
+  return data?.data ?? []
 }
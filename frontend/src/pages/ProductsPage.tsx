@@ -47,37 +47,22 @@ export default function ProductsPage() {
 
   const searchEnabled = q.trim().length > 1
   const listQuery = useProducts({ page, limit: PAGE_SIZE, category })
-  const searchQuery = useSearchProducts(q.trim())
+  const searchQuery = useSearchProducts(q.trim(), { page, limit: PAGE_SIZE })
+  const activeQuery = searchEnabled ? searchQuery : listQuery
 
-  const baseProducts: Product[] = useMemo(() => {
-    const items = searchEnabled ? (searchQuery.data ?? []) : (listQuery.data?.data ?? [])
+  // Search results are paged by the server in relevance order; sort and category apply to the page shown.
+  const paged: Product[] = useMemo(() => {
+    const items: Product[] = activeQuery.data?.data ?? []
     const filtered = category ? items.filter((p) => p.category === category) : items
     return sortProducts(filtered, sort)
-  }, [category, listQuery.data?.data, searchEnabled, searchQuery.data, sort])
-
-  const { paged, totalPages } = useMemo(() => {
-    if (searchEnabled) {
-      const total = baseProducts.length
-      const pages = Math.max(1, Math.ceil(total / PAGE_SIZE))
-      const safePage = Math.min(page, pages)
-      const start = (safePage - 1) * PAGE_SIZE
-      return {
-        paged: baseProducts.slice(start, start + PAGE_SIZE),
-        totalPages: pages,
-      }
-    }
+  }, [activeQuery.data?.data, category, sort])
 
-    const total = metaNumber(listQuery.data?.meta, 'total')
-    const pages = total != null ? Math.max(1, Math.ceil(total / PAGE_SIZE)) : Math.max(1, page)
-    return {
-      paged: baseProducts,
-      totalPages: pages,
-    }
-  }, [baseProducts, listQuery.data?.meta, page, searchEnabled])
+  const totalPages = useMemo(() => {
+    const total = metaNumber(activeQuery.data?.meta, 'total')
+    return total != null ? Math.max(1, Math.ceil(total / PAGE_SIZE)) : Math.max(1, page)
+  }, [activeQuery.data?.meta, page])
 
-  const isLoading = searchEnabled ? searchQuery.isLoading : listQuery.isLoading
-  const isError = searchEnabled ? searchQuery.isError : listQuery.isError
-  const refetch = searchEnabled ? searchQuery.refetch : listQuery.refetch
+  const { isLoading, isError, refetch } = activeQuery
 
   useEffect(() => {
     document.title = 'Products | ShopGo'
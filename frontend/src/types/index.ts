@@ -20,6 +20,12 @@ export interface Product {
   rating: ProductRating;
 }
 
+/** A product matching a search, with its relevance and HTML excerpts (matches in <mark>) per field. */
+export interface SearchHit extends Product {
+  score: number;
+  highlights?: Record<string, string>;
+}
+
 export interface CartItem {
   id: string;
   productId: number;